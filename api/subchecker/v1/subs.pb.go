@@ -0,0 +1,985 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: subchecker/v1/subs.proto
+
+// gRPC API of SubChecker, it mirrors the REST /subs endpoints.
+// Months are "MM-YYYY" like in REST requests.
+
+package subcheckerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Sub struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	OrgId       string                 `protobuf:"bytes,2,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	ServiceId   int64                  `protobuf:"varint,3,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	ServiceName string                 `protobuf:"bytes,4,opt,name=service_name,json=serviceName,proto3" json:"service_name,omitempty"`
+	Price       int64                  `protobuf:"varint,5,opt,name=price,proto3" json:"price,omitempty"`
+	UserId      string                 `protobuf:"bytes,6,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	StartDate   string                 `protobuf:"bytes,7,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	// Empty for an open subscription
+	EndDate       string `protobuf:"bytes,8,opt,name=end_date,json=endDate,proto3" json:"end_date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sub) Reset() {
+	*x = Sub{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sub) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sub) ProtoMessage() {}
+
+func (x *Sub) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sub.ProtoReflect.Descriptor instead.
+func (*Sub) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Sub) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Sub) GetOrgId() string {
+	if x != nil {
+		return x.OrgId
+	}
+	return ""
+}
+
+func (x *Sub) GetServiceId() int64 {
+	if x != nil {
+		return x.ServiceId
+	}
+	return 0
+}
+
+func (x *Sub) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+func (x *Sub) GetPrice() int64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Sub) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Sub) GetStartDate() string {
+	if x != nil {
+		return x.StartDate
+	}
+	return ""
+}
+
+func (x *Sub) GetEndDate() string {
+	if x != nil {
+		return x.EndDate
+	}
+	return ""
+}
+
+// SubFilter narrows subscriptions, empty fields match everything
+type SubFilter struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	UserId    string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ServiceId int64                  `protobuf:"varint,2,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	// Case-insensitive
+	ServiceName   string `protobuf:"bytes,3,opt,name=service_name,json=serviceName,proto3" json:"service_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubFilter) Reset() {
+	*x = SubFilter{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubFilter) ProtoMessage() {}
+
+func (x *SubFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubFilter.ProtoReflect.Descriptor instead.
+func (*SubFilter) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SubFilter) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SubFilter) GetServiceId() int64 {
+	if x != nil {
+		return x.ServiceId
+	}
+	return 0
+}
+
+func (x *SubFilter) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+type CreateSubResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sub           *Sub                   `protobuf:"bytes,1,opt,name=sub,proto3" json:"sub,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSubResponse) Reset() {
+	*x = CreateSubResponse{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSubResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSubResponse) ProtoMessage() {}
+
+func (x *CreateSubResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSubResponse.ProtoReflect.Descriptor instead.
+func (*CreateSubResponse) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateSubResponse) GetSub() *Sub {
+	if x != nil {
+		return x.Sub
+	}
+	return nil
+}
+
+type CreateSubRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ServiceId     int64                  `protobuf:"varint,1,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	ServiceName   string                 `protobuf:"bytes,2,opt,name=service_name,json=serviceName,proto3" json:"service_name,omitempty"`
+	Price         *int64                 `protobuf:"varint,3,opt,name=price,proto3,oneof" json:"price,omitempty"`
+	UserId        string                 `protobuf:"bytes,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	StartDate     string                 `protobuf:"bytes,5,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	EndDate       string                 `protobuf:"bytes,6,opt,name=end_date,json=endDate,proto3" json:"end_date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateSubRequest) Reset() {
+	*x = CreateSubRequest{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateSubRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateSubRequest) ProtoMessage() {}
+
+func (x *CreateSubRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateSubRequest.ProtoReflect.Descriptor instead.
+func (*CreateSubRequest) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CreateSubRequest) GetServiceId() int64 {
+	if x != nil {
+		return x.ServiceId
+	}
+	return 0
+}
+
+func (x *CreateSubRequest) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+func (x *CreateSubRequest) GetPrice() int64 {
+	if x != nil && x.Price != nil {
+		return *x.Price
+	}
+	return 0
+}
+
+func (x *CreateSubRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *CreateSubRequest) GetStartDate() string {
+	if x != nil {
+		return x.StartDate
+	}
+	return ""
+}
+
+func (x *CreateSubRequest) GetEndDate() string {
+	if x != nil {
+		return x.EndDate
+	}
+	return ""
+}
+
+type GetSubResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sub           *Sub                   `protobuf:"bytes,1,opt,name=sub,proto3" json:"sub,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSubResponse) Reset() {
+	*x = GetSubResponse{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSubResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSubResponse) ProtoMessage() {}
+
+func (x *GetSubResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSubResponse.ProtoReflect.Descriptor instead.
+func (*GetSubResponse) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetSubResponse) GetSub() *Sub {
+	if x != nil {
+		return x.Sub
+	}
+	return nil
+}
+
+type GetSubRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSubRequest) Reset() {
+	*x = GetSubRequest{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSubRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSubRequest) ProtoMessage() {}
+
+func (x *GetSubRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSubRequest.ProtoReflect.Descriptor instead.
+func (*GetSubRequest) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetSubRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type UpdateSubResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sub           *Sub                   `protobuf:"bytes,1,opt,name=sub,proto3" json:"sub,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateSubResponse) Reset() {
+	*x = UpdateSubResponse{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSubResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSubResponse) ProtoMessage() {}
+
+func (x *UpdateSubResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSubResponse.ProtoReflect.Descriptor instead.
+func (*UpdateSubResponse) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateSubResponse) GetSub() *Sub {
+	if x != nil {
+		return x.Sub
+	}
+	return nil
+}
+
+type UpdateSubRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ServiceId     int64                  `protobuf:"varint,2,opt,name=service_id,json=serviceId,proto3" json:"service_id,omitempty"`
+	ServiceName   string                 `protobuf:"bytes,3,opt,name=service_name,json=serviceName,proto3" json:"service_name,omitempty"`
+	Price         *int64                 `protobuf:"varint,4,opt,name=price,proto3,oneof" json:"price,omitempty"`
+	UserId        string                 `protobuf:"bytes,5,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	StartDate     string                 `protobuf:"bytes,6,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	EndDate       string                 `protobuf:"bytes,7,opt,name=end_date,json=endDate,proto3" json:"end_date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateSubRequest) Reset() {
+	*x = UpdateSubRequest{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSubRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSubRequest) ProtoMessage() {}
+
+func (x *UpdateSubRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSubRequest.ProtoReflect.Descriptor instead.
+func (*UpdateSubRequest) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateSubRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateSubRequest) GetServiceId() int64 {
+	if x != nil {
+		return x.ServiceId
+	}
+	return 0
+}
+
+func (x *UpdateSubRequest) GetServiceName() string {
+	if x != nil {
+		return x.ServiceName
+	}
+	return ""
+}
+
+func (x *UpdateSubRequest) GetPrice() int64 {
+	if x != nil && x.Price != nil {
+		return *x.Price
+	}
+	return 0
+}
+
+func (x *UpdateSubRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UpdateSubRequest) GetStartDate() string {
+	if x != nil {
+		return x.StartDate
+	}
+	return ""
+}
+
+func (x *UpdateSubRequest) GetEndDate() string {
+	if x != nil {
+		return x.EndDate
+	}
+	return ""
+}
+
+type DeleteSubRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSubRequest) Reset() {
+	*x = DeleteSubRequest{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSubRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSubRequest) ProtoMessage() {}
+
+func (x *DeleteSubRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSubRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSubRequest) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteSubRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteSubResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSubResponse) Reset() {
+	*x = DeleteSubResponse{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSubResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSubResponse) ProtoMessage() {}
+
+func (x *DeleteSubResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSubResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSubResponse) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{9}
+}
+
+type ListSubsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *SubFilter             `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// 50 if not set, 500 at most
+	Limit         int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32 `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubsRequest) Reset() {
+	*x = ListSubsRequest{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubsRequest) ProtoMessage() {}
+
+func (x *ListSubsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubsRequest.ProtoReflect.Descriptor instead.
+func (*ListSubsRequest) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListSubsRequest) GetFilter() *SubFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListSubsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListSubsRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ListSubsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subs          []*Sub                 `protobuf:"bytes,1,rep,name=subs,proto3" json:"subs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSubsResponse) Reset() {
+	*x = ListSubsResponse{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSubsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSubsResponse) ProtoMessage() {}
+
+func (x *ListSubsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSubsResponse.ProtoReflect.Descriptor instead.
+func (*ListSubsResponse) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListSubsResponse) GetSubs() []*Sub {
+	if x != nil {
+		return x.Subs
+	}
+	return nil
+}
+
+type SumCostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filter        *SubFilter             `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	StartMonth    string                 `protobuf:"bytes,2,opt,name=start_month,json=startMonth,proto3" json:"start_month,omitempty"`
+	EndMonth      string                 `protobuf:"bytes,3,opt,name=end_month,json=endMonth,proto3" json:"end_month,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SumCostRequest) Reset() {
+	*x = SumCostRequest{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SumCostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SumCostRequest) ProtoMessage() {}
+
+func (x *SumCostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SumCostRequest.ProtoReflect.Descriptor instead.
+func (*SumCostRequest) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SumCostRequest) GetFilter() *SubFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *SumCostRequest) GetStartMonth() string {
+	if x != nil {
+		return x.StartMonth
+	}
+	return ""
+}
+
+func (x *SumCostRequest) GetEndMonth() string {
+	if x != nil {
+		return x.EndMonth
+	}
+	return ""
+}
+
+type SumCostResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TotalRub      int64                  `protobuf:"varint,1,opt,name=total_rub,json=totalRub,proto3" json:"total_rub,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SumCostResponse) Reset() {
+	*x = SumCostResponse{}
+	mi := &file_subchecker_v1_subs_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SumCostResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SumCostResponse) ProtoMessage() {}
+
+func (x *SumCostResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_subchecker_v1_subs_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SumCostResponse.ProtoReflect.Descriptor instead.
+func (*SumCostResponse) Descriptor() ([]byte, []int) {
+	return file_subchecker_v1_subs_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SumCostResponse) GetTotalRub() int64 {
+	if x != nil {
+		return x.TotalRub
+	}
+	return 0
+}
+
+var File_subchecker_v1_subs_proto protoreflect.FileDescriptor
+
+const file_subchecker_v1_subs_proto_rawDesc = "" +
+	"\n" +
+	"\x18subchecker/v1/subs.proto\x12\rsubchecker.v1\"\xd7\x01\n" +
+	"\x03Sub\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x15\n" +
+	"\x06org_id\x18\x02 \x01(\tR\x05orgId\x12\x1d\n" +
+	"\n" +
+	"service_id\x18\x03 \x01(\x03R\tserviceId\x12!\n" +
+	"\fservice_name\x18\x04 \x01(\tR\vserviceName\x12\x14\n" +
+	"\x05price\x18\x05 \x01(\x03R\x05price\x12\x17\n" +
+	"\auser_id\x18\x06 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"start_date\x18\a \x01(\tR\tstartDate\x12\x19\n" +
+	"\bend_date\x18\b \x01(\tR\aendDate\"f\n" +
+	"\tSubFilter\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"service_id\x18\x02 \x01(\x03R\tserviceId\x12!\n" +
+	"\fservice_name\x18\x03 \x01(\tR\vserviceName\"9\n" +
+	"\x11CreateSubResponse\x12$\n" +
+	"\x03sub\x18\x01 \x01(\v2\x12.subchecker.v1.SubR\x03sub\"\xcc\x01\n" +
+	"\x10CreateSubRequest\x12\x1d\n" +
+	"\n" +
+	"service_id\x18\x01 \x01(\x03R\tserviceId\x12!\n" +
+	"\fservice_name\x18\x02 \x01(\tR\vserviceName\x12\x19\n" +
+	"\x05price\x18\x03 \x01(\x03H\x00R\x05price\x88\x01\x01\x12\x17\n" +
+	"\auser_id\x18\x04 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"start_date\x18\x05 \x01(\tR\tstartDate\x12\x19\n" +
+	"\bend_date\x18\x06 \x01(\tR\aendDateB\b\n" +
+	"\x06_price\"6\n" +
+	"\x0eGetSubResponse\x12$\n" +
+	"\x03sub\x18\x01 \x01(\v2\x12.subchecker.v1.SubR\x03sub\"\x1f\n" +
+	"\rGetSubRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"9\n" +
+	"\x11UpdateSubResponse\x12$\n" +
+	"\x03sub\x18\x01 \x01(\v2\x12.subchecker.v1.SubR\x03sub\"\xdc\x01\n" +
+	"\x10UpdateSubRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1d\n" +
+	"\n" +
+	"service_id\x18\x02 \x01(\x03R\tserviceId\x12!\n" +
+	"\fservice_name\x18\x03 \x01(\tR\vserviceName\x12\x19\n" +
+	"\x05price\x18\x04 \x01(\x03H\x00R\x05price\x88\x01\x01\x12\x17\n" +
+	"\auser_id\x18\x05 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"start_date\x18\x06 \x01(\tR\tstartDate\x12\x19\n" +
+	"\bend_date\x18\a \x01(\tR\aendDateB\b\n" +
+	"\x06_price\"\"\n" +
+	"\x10DeleteSubRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x13\n" +
+	"\x11DeleteSubResponse\"q\n" +
+	"\x0fListSubsRequest\x120\n" +
+	"\x06filter\x18\x01 \x01(\v2\x18.subchecker.v1.SubFilterR\x06filter\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\":\n" +
+	"\x10ListSubsResponse\x12&\n" +
+	"\x04subs\x18\x01 \x03(\v2\x12.subchecker.v1.SubR\x04subs\"\x80\x01\n" +
+	"\x0eSumCostRequest\x120\n" +
+	"\x06filter\x18\x01 \x01(\v2\x18.subchecker.v1.SubFilterR\x06filter\x12\x1f\n" +
+	"\vstart_month\x18\x02 \x01(\tR\n" +
+	"startMonth\x12\x1b\n" +
+	"\tend_month\x18\x03 \x01(\tR\bendMonth\".\n" +
+	"\x0fSumCostResponse\x12\x1b\n" +
+	"\ttotal_rub\x18\x01 \x01(\x03R\btotalRub2\xda\x03\n" +
+	"\n" +
+	"SubService\x12N\n" +
+	"\tCreateSub\x12\x1f.subchecker.v1.CreateSubRequest\x1a .subchecker.v1.CreateSubResponse\x12E\n" +
+	"\x06GetSub\x12\x1c.subchecker.v1.GetSubRequest\x1a\x1d.subchecker.v1.GetSubResponse\x12N\n" +
+	"\tUpdateSub\x12\x1f.subchecker.v1.UpdateSubRequest\x1a .subchecker.v1.UpdateSubResponse\x12N\n" +
+	"\tDeleteSub\x12\x1f.subchecker.v1.DeleteSubRequest\x1a .subchecker.v1.DeleteSubResponse\x12K\n" +
+	"\bListSubs\x12\x1e.subchecker.v1.ListSubsRequest\x1a\x1f.subchecker.v1.ListSubsResponse\x12H\n" +
+	"\aSumCost\x12\x1d.subchecker.v1.SumCostRequest\x1a\x1e.subchecker.v1.SumCostResponseB>Z<github.com/tmozzze/SubChecker/api/subchecker/v1;subcheckerv1b\x06proto3"
+
+var (
+	file_subchecker_v1_subs_proto_rawDescOnce sync.Once
+	file_subchecker_v1_subs_proto_rawDescData []byte
+)
+
+func file_subchecker_v1_subs_proto_rawDescGZIP() []byte {
+	file_subchecker_v1_subs_proto_rawDescOnce.Do(func() {
+		file_subchecker_v1_subs_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_subchecker_v1_subs_proto_rawDesc), len(file_subchecker_v1_subs_proto_rawDesc)))
+	})
+	return file_subchecker_v1_subs_proto_rawDescData
+}
+
+var file_subchecker_v1_subs_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_subchecker_v1_subs_proto_goTypes = []any{
+	(*Sub)(nil),               // 0: subchecker.v1.Sub
+	(*SubFilter)(nil),         // 1: subchecker.v1.SubFilter
+	(*CreateSubResponse)(nil), // 2: subchecker.v1.CreateSubResponse
+	(*CreateSubRequest)(nil),  // 3: subchecker.v1.CreateSubRequest
+	(*GetSubResponse)(nil),    // 4: subchecker.v1.GetSubResponse
+	(*GetSubRequest)(nil),     // 5: subchecker.v1.GetSubRequest
+	(*UpdateSubResponse)(nil), // 6: subchecker.v1.UpdateSubResponse
+	(*UpdateSubRequest)(nil),  // 7: subchecker.v1.UpdateSubRequest
+	(*DeleteSubRequest)(nil),  // 8: subchecker.v1.DeleteSubRequest
+	(*DeleteSubResponse)(nil), // 9: subchecker.v1.DeleteSubResponse
+	(*ListSubsRequest)(nil),   // 10: subchecker.v1.ListSubsRequest
+	(*ListSubsResponse)(nil),  // 11: subchecker.v1.ListSubsResponse
+	(*SumCostRequest)(nil),    // 12: subchecker.v1.SumCostRequest
+	(*SumCostResponse)(nil),   // 13: subchecker.v1.SumCostResponse
+}
+var file_subchecker_v1_subs_proto_depIdxs = []int32{
+	0,  // 0: subchecker.v1.CreateSubResponse.sub:type_name -> subchecker.v1.Sub
+	0,  // 1: subchecker.v1.GetSubResponse.sub:type_name -> subchecker.v1.Sub
+	0,  // 2: subchecker.v1.UpdateSubResponse.sub:type_name -> subchecker.v1.Sub
+	1,  // 3: subchecker.v1.ListSubsRequest.filter:type_name -> subchecker.v1.SubFilter
+	0,  // 4: subchecker.v1.ListSubsResponse.subs:type_name -> subchecker.v1.Sub
+	1,  // 5: subchecker.v1.SumCostRequest.filter:type_name -> subchecker.v1.SubFilter
+	3,  // 6: subchecker.v1.SubService.CreateSub:input_type -> subchecker.v1.CreateSubRequest
+	5,  // 7: subchecker.v1.SubService.GetSub:input_type -> subchecker.v1.GetSubRequest
+	7,  // 8: subchecker.v1.SubService.UpdateSub:input_type -> subchecker.v1.UpdateSubRequest
+	8,  // 9: subchecker.v1.SubService.DeleteSub:input_type -> subchecker.v1.DeleteSubRequest
+	10, // 10: subchecker.v1.SubService.ListSubs:input_type -> subchecker.v1.ListSubsRequest
+	12, // 11: subchecker.v1.SubService.SumCost:input_type -> subchecker.v1.SumCostRequest
+	2,  // 12: subchecker.v1.SubService.CreateSub:output_type -> subchecker.v1.CreateSubResponse
+	4,  // 13: subchecker.v1.SubService.GetSub:output_type -> subchecker.v1.GetSubResponse
+	6,  // 14: subchecker.v1.SubService.UpdateSub:output_type -> subchecker.v1.UpdateSubResponse
+	9,  // 15: subchecker.v1.SubService.DeleteSub:output_type -> subchecker.v1.DeleteSubResponse
+	11, // 16: subchecker.v1.SubService.ListSubs:output_type -> subchecker.v1.ListSubsResponse
+	13, // 17: subchecker.v1.SubService.SumCost:output_type -> subchecker.v1.SumCostResponse
+	12, // [12:18] is the sub-list for method output_type
+	6,  // [6:12] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_subchecker_v1_subs_proto_init() }
+func file_subchecker_v1_subs_proto_init() {
+	if File_subchecker_v1_subs_proto != nil {
+		return
+	}
+	file_subchecker_v1_subs_proto_msgTypes[3].OneofWrappers = []any{}
+	file_subchecker_v1_subs_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_subchecker_v1_subs_proto_rawDesc), len(file_subchecker_v1_subs_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_subchecker_v1_subs_proto_goTypes,
+		DependencyIndexes: file_subchecker_v1_subs_proto_depIdxs,
+		MessageInfos:      file_subchecker_v1_subs_proto_msgTypes,
+	}.Build()
+	File_subchecker_v1_subs_proto = out.File
+	file_subchecker_v1_subs_proto_goTypes = nil
+	file_subchecker_v1_subs_proto_depIdxs = nil
+}
@@ -0,0 +1,322 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: subchecker/v1/subs.proto
+
+// gRPC API of SubChecker, it mirrors the REST /subs endpoints.
+// Months are "MM-YYYY" like in REST requests.
+
+package subcheckerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SubService_CreateSub_FullMethodName = "/subchecker.v1.SubService/CreateSub"
+	SubService_GetSub_FullMethodName    = "/subchecker.v1.SubService/GetSub"
+	SubService_UpdateSub_FullMethodName = "/subchecker.v1.SubService/UpdateSub"
+	SubService_DeleteSub_FullMethodName = "/subchecker.v1.SubService/DeleteSub"
+	SubService_ListSubs_FullMethodName  = "/subchecker.v1.SubService/ListSubs"
+	SubService_SumCost_FullMethodName   = "/subchecker.v1.SubService/SumCost"
+)
+
+// SubServiceClient is the client API for SubService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SubServiceClient interface {
+	// CreateSub refers to a service by service_id or by service_name, a new
+	// name is added to the catalog. Without price the default price of the
+	// service is used.
+	CreateSub(ctx context.Context, in *CreateSubRequest, opts ...grpc.CallOption) (*CreateSubResponse, error)
+	GetSub(ctx context.Context, in *GetSubRequest, opts ...grpc.CallOption) (*GetSubResponse, error)
+	UpdateSub(ctx context.Context, in *UpdateSubRequest, opts ...grpc.CallOption) (*UpdateSubResponse, error)
+	DeleteSub(ctx context.Context, in *DeleteSubRequest, opts ...grpc.CallOption) (*DeleteSubResponse, error)
+	ListSubs(ctx context.Context, in *ListSubsRequest, opts ...grpc.CallOption) (*ListSubsResponse, error)
+	// SumCost is the total cost of subscriptions for a period, months inclusive
+	SumCost(ctx context.Context, in *SumCostRequest, opts ...grpc.CallOption) (*SumCostResponse, error)
+}
+
+type subServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSubServiceClient(cc grpc.ClientConnInterface) SubServiceClient {
+	return &subServiceClient{cc}
+}
+
+func (c *subServiceClient) CreateSub(ctx context.Context, in *CreateSubRequest, opts ...grpc.CallOption) (*CreateSubResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateSubResponse)
+	err := c.cc.Invoke(ctx, SubService_CreateSub_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *subServiceClient) GetSub(ctx context.Context, in *GetSubRequest, opts ...grpc.CallOption) (*GetSubResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSubResponse)
+	err := c.cc.Invoke(ctx, SubService_GetSub_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *subServiceClient) UpdateSub(ctx context.Context, in *UpdateSubRequest, opts ...grpc.CallOption) (*UpdateSubResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateSubResponse)
+	err := c.cc.Invoke(ctx, SubService_UpdateSub_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *subServiceClient) DeleteSub(ctx context.Context, in *DeleteSubRequest, opts ...grpc.CallOption) (*DeleteSubResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSubResponse)
+	err := c.cc.Invoke(ctx, SubService_DeleteSub_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *subServiceClient) ListSubs(ctx context.Context, in *ListSubsRequest, opts ...grpc.CallOption) (*ListSubsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSubsResponse)
+	err := c.cc.Invoke(ctx, SubService_ListSubs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *subServiceClient) SumCost(ctx context.Context, in *SumCostRequest, opts ...grpc.CallOption) (*SumCostResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SumCostResponse)
+	err := c.cc.Invoke(ctx, SubService_SumCost_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SubServiceServer is the server API for SubService service.
+// All implementations must embed UnimplementedSubServiceServer
+// for forward compatibility.
+type SubServiceServer interface {
+	// CreateSub refers to a service by service_id or by service_name, a new
+	// name is added to the catalog. Without price the default price of the
+	// service is used.
+	CreateSub(context.Context, *CreateSubRequest) (*CreateSubResponse, error)
+	GetSub(context.Context, *GetSubRequest) (*GetSubResponse, error)
+	UpdateSub(context.Context, *UpdateSubRequest) (*UpdateSubResponse, error)
+	DeleteSub(context.Context, *DeleteSubRequest) (*DeleteSubResponse, error)
+	ListSubs(context.Context, *ListSubsRequest) (*ListSubsResponse, error)
+	// SumCost is the total cost of subscriptions for a period, months inclusive
+	SumCost(context.Context, *SumCostRequest) (*SumCostResponse, error)
+	mustEmbedUnimplementedSubServiceServer()
+}
+
+// UnimplementedSubServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSubServiceServer struct{}
+
+func (UnimplementedSubServiceServer) CreateSub(context.Context, *CreateSubRequest) (*CreateSubResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSub not implemented")
+}
+func (UnimplementedSubServiceServer) GetSub(context.Context, *GetSubRequest) (*GetSubResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSub not implemented")
+}
+func (UnimplementedSubServiceServer) UpdateSub(context.Context, *UpdateSubRequest) (*UpdateSubResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSub not implemented")
+}
+func (UnimplementedSubServiceServer) DeleteSub(context.Context, *DeleteSubRequest) (*DeleteSubResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSub not implemented")
+}
+func (UnimplementedSubServiceServer) ListSubs(context.Context, *ListSubsRequest) (*ListSubsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSubs not implemented")
+}
+func (UnimplementedSubServiceServer) SumCost(context.Context, *SumCostRequest) (*SumCostResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SumCost not implemented")
+}
+func (UnimplementedSubServiceServer) mustEmbedUnimplementedSubServiceServer() {}
+func (UnimplementedSubServiceServer) testEmbeddedByValue()                    {}
+
+// UnsafeSubServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SubServiceServer will
+// result in compilation errors.
+type UnsafeSubServiceServer interface {
+	mustEmbedUnimplementedSubServiceServer()
+}
+
+func RegisterSubServiceServer(s grpc.ServiceRegistrar, srv SubServiceServer) {
+	// If the following call pancis, it indicates UnimplementedSubServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SubService_ServiceDesc, srv)
+}
+
+func _SubService_CreateSub_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSubRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubServiceServer).CreateSub(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SubService_CreateSub_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubServiceServer).CreateSub(ctx, req.(*CreateSubRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubService_GetSub_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSubRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubServiceServer).GetSub(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SubService_GetSub_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubServiceServer).GetSub(ctx, req.(*GetSubRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubService_UpdateSub_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSubRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubServiceServer).UpdateSub(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SubService_UpdateSub_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubServiceServer).UpdateSub(ctx, req.(*UpdateSubRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubService_DeleteSub_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSubRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubServiceServer).DeleteSub(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SubService_DeleteSub_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubServiceServer).DeleteSub(ctx, req.(*DeleteSubRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubService_ListSubs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSubsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubServiceServer).ListSubs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SubService_ListSubs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubServiceServer).ListSubs(ctx, req.(*ListSubsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SubService_SumCost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SumCostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SubServiceServer).SumCost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SubService_SumCost_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SubServiceServer).SumCost(ctx, req.(*SumCostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SubService_ServiceDesc is the grpc.ServiceDesc for SubService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SubService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "subchecker.v1.SubService",
+	HandlerType: (*SubServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateSub",
+			Handler:    _SubService_CreateSub_Handler,
+		},
+		{
+			MethodName: "GetSub",
+			Handler:    _SubService_GetSub_Handler,
+		},
+		{
+			MethodName: "UpdateSub",
+			Handler:    _SubService_UpdateSub_Handler,
+		},
+		{
+			MethodName: "DeleteSub",
+			Handler:    _SubService_DeleteSub_Handler,
+		},
+		{
+			MethodName: "ListSubs",
+			Handler:    _SubService_ListSubs_Handler,
+		},
+		{
+			MethodName: "SumCost",
+			Handler:    _SubService_SumCost_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "subchecker/v1/subs.proto",
+}
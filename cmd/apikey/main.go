@@ -0,0 +1,129 @@
+// Command apikey manages API keys of a SubChecker database.
+//
+//	apikey issue -name NAME [-org ORG] -scopes read,write
+//	apikey revoke -id ID
+//	apikey list
+//
+// It reads the same .env as the server.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/config"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+	"github.com/tmozzze/SubChecker/internal/service"
+	"github.com/tmozzze/SubChecker/internal/storage"
+)
+
+var errUsage = errors.New(`usage:
+  apikey issue -name NAME [-org ORG] -scopes read,write,admin
+  apikey revoke -id ID
+  apikey list`)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	store, err := storage.Open(ctx, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer store.Close()
+
+	if !store.Persistent() {
+		return fmt.Errorf("api keys need a persistent DB_DRIVER, got %q", cfg.DBDriver)
+	}
+
+	keys := service.NewApiKeyService(store.Repos.ApiKeys, logger)
+
+	switch args[0] {
+	case "issue":
+		fs := flag.NewFlagSet("issue", flag.ContinueOnError)
+		name := fs.String("name", "", "key name, e.g. the client that uses it")
+		org := fs.String("org", reqctx.DefaultOrgId, "organization the key acts in")
+		scopes := fs.String("scopes", "read", "comma-separated scopes: read, write, admin")
+		if err := fs.Parse(args[1:]); err != nil {
+			return errUsage
+		}
+		if *name == "" {
+			return errors.New("-name is required")
+		}
+
+		key, k, err := keys.Issue(ctx, *name, *org, strings.Split(*scopes, ","))
+		if err != nil {
+			return fmt.Errorf("failed to issue key: %w", err)
+		}
+		fmt.Printf("Issued key %d (%s) for org %s with scopes %s\n", k.KeyId, k.Name, k.OrgId, strings.Join(k.Scopes, ","))
+		fmt.Println("Store it now, it can't be shown again:")
+		fmt.Println(key)
+
+	case "revoke":
+		fs := flag.NewFlagSet("revoke", flag.ContinueOnError)
+		id := fs.Int("id", 0, "key id")
+		if err := fs.Parse(args[1:]); err != nil {
+			return errUsage
+		}
+		if *id == 0 {
+			return errors.New("-id is required")
+		}
+
+		if err := keys.Revoke(ctx, *id); err != nil {
+			return fmt.Errorf("failed to revoke key %d: %w", *id, err)
+		}
+		fmt.Printf("Revoked key %d\n", *id)
+
+	case "list":
+		list, err := keys.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list keys: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tORG\tPREFIX\tSCOPES\tCREATED\tREVOKED")
+		for _, k := range list {
+			revoked := "-"
+			if k.RevokedAt != nil {
+				revoked = k.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				k.KeyId, k.Name, k.OrgId, k.Prefix, strings.Join(k.Scopes, ","), k.CreatedAt.Format(time.RFC3339), revoked)
+		}
+		return w.Flush()
+
+	default:
+		return errUsage
+	}
+
+	return nil
+}
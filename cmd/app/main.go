@@ -2,7 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	swaggerFiles "github.com/swaggo/files"
@@ -11,18 +17,48 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/auth"
 	"github.com/tmozzze/SubChecker/internal/config"
-	database "github.com/tmozzze/SubChecker/internal/db"
+	"github.com/tmozzze/SubChecker/internal/gql"
+	"github.com/tmozzze/SubChecker/internal/grpcapi"
+	"github.com/tmozzze/SubChecker/internal/health"
 	httpHandler "github.com/tmozzze/SubChecker/internal/http"
+	"github.com/tmozzze/SubChecker/internal/metrics"
+	"github.com/tmozzze/SubChecker/internal/policy"
+	"github.com/tmozzze/SubChecker/internal/reminder"
 	"github.com/tmozzze/SubChecker/internal/repository"
 	"github.com/tmozzze/SubChecker/internal/service"
+	"github.com/tmozzze/SubChecker/internal/storage"
+	"github.com/tmozzze/SubChecker/internal/stream"
+	"github.com/tmozzze/SubChecker/internal/webhook"
+	"google.golang.org/grpc"
 )
 
 // @title SubChecker API
 // @version 1.0
 // @description REST service for subscription aggregation
 // @host localhost:8080
-// @BasePath /
+// @BasePath /api/v1
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT as "Bearer <token>"
+
+// Prefixes of the API versions
+const (
+	apiV1 = "/api/v1"
+	apiV2 = "/api/v2"
+)
+
+// legacyDeprecatedAt is when the unprefixed paths were deprecated
+var legacyDeprecatedAt = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+// readyTimeout limits the checks of /readyz
+const readyTimeout = 2 * time.Second
+
 func main() {
 	// Logger
 	logger := logrus.New()
@@ -36,49 +72,197 @@ func main() {
 	}
 	logger.Info("Config is OK")
 
-	// Context
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	if err := run(cfg, logger); err != nil {
+		logger.WithError(err).Fatal("server failed")
+	}
+	logger.Info("Server stopped")
+}
+
+// run serves until SIGTERM or SIGINT and then shuts down: stops accepting
+// connections, drains in-flight requests, stops background workers and
+// closes the storage
+func run(cfg *config.Config, logger *logrus.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// DB
-	db, err := database.NewDB(ctx, cfg)
+	// Storage
+	openCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	store, err := storage.Open(openCtx, cfg, logger)
 	if err != nil {
-		logger.WithError(err).Fatal("db failed:", err)
+		return fmt.Errorf("storage failed: %w", err)
 	}
-	defer db.Pool.Close()
+	defer store.Close()
+	repos := store.Repos
 
-	logger.Infof("Connected to %s on port %s", cfg.DBName, cfg.DBPort)
+	// Access policy
+	pol := policy.Default()
+	if cfg.AuthPolicyFile != "" {
+		pol, err = policy.Load(cfg.AuthPolicyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load access policy: %w", err)
+		}
+	}
 
-	// Migrations
-	err = database.RunMigration(ctx, db.Pool, cfg, logger)
-	if err != nil {
-		logger.WithError(err).Fatal("failed to migrate db")
+	// Metrics
+	mx := metrics.New()
+	if store.Pool != nil {
+		mx.RegisterPool(store.Pool)
 	}
+	mx.RegisterSubs(repos.Subs, repository.NewOrgSelector(repos.Orgs, nil, "metrics", logger), logger)
 
-	// Repository
-	repo := repository.NewSubRepository(db.Pool, logger)
+	// Readiness
+	checker := health.NewChecker(readyTimeout)
+	if store.Persistent() {
+		checker.Add("database", store.Ping)
+		checker.Add("migrations", store.CheckMigrations)
+	}
+
+	// Background workers run until the servers are stopped
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	running := health.NewWorkers()
+	checker.Add("workers", running.Check)
+	startWorker := func(name string, run func(context.Context)) {
+		workers.Add(1)
+		running.Go(name, func() {
+			defer workers.Done()
+			run(workersCtx)
+		})
+	}
 
 	// Service
-	svc := service.NewSubService(repo, logger)
+	// With Postgres every instance streams changes notified by the database,
+	// including those made through other instances
+	broker := stream.NewBroker(cfg.SubEventsBuffer)
+	var subEvents stream.Hub = broker
+	if store.Pool != nil {
+		subEvents = stream.Remote(broker)
+		listener := stream.NewListener(store.Pool, broker, logger)
+		checker.Add("sub_changes", listener.Check)
+		startWorker("sub_changes", listener.Run)
+	}
+	svc := service.NewPolicySubService(service.NewSubService(repos.Subs, repos.Users, repos.Services, repos.PriceChanges, repos.Webhooks, subEvents, repos.Tx, mx, cfg.SubsOverlapCheck, logger), pol, logger)
+	users := service.NewUserService(repos.Users, logger)
+	catalog := service.NewCatalogService(repos.Services, logger)
+	budgets := service.NewBudgetService(repos.Budgets, svc, pol, logger)
+	apiKeys := service.NewApiKeyService(repos.ApiKeys, logger)
+	webhooks := service.NewWebhookService(repos.Webhooks, logger)
+
+	// Auth
+	var authenticators []auth.Authenticator
+	if cfg.JWTEnabled() {
+		jwtAuth, err := auth.NewJWTAuthenticator(auth.JWTConfig{
+			HS256Secret: cfg.JWTHS256Secret,
+			JWKSFile:    cfg.JWTJWKSFile,
+			Issuer:      cfg.JWTIssuer,
+			Audience:    cfg.JWTAudience,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set up JWT auth: %w", err)
+		}
+		authenticators = append(authenticators, jwtAuth)
+	}
+	if cfg.AuthApiKeys {
+		if !store.Persistent() {
+			logger.Warn("API key auth is on with in-memory storage, no key can be issued")
+		}
+		authenticators = append(authenticators, auth.NewApiKeyAuthenticator(apiKeys))
+	}
+	if len(authenticators) == 0 {
+		logger.Warn("Authentication is disabled, the API is open to everyone")
+	}
 
 	// Hanlders
 	handler := httpHandler.NewSubHandler(svc, logger)
+	userHandler := httpHandler.NewUserHandler(users, logger)
+	catalogHandler := httpHandler.NewCatalogHandler(catalog, logger)
+	budgetHandler := httpHandler.NewBudgetHandler(budgets, logger)
+	webhookHandler := httpHandler.NewWebhookHandler(webhooks, logger)
+	schema, err := gql.NewSchema(svc, users, cfg.GraphQLMaxDepth, logger)
+	if err != nil {
+		return fmt.Errorf("failed to parse GraphQL schema: %w", err)
+	}
+	graphqlHandler := httpHandler.NewGraphQLHandler(schema, logger)
 
 	// Router
 	router := gin.Default()
+	router.Use(httpHandler.RequestContext(), httpHandler.Metrics(mx))
+
+	// Probes
+	healthHandler := httpHandler.NewHealthHandler(checker)
+	router.GET("/healthz", healthHandler.Live)
+	router.GET("/readyz", healthHandler.Ready)
+	router.GET("/metrics", gin.WrapH(mx.Handler()))
 
 	// Swagger UI
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// CRUD + SUM
-	subs := router.Group("/subs")
-	{
-		subs.POST("", handler.CreateSub)
-		subs.GET("", handler.ListSubs)
-		subs.GET("/:sub_id", handler.GetSubById)
-		subs.PUT("/:sub_id", handler.UpdateSub)
-		subs.DELETE("/:sub_id", handler.DeleteSub)
-		subs.GET("/sum", handler.SumCost)
+	// API
+	h := &handlers{
+		authenticate: httpHandler.Authenticate(logger, authenticators...),
+		subs:         handler,
+		users:        userHandler,
+		catalog:      catalogHandler,
+		budgets:      budgetHandler,
+		webhooks:     webhookHandler,
+		graphql:      graphqlHandler,
+	}
+	registerV1(router.Group(apiV1), h)
+	registerV2(router.Group(apiV2), h)
+	// Legacy root paths are aliases of v1 until the sunset
+	registerV1(router.Group("", httpHandler.Deprecated(legacyDeprecatedAt, cfg.LegacySunset, apiV1)), h)
+
+	// Webhooks
+	if cfg.WebhooksEnabled {
+		orgs := repository.NewOrgSelector(repos.Orgs, cfg.WebhookOrgs, "webhooks", logger)
+		dispatcher := webhook.NewDispatcher(repos.Webhooks, orgs, cfg.WebhookPollInterval,
+			cfg.WebhookRetryBackoff, cfg.WebhookMaxAttempts, logger)
+		startWorker("webhooks", dispatcher.Run)
+	}
+
+	// Reminders
+	if cfg.RemindersEnabled {
+		var notifiers []reminder.Notifier
+		for _, name := range cfg.ReminderNotifiers {
+			switch name {
+			case "log":
+				notifiers = append(notifiers, reminder.NewLogNotifier(logger))
+			case "webhook":
+				notifiers = append(notifiers, reminder.NewWebhookNotifier(cfg.ReminderWebhookURL))
+			case "smtp":
+				notifiers = append(notifiers, reminder.NewSMTPNotifier(reminder.SMTPConfig{
+					Addr:     cfg.SMTPAddr,
+					From:     cfg.SMTPFrom,
+					To:       cfg.SMTPTo,
+					Username: cfg.SMTPUsername,
+					Password: cfg.SMTPPassword,
+				}))
+			}
+		}
+		orgs := repository.NewOrgSelector(repos.Orgs, cfg.ReminderOrgs, "reminders", logger)
+		scheduler := reminder.NewScheduler(repos.Subs, repos.Reminders, notifiers,
+			orgs, cfg.ReminderLead, cfg.ReminderInterval, logger)
+		startWorker("reminders", scheduler.Run)
+	}
+
+	serverErr := make(chan error, 2)
+
+	// gRPC
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != "" {
+		lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		grpcServer = grpcapi.NewServer(svc, logger, authenticators...)
+		go func() {
+			logger.Infof("Starting gRPC server on %s", lis.Addr())
+			if err := grpcServer.Serve(lis); err != nil {
+				serverErr <- fmt.Errorf("gRPC server failed: %w", err)
+			}
+		}()
 	}
 
 	// Start
@@ -87,9 +271,75 @@ func main() {
 		port = "8080"
 	}
 
-	addr := fmt.Sprintf(":%s", port)
-	logger.Infof("Starting server on %s", addr)
-	if err := router.Run(addr); err != nil {
-		logger.Fatal("failed to start server:", err)
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%s", port),
+		Handler:      router,
+		ReadTimeout:  cfg.ServerReadTimeout,
+		WriteTimeout: cfg.ServerWriteTimeout,
+		IdleTimeout:  cfg.ServerIdleTimeout,
+	}
+	// Shutdown waits for streams, so they are ended first
+	srv.RegisterOnShutdown(broker.Close)
+	go func() {
+		logger.Infof("Starting server on %s", srv.Addr)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- fmt.Errorf("failed to start server: %w", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutting down")
+	case err = <-serverErr:
+		logger.WithError(err).Error("Server failed, shutting down")
+	}
+	stop()
+
+	// Keep serving while the orchestrator notices that the instance isn't ready
+	checker.Drain()
+	if cfg.ShutdownDelay > 0 {
+		logger.Infof("Not ready, waiting %s before stopping the servers", cfg.ShutdownDelay)
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+
+	var servers sync.WaitGroup
+	if grpcServer != nil {
+		servers.Add(1)
+		go func() {
+			defer servers.Done()
+			// Cut off calls still running at the deadline
+			stopped := make(chan struct{})
+			go func() {
+				select {
+				case <-shutdownCtx.Done():
+					grpcServer.Stop()
+				case <-stopped:
+				}
+			}()
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+	}
+	if shutdownErr := srv.Shutdown(shutdownCtx); shutdownErr != nil {
+		logger.WithError(shutdownErr).Warn("Requests were not drained in time, closing connections")
+		srv.Close()
 	}
+	servers.Wait()
+
+	stopWorkers()
+	drained := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-shutdownCtx.Done():
+		logger.Warn("Background workers did not stop in time")
+	}
+
+	return err
 }
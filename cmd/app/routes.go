@@ -0,0 +1,90 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tmozzze/SubChecker/internal/auth"
+	httpHandler "github.com/tmozzze/SubChecker/internal/http"
+)
+
+// handlers are what API versions are built of. A new version gets its own
+// register function, reusing the handlers of endpoints that didn't change.
+type handlers struct {
+	authenticate gin.HandlerFunc
+	subs         *httpHandler.SubHandler
+	users        *httpHandler.UserHandler
+	catalog      *httpHandler.CatalogHandler
+	budgets      *httpHandler.BudgetHandler
+	webhooks     *httpHandler.WebhookHandler
+	graphql      *httpHandler.GraphQLHandler
+}
+
+// registerV1 mounts the v1 API on g, at /api/v1 and at the legacy root paths
+func registerV1(g *gin.RouterGroup, h *handlers) {
+	read := httpHandler.RequireScope(auth.ScopeRead)
+	write := httpHandler.RequireScope(auth.ScopeWrite)
+	admin := httpHandler.RequireScope(auth.ScopeAdmin)
+
+	subs := g.Group("/subs", h.authenticate)
+	{
+		subs.POST("", write, h.subs.CreateSub)
+		subs.GET("", read, h.subs.ListSubs)
+		subs.GET("/:sub_id", read, h.subs.GetSubById)
+		subs.PUT("/:sub_id", write, h.subs.UpdateSub)
+		subs.DELETE("/:sub_id", write, h.subs.DeleteSub)
+		subs.GET("/:sub_id/history", read, h.subs.GetSubHistory)
+		subs.GET("/sum", read, h.subs.SumCost)
+		subs.GET("/sum/categories", read, h.subs.SumByCategory)
+		subs.GET("/forecast", read, h.subs.Forecast)
+		subs.GET("/overlaps", read, h.subs.Overlaps)
+		subs.GET("/events", read, h.subs.SubEvents)
+		subs.POST("/:sub_id/price-changes", write, h.subs.SchedulePrice)
+		subs.GET("/:sub_id/price-changes", read, h.subs.ListPriceChanges)
+		subs.DELETE("/:sub_id/price-changes/:change_id", write, h.subs.CancelPriceChange)
+	}
+
+	users := g.Group("/users", h.authenticate)
+	{
+		users.POST("", admin, h.users.CreateUser)
+		users.GET("", read, h.users.ListUsers)
+		users.GET("/:user_id", read, h.users.GetUserById)
+		users.PUT("/:user_id", admin, h.users.UpdateUser)
+		users.DELETE("/:user_id", admin, h.users.DeleteUser)
+	}
+
+	services := g.Group("/services", h.authenticate)
+	{
+		services.POST("", write, h.catalog.CreateService)
+		services.GET("", read, h.catalog.ListServices)
+		services.GET("/:service_id", read, h.catalog.GetServiceById)
+		services.PUT("/:service_id", write, h.catalog.UpdateService)
+		services.DELETE("/:service_id", write, h.catalog.DeleteService)
+	}
+
+	budgets := g.Group("/budgets", h.authenticate)
+	{
+		budgets.POST("", write, h.budgets.CreateBudget)
+		budgets.GET("", read, h.budgets.ListBudgets)
+		budgets.GET("/:budget_id", read, h.budgets.GetBudgetById)
+		budgets.PUT("/:budget_id", write, h.budgets.UpdateBudget)
+		budgets.DELETE("/:budget_id", write, h.budgets.DeleteBudget)
+		budgets.GET("/:budget_id/report", read, h.budgets.GetBudgetReport)
+	}
+
+	webhooks := g.Group("/webhooks", h.authenticate)
+	{
+		webhooks.POST("", admin, h.webhooks.CreateWebhook)
+		webhooks.GET("", admin, h.webhooks.ListWebhooks)
+		webhooks.GET("/:webhook_id", admin, h.webhooks.GetWebhookById)
+		webhooks.DELETE("/:webhook_id", admin, h.webhooks.DeleteWebhook)
+		webhooks.GET("/:webhook_id/deliveries", admin, h.webhooks.ListWebhookDeliveries)
+	}
+
+	g.POST("/graphql", h.authenticate, read, h.graphql.Query)
+}
+
+// registerV2 mounts the v2 API on g. It is v1 with collections wrapped in
+// an envelope with the page and links to the neighbouring pages.
+func registerV2(g *gin.RouterGroup, h *handlers) {
+	g.Use(httpHandler.Envelope())
+	registerV1(g, h)
+}
@@ -0,0 +1,5 @@
+-- 000002_create_sub_audit.down.sql
+
+DROP TRIGGER IF EXISTS sub_audit_append_only ON sub_audit;
+DROP FUNCTION IF EXISTS sub_audit_append_only();
+DROP TABLE IF EXISTS sub_audit;
@@ -0,0 +1,26 @@
+-- 000002_create_sub_audit.up.sql
+
+CREATE TABLE IF NOT EXISTS sub_audit (
+    audit_id BIGSERIAL PRIMARY KEY,
+    sub_id INT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before_state JSONB NULL,
+    after_state JSONB NULL,
+    actor TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS sub_audit_sub_id_idx ON sub_audit (sub_id, audit_id);
+
+-- Audit log is append-only: updates and deletes are rejected
+CREATE OR REPLACE FUNCTION sub_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'sub_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sub_audit_append_only ON sub_audit;
+CREATE TRIGGER sub_audit_append_only
+    BEFORE UPDATE OR DELETE ON sub_audit
+    FOR EACH ROW EXECUTE FUNCTION sub_audit_append_only();
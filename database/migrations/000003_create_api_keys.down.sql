@@ -0,0 +1,3 @@
+-- 000003_create_api_keys.down.sql
+
+DROP TABLE IF EXISTS api_keys;
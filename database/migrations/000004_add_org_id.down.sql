@@ -0,0 +1,16 @@
+-- 000004_add_org_id.down.sql
+
+DROP POLICY IF EXISTS sub_audit_org_isolation ON sub_audit;
+ALTER TABLE sub_audit NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE sub_audit DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS subs_org_isolation ON subs;
+ALTER TABLE subs NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE subs DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX IF EXISTS subs_org_id_user_id_idx;
+DROP INDEX IF EXISTS subs_org_id_idx;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS org_id;
+ALTER TABLE sub_audit DROP COLUMN IF EXISTS org_id;
+ALTER TABLE subs DROP COLUMN IF EXISTS org_id;
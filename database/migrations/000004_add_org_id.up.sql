@@ -0,0 +1,27 @@
+-- 000004_add_org_id.up.sql
+
+-- Every row belongs to an organization (tenant). Rows created before
+-- multi-tenancy go to the default one.
+ALTER TABLE subs ADD COLUMN IF NOT EXISTS org_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE sub_audit ADD COLUMN IF NOT EXISTS org_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS org_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS subs_org_id_idx ON subs (org_id, sub_id);
+CREATE INDEX IF NOT EXISTS subs_org_id_user_id_idx ON subs (org_id, user_id);
+
+-- Row-level security: a session only sees rows of the organization set with
+-- set_config('app.org_id', ...). Without it no rows are visible.
+-- Superusers and roles with BYPASSRLS are not restricted.
+ALTER TABLE subs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subs FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS subs_org_isolation ON subs;
+CREATE POLICY subs_org_isolation ON subs
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
+
+ALTER TABLE sub_audit ENABLE ROW LEVEL SECURITY;
+ALTER TABLE sub_audit FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS sub_audit_org_isolation ON sub_audit;
+CREATE POLICY sub_audit_org_isolation ON sub_audit
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
@@ -0,0 +1,21 @@
+-- 000005_create_users_services.down.sql
+
+ALTER TABLE subs NO FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE subs ADD COLUMN IF NOT EXISTS service_name TEXT;
+
+UPDATE subs s SET service_name = sv.name
+FROM services sv
+WHERE sv.service_id = s.service_id;
+
+ALTER TABLE subs ALTER COLUMN service_name SET NOT NULL;
+
+DROP INDEX IF EXISTS subs_org_id_service_id_idx;
+ALTER TABLE subs DROP CONSTRAINT IF EXISTS subs_user_fk;
+ALTER TABLE subs DROP CONSTRAINT IF EXISTS subs_service_fk;
+ALTER TABLE subs DROP COLUMN IF EXISTS service_id;
+
+ALTER TABLE subs FORCE ROW LEVEL SECURITY;
+
+DROP TABLE IF EXISTS services;
+DROP TABLE IF EXISTS users;
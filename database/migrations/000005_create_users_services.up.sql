@@ -0,0 +1,71 @@
+-- 000005_create_users_services.up.sql
+
+CREATE TABLE IF NOT EXISTS users (
+    org_id TEXT NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+-- name_key is the name in lower case with collapsed whitespace,
+-- so "Netflix", "netflix " and "NETFLIX" are one service
+CREATE TABLE IF NOT EXISTS services (
+    service_id SERIAL PRIMARY KEY,
+    org_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    name_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (org_id, name_key),
+    UNIQUE (org_id, service_id)
+);
+
+-- Row-level security must not hide existing subscriptions from the migration
+ALTER TABLE subs NO FORCE ROW LEVEL SECURITY;
+
+INSERT INTO users (org_id, user_id)
+SELECT DISTINCT org_id, user_id FROM subs
+ON CONFLICT DO NOTHING;
+
+-- A service is named after its earliest subscription
+INSERT INTO services (org_id, name, name_key)
+SELECT DISTINCT ON (org_id, name_key) org_id, regexp_replace(btrim(service_name), '\s+', ' ', 'g'), name_key
+FROM (
+    SELECT sub_id, org_id, service_name, lower(regexp_replace(btrim(service_name), '\s+', ' ', 'g')) AS name_key
+    FROM subs
+) s
+ORDER BY org_id, name_key, sub_id
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE subs ADD COLUMN IF NOT EXISTS service_id INT;
+
+UPDATE subs s SET service_id = sv.service_id
+FROM services sv
+WHERE sv.org_id = s.org_id
+  AND sv.name_key = lower(regexp_replace(btrim(s.service_name), '\s+', ' ', 'g'));
+
+ALTER TABLE subs ALTER COLUMN service_id SET NOT NULL;
+ALTER TABLE subs DROP COLUMN service_name;
+
+ALTER TABLE subs ADD CONSTRAINT subs_service_fk
+    FOREIGN KEY (org_id, service_id) REFERENCES services (org_id, service_id);
+ALTER TABLE subs ADD CONSTRAINT subs_user_fk
+    FOREIGN KEY (org_id, user_id) REFERENCES users (org_id, user_id);
+
+CREATE INDEX IF NOT EXISTS subs_org_id_service_id_idx ON subs (org_id, service_id);
+
+ALTER TABLE subs FORCE ROW LEVEL SECURITY;
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS users_org_isolation ON users;
+CREATE POLICY users_org_isolation ON users
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
+
+ALTER TABLE services ENABLE ROW LEVEL SECURITY;
+ALTER TABLE services FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS services_org_isolation ON services;
+CREATE POLICY services_org_isolation ON services
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
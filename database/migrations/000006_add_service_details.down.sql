@@ -0,0 +1,10 @@
+-- 000006_add_service_details.down.sql
+
+DROP INDEX IF EXISTS services_org_id_category_idx;
+
+ALTER TABLE services
+    DROP COLUMN IF EXISTS logo_url,
+    DROP COLUMN IF EXISTS website,
+    DROP COLUMN IF EXISTS billing_period,
+    DROP COLUMN IF EXISTS default_price,
+    DROP COLUMN IF EXISTS category;
@@ -0,0 +1,11 @@
+-- 000006_add_service_details.up.sql
+
+-- default_price is per billing period, NULL when the service has no usual price
+ALTER TABLE services
+    ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS default_price INT NULL CHECK (default_price >= 0),
+    ADD COLUMN IF NOT EXISTS billing_period TEXT NOT NULL DEFAULT 'month' CHECK (billing_period IN ('month', 'year')),
+    ADD COLUMN IF NOT EXISTS website TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS logo_url TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS services_org_id_category_idx ON services (org_id, category);
@@ -0,0 +1,21 @@
+-- 000007_create_budgets.up.sql
+
+-- A monthly budget of one user or of one team from the access policy file
+CREATE TABLE IF NOT EXISTS budgets (
+    budget_id SERIAL PRIMARY KEY,
+    org_id TEXT NOT NULL,
+    user_id UUID NULL,
+    team TEXT NULL,
+    amount INT NOT NULL CHECK (amount >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((user_id IS NULL) <> (team IS NULL)),
+    UNIQUE (org_id, user_id),
+    UNIQUE (org_id, team)
+);
+
+ALTER TABLE budgets ENABLE ROW LEVEL SECURITY;
+ALTER TABLE budgets FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS budgets_org_isolation ON budgets;
+CREATE POLICY budgets_org_isolation ON budgets
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
@@ -0,0 +1,3 @@
+-- 000008_create_sub_price_changes.down.sql
+
+DROP TABLE IF EXISTS sub_price_changes;
@@ -0,0 +1,19 @@
+-- 000008_create_sub_price_changes.up.sql
+
+-- A price a subscription will have from effective_month on
+CREATE TABLE IF NOT EXISTS sub_price_changes (
+    change_id SERIAL PRIMARY KEY,
+    org_id TEXT NOT NULL,
+    sub_id INT NOT NULL REFERENCES subs (sub_id) ON DELETE CASCADE,
+    effective_month DATE NOT NULL,
+    price INT NOT NULL CHECK (price >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (sub_id, effective_month)
+);
+
+ALTER TABLE sub_price_changes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE sub_price_changes FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS sub_price_changes_org_isolation ON sub_price_changes;
+CREATE POLICY sub_price_changes_org_isolation ON sub_price_changes
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
@@ -0,0 +1,20 @@
+-- 000009_create_sent_reminders.up.sql
+
+-- A reminder sent through one channel, so that it isn't sent twice
+CREATE TABLE IF NOT EXISTS sent_reminders (
+    reminder_id BIGSERIAL PRIMARY KEY,
+    org_id TEXT NOT NULL,
+    sub_id INT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('renewal', 'end')),
+    due_date DATE NOT NULL,
+    channel TEXT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (org_id, sub_id, kind, due_date, channel)
+);
+
+ALTER TABLE sent_reminders ENABLE ROW LEVEL SECURITY;
+ALTER TABLE sent_reminders FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS sent_reminders_org_isolation ON sent_reminders;
+CREATE POLICY sent_reminders_org_isolation ON sent_reminders
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
@@ -0,0 +1,68 @@
+-- 000010_create_webhooks.up.sql
+
+-- An endpoint subscription events are POSTed to, events is empty for every event
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    endpoint_id SERIAL PRIMARY KEY,
+    org_id TEXT NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Transactional outbox: an event is written with the change it describes
+-- and fanned out to endpoints after commit
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    event_id BIGSERIAL PRIMARY KEY,
+    org_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    sub_id INT NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    dispatched_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_outbox_undispatched_idx
+    ON webhook_outbox (org_id, event_id) WHERE dispatched_at IS NULL;
+
+-- Sending of one event to one endpoint, also the delivery log
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id BIGSERIAL PRIMARY KEY,
+    org_id TEXT NOT NULL,
+    event_id BIGINT NOT NULL REFERENCES webhook_outbox (event_id) ON DELETE CASCADE,
+    endpoint_id INT NOT NULL REFERENCES webhook_endpoints (endpoint_id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    response_status INT NULL,
+    last_error TEXT NULL,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (event_id, endpoint_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (org_id, next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_idx
+    ON webhook_deliveries (endpoint_id, delivery_id);
+
+ALTER TABLE webhook_endpoints ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_endpoints FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS webhook_endpoints_org_isolation ON webhook_endpoints;
+CREATE POLICY webhook_endpoints_org_isolation ON webhook_endpoints
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
+
+ALTER TABLE webhook_outbox ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_outbox FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS webhook_outbox_org_isolation ON webhook_outbox;
+CREATE POLICY webhook_outbox_org_isolation ON webhook_outbox
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
+
+ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_deliveries FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS webhook_deliveries_org_isolation ON webhook_deliveries;
+CREATE POLICY webhook_deliveries_org_isolation ON webhook_deliveries
+    USING (org_id = current_setting('app.org_id', true))
+    WITH CHECK (org_id = current_setting('app.org_id', true));
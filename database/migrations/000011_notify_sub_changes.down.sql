@@ -0,0 +1,5 @@
+-- 000011_notify_sub_changes.down.sql
+
+DROP TRIGGER IF EXISTS subs_notify_change ON subs;
+DROP FUNCTION IF EXISTS notify_sub_change();
+DROP FUNCTION IF EXISTS sub_change_json(subs);
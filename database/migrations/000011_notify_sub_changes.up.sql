@@ -0,0 +1,44 @@
+-- 000011_notify_sub_changes.up.sql
+
+-- A subscription as JSON in the shape of the API, with the name of its service
+CREATE OR REPLACE FUNCTION sub_change_json(s subs) RETURNS jsonb AS $$
+    SELECT jsonb_build_object(
+        'id', s.sub_id,
+        'org_id', s.org_id,
+        'service_id', s.service_id,
+        'service_name', (SELECT name FROM services sv WHERE sv.org_id = s.org_id AND sv.service_id = s.service_id),
+        'price', s.price,
+        'user_id', s.user_id,
+        'start_date', to_char(s.start_date, 'YYYY-MM-DD"T00:00:00Z"'),
+        'end_date', to_char(s.end_date, 'YYYY-MM-DD"T00:00:00Z"')
+    );
+$$ LANGUAGE sql STABLE;
+
+-- Every change of subs is sent to the sub_changes channel, listeners get
+-- it once the transaction commits. Every SubChecker instance listens and
+-- passes changes on to its event stream subscribers.
+CREATE OR REPLACE FUNCTION notify_sub_change() RETURNS trigger AS $$
+DECLARE
+    payload jsonb;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        payload := jsonb_build_object('type', 'sub.created', 'org_id', NEW.org_id, 'sub', sub_change_json(NEW));
+    ELSIF TG_OP = 'DELETE' THEN
+        payload := jsonb_build_object('type', 'sub.deleted', 'org_id', OLD.org_id, 'sub', sub_change_json(OLD));
+    ELSE
+        payload := jsonb_build_object(
+            'type', CASE WHEN OLD.end_date IS NULL AND NEW.end_date IS NOT NULL THEN 'sub.ended' ELSE 'sub.updated' END,
+            'org_id', NEW.org_id,
+            'sub', sub_change_json(NEW),
+            'previous', sub_change_json(OLD)
+        );
+    END IF;
+    PERFORM pg_notify('sub_changes', jsonb_strip_nulls(payload)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS subs_notify_change ON subs;
+CREATE TRIGGER subs_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON subs
+    FOR EACH ROW EXECUTE FUNCTION notify_sub_change();
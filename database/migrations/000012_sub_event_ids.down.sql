@@ -0,0 +1,24 @@
+-- 000012_sub_event_ids.down.sql
+
+CREATE OR REPLACE FUNCTION notify_sub_change() RETURNS trigger AS $$
+DECLARE
+    payload jsonb;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        payload := jsonb_build_object('type', 'sub.created', 'org_id', NEW.org_id, 'sub', sub_change_json(NEW));
+    ELSIF TG_OP = 'DELETE' THEN
+        payload := jsonb_build_object('type', 'sub.deleted', 'org_id', OLD.org_id, 'sub', sub_change_json(OLD));
+    ELSE
+        payload := jsonb_build_object(
+            'type', CASE WHEN OLD.end_date IS NULL AND NEW.end_date IS NOT NULL THEN 'sub.ended' ELSE 'sub.updated' END,
+            'org_id', NEW.org_id,
+            'sub', sub_change_json(NEW),
+            'previous', sub_change_json(OLD)
+        );
+    END IF;
+    PERFORM pg_notify('sub_changes', jsonb_strip_nulls(payload)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP SEQUENCE IF EXISTS sub_event_id_seq;
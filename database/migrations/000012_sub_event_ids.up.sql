@@ -0,0 +1,27 @@
+-- 000012_sub_event_ids.up.sql
+
+-- Ids of subscription change events, shared by every SubChecker instance
+-- so a client can resume its event stream on any of them
+CREATE SEQUENCE IF NOT EXISTS sub_event_id_seq;
+
+CREATE OR REPLACE FUNCTION notify_sub_change() RETURNS trigger AS $$
+DECLARE
+    payload jsonb;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        payload := jsonb_build_object('type', 'sub.created', 'org_id', NEW.org_id, 'sub', sub_change_json(NEW));
+    ELSIF TG_OP = 'DELETE' THEN
+        payload := jsonb_build_object('type', 'sub.deleted', 'org_id', OLD.org_id, 'sub', sub_change_json(OLD));
+    ELSE
+        payload := jsonb_build_object(
+            'type', CASE WHEN OLD.end_date IS NULL AND NEW.end_date IS NOT NULL THEN 'sub.ended' ELSE 'sub.updated' END,
+            'org_id', NEW.org_id,
+            'sub', sub_change_json(NEW),
+            'previous', sub_change_json(OLD)
+        );
+    END IF;
+    payload := payload || jsonb_build_object('id', nextval('sub_event_id_seq'), 'at', now());
+    PERFORM pg_notify('sub_changes', jsonb_strip_nulls(payload)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,6 @@
+-- 000013_create_orgs.down.sql
+
+DROP TRIGGER IF EXISTS webhook_endpoints_register_org ON webhook_endpoints;
+DROP TRIGGER IF EXISTS users_register_org ON users;
+DROP FUNCTION IF EXISTS register_org();
+DROP TABLE IF EXISTS orgs;
@@ -0,0 +1,37 @@
+-- 000013_create_orgs.up.sql
+
+-- Organizations that have users or webhook endpoints. Background jobs work
+-- through them one by one; the table has no row-level security, so that
+-- they can be found before an organization is set.
+CREATE TABLE IF NOT EXISTS orgs (
+    org_id TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION register_org() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO orgs (org_id) VALUES (NEW.org_id) ON CONFLICT DO NOTHING;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_register_org ON users;
+CREATE TRIGGER users_register_org
+    AFTER INSERT ON users
+    FOR EACH ROW EXECUTE FUNCTION register_org();
+
+DROP TRIGGER IF EXISTS webhook_endpoints_register_org ON webhook_endpoints;
+CREATE TRIGGER webhook_endpoints_register_org
+    AFTER INSERT ON webhook_endpoints
+    FOR EACH ROW EXECUTE FUNCTION register_org();
+
+-- Row-level security must not hide existing organizations from the migration
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhook_endpoints NO FORCE ROW LEVEL SECURITY;
+
+INSERT INTO orgs (org_id)
+SELECT org_id FROM users UNION SELECT org_id FROM webhook_endpoints
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+ALTER TABLE webhook_endpoints FORCE ROW LEVEL SECURITY;
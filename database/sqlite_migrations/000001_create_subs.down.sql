@@ -0,0 +1,3 @@
+-- 000001_create_subs.down.sql
+
+DROP TABLE IF EXISTS subs;
@@ -0,0 +1,10 @@
+-- 000001_create_subs.up.sql
+
+CREATE TABLE IF NOT EXISTS subs (
+    sub_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_name TEXT NOT NULL,
+    price INTEGER NOT NULL CHECK (price >= 0),
+    user_id TEXT NOT NULL,
+    start_date TEXT NOT NULL,
+    end_date TEXT NULL
+);
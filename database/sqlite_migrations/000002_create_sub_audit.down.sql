@@ -0,0 +1,5 @@
+-- 000002_create_sub_audit.down.sql
+
+DROP TRIGGER IF EXISTS sub_audit_no_delete;
+DROP TRIGGER IF EXISTS sub_audit_no_update;
+DROP TABLE IF EXISTS sub_audit;
@@ -0,0 +1,27 @@
+-- 000002_create_sub_audit.up.sql
+
+CREATE TABLE IF NOT EXISTS sub_audit (
+    audit_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sub_id INTEGER NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before_state TEXT NULL,
+    after_state TEXT NULL,
+    actor TEXT NOT NULL,
+    request_id TEXT NOT NULL,
+    changed_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sub_audit_sub_id_idx ON sub_audit (sub_id, audit_id);
+
+-- Audit log is append-only: updates and deletes are rejected
+CREATE TRIGGER IF NOT EXISTS sub_audit_no_update
+    BEFORE UPDATE ON sub_audit
+BEGIN
+    SELECT RAISE(ABORT, 'sub_audit is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS sub_audit_no_delete
+    BEFORE DELETE ON sub_audit
+BEGIN
+    SELECT RAISE(ABORT, 'sub_audit is append-only');
+END;
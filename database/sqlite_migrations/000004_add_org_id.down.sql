@@ -0,0 +1,8 @@
+-- 000004_add_org_id.down.sql
+
+DROP INDEX IF EXISTS subs_org_id_user_id_idx;
+DROP INDEX IF EXISTS subs_org_id_idx;
+
+ALTER TABLE api_keys DROP COLUMN org_id;
+ALTER TABLE sub_audit DROP COLUMN org_id;
+ALTER TABLE subs DROP COLUMN org_id;
@@ -0,0 +1,10 @@
+-- 000004_add_org_id.up.sql
+
+-- Every row belongs to an organization (tenant). Rows created before
+-- multi-tenancy go to the default one.
+ALTER TABLE subs ADD COLUMN org_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE sub_audit ADD COLUMN org_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN org_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS subs_org_id_idx ON subs (org_id, sub_id);
+CREATE INDEX IF NOT EXISTS subs_org_id_user_id_idx ON subs (org_id, user_id);
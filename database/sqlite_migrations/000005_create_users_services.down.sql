@@ -0,0 +1,28 @@
+-- 000005_create_users_services.down.sql
+
+BEGIN;
+
+CREATE TABLE subs_old (
+    sub_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_name TEXT NOT NULL,
+    price INTEGER NOT NULL CHECK (price >= 0),
+    user_id TEXT NOT NULL,
+    start_date TEXT NOT NULL,
+    end_date TEXT NULL,
+    org_id TEXT NOT NULL DEFAULT 'default'
+);
+
+INSERT INTO subs_old (sub_id, service_name, price, user_id, start_date, end_date, org_id)
+SELECT s.sub_id, sv.name, s.price, s.user_id, s.start_date, s.end_date, s.org_id
+FROM subs s JOIN services sv ON sv.service_id = s.service_id;
+
+DROP TABLE subs;
+ALTER TABLE subs_old RENAME TO subs;
+
+CREATE INDEX IF NOT EXISTS subs_org_id_idx ON subs (org_id, sub_id);
+CREATE INDEX IF NOT EXISTS subs_org_id_user_id_idx ON subs (org_id, user_id);
+
+DROP TABLE IF EXISTS services;
+DROP TABLE IF EXISTS users;
+
+COMMIT;
@@ -0,0 +1,71 @@
+-- 000005_create_users_services.up.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS users (
+    org_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (org_id, user_id)
+);
+
+-- name_key is the name in lower case with collapsed whitespace,
+-- so "Netflix", "netflix " and "NETFLIX" are one service
+CREATE TABLE IF NOT EXISTS services (
+    service_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    name_key TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    UNIQUE (org_id, name_key),
+    UNIQUE (org_id, service_id)
+);
+
+INSERT OR IGNORE INTO users (org_id, user_id, created_at)
+SELECT DISTINCT org_id, user_id, strftime('%Y-%m-%dT%H:%M:%fZ', 'now') FROM subs;
+
+-- SQLite has no regexp_replace: runs of up to four spaces are collapsed.
+-- A service is named after its earliest subscription.
+CREATE TEMP TABLE sub_service_keys AS
+SELECT sub_id, org_id,
+       trim(replace(replace(service_name, '  ', ' '), '  ', ' ')) AS name,
+       lower(trim(replace(replace(service_name, '  ', ' '), '  ', ' '))) AS name_key
+FROM subs;
+
+INSERT OR IGNORE INTO services (org_id, name, name_key, created_at)
+SELECT org_id, name, name_key, strftime('%Y-%m-%dT%H:%M:%fZ', 'now')
+FROM (SELECT org_id, name, name_key, min(sub_id) FROM sub_service_keys GROUP BY org_id, name_key);
+
+CREATE TABLE subs_new (
+    sub_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id TEXT NOT NULL DEFAULT 'default',
+    service_id INTEGER NOT NULL,
+    price INTEGER NOT NULL CHECK (price >= 0),
+    user_id TEXT NOT NULL,
+    start_date TEXT NOT NULL,
+    end_date TEXT NULL,
+    FOREIGN KEY (org_id, service_id) REFERENCES services (org_id, service_id),
+    FOREIGN KEY (org_id, user_id) REFERENCES users (org_id, user_id)
+);
+
+INSERT INTO subs_new (sub_id, org_id, service_id, price, user_id, start_date, end_date)
+SELECT s.sub_id, s.org_id, sv.service_id, s.price, s.user_id, s.start_date, s.end_date
+FROM subs s
+JOIN sub_service_keys k ON k.sub_id = s.sub_id
+JOIN services sv ON sv.org_id = k.org_id AND sv.name_key = k.name_key;
+
+DROP TABLE sub_service_keys;
+DROP TABLE subs;
+ALTER TABLE subs_new RENAME TO subs;
+
+-- Ids of deleted subscriptions are never reused, their history stays separate
+INSERT INTO sqlite_sequence (name, seq)
+SELECT 'subs', 0 WHERE NOT EXISTS (SELECT 1 FROM sqlite_sequence WHERE name = 'subs');
+UPDATE sqlite_sequence SET seq = max(seq, (SELECT coalesce(max(sub_id), 0) FROM sub_audit)) WHERE name = 'subs';
+
+CREATE INDEX IF NOT EXISTS subs_org_id_idx ON subs (org_id, sub_id);
+CREATE INDEX IF NOT EXISTS subs_org_id_user_id_idx ON subs (org_id, user_id);
+CREATE INDEX IF NOT EXISTS subs_org_id_service_id_idx ON subs (org_id, service_id);
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- 000006_add_service_details.down.sql
+
+DROP INDEX IF EXISTS services_org_id_category_idx;
+
+ALTER TABLE services DROP COLUMN logo_url;
+ALTER TABLE services DROP COLUMN website;
+ALTER TABLE services DROP COLUMN billing_period;
+ALTER TABLE services DROP COLUMN default_price;
+ALTER TABLE services DROP COLUMN category;
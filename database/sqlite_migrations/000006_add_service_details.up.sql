@@ -0,0 +1,10 @@
+-- 000006_add_service_details.up.sql
+
+-- default_price is per billing period, NULL when the service has no usual price
+ALTER TABLE services ADD COLUMN category TEXT NOT NULL DEFAULT '';
+ALTER TABLE services ADD COLUMN default_price INTEGER NULL CHECK (default_price >= 0);
+ALTER TABLE services ADD COLUMN billing_period TEXT NOT NULL DEFAULT 'month' CHECK (billing_period IN ('month', 'year'));
+ALTER TABLE services ADD COLUMN website TEXT NOT NULL DEFAULT '';
+ALTER TABLE services ADD COLUMN logo_url TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS services_org_id_category_idx ON services (org_id, category);
@@ -0,0 +1,3 @@
+-- 000007_create_budgets.down.sql
+
+DROP TABLE IF EXISTS budgets;
@@ -0,0 +1,14 @@
+-- 000007_create_budgets.up.sql
+
+-- A monthly budget of one user or of one team from the access policy file
+CREATE TABLE IF NOT EXISTS budgets (
+    budget_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id TEXT NOT NULL,
+    user_id TEXT NULL,
+    team TEXT NULL,
+    amount INTEGER NOT NULL CHECK (amount >= 0),
+    created_at TEXT NOT NULL,
+    CHECK ((user_id IS NULL) <> (team IS NULL)),
+    UNIQUE (org_id, user_id),
+    UNIQUE (org_id, team)
+);
@@ -0,0 +1,12 @@
+-- 000008_create_sub_price_changes.up.sql
+
+-- A price a subscription will have from effective_month on
+CREATE TABLE IF NOT EXISTS sub_price_changes (
+    change_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id TEXT NOT NULL,
+    sub_id INTEGER NOT NULL REFERENCES subs (sub_id) ON DELETE CASCADE,
+    effective_month TEXT NOT NULL,
+    price INTEGER NOT NULL CHECK (price >= 0),
+    created_at TEXT NOT NULL,
+    UNIQUE (sub_id, effective_month)
+);
@@ -0,0 +1,3 @@
+-- 000009_create_sent_reminders.down.sql
+
+DROP TABLE IF EXISTS sent_reminders;
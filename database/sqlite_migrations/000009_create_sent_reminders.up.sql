@@ -0,0 +1,13 @@
+-- 000009_create_sent_reminders.up.sql
+
+-- A reminder sent through one channel, so that it isn't sent twice
+CREATE TABLE IF NOT EXISTS sent_reminders (
+    reminder_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id TEXT NOT NULL,
+    sub_id INTEGER NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('renewal', 'end')),
+    due_date TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    sent_at TEXT NOT NULL,
+    UNIQUE (org_id, sub_id, kind, due_date, channel)
+);
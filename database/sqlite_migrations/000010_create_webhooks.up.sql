@@ -0,0 +1,48 @@
+-- 000010_create_webhooks.up.sql
+
+-- An endpoint subscription events are POSTed to, events is a comma-separated
+-- list, empty for every event
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    endpoint_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id TEXT NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL
+);
+
+-- Transactional outbox: an event is written with the change it describes
+-- and fanned out to endpoints after commit
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+    event_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    sub_id INTEGER NOT NULL,
+    data TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    dispatched_at TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_outbox_undispatched_idx
+    ON webhook_outbox (org_id, event_id) WHERE dispatched_at IS NULL;
+
+-- Sending of one event to one endpoint, also the delivery log
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id TEXT NOT NULL,
+    event_id INTEGER NOT NULL REFERENCES webhook_outbox (event_id) ON DELETE CASCADE,
+    endpoint_id INTEGER NOT NULL REFERENCES webhook_endpoints (endpoint_id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER NULL,
+    last_error TEXT NULL,
+    next_attempt_at TEXT NOT NULL,
+    delivered_at TEXT NULL,
+    created_at TEXT NOT NULL,
+    UNIQUE (event_id, endpoint_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx
+    ON webhook_deliveries (org_id, next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_idx
+    ON webhook_deliveries (endpoint_id, delivery_id);
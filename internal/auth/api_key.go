@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+const (
+	ApiKeyHeader = "X-API-Key"
+
+	apiKeyPrefix    = "sck_"
+	apiKeyPrefixLen = len(apiKeyPrefix) + 8
+)
+
+// GenerateApiKey returns a new random key, its display prefix and the hash to store.
+// The key itself is shown once and never stored.
+func GenerateApiKey() (key, prefix, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyPrefix + hex.EncodeToString(b)
+	return key, key[:apiKeyPrefixLen], HashApiKey(key), nil
+}
+
+// HashApiKey hashes a key for storage and lookup. Keys are 256-bit random
+// values, so a fast unsalted hash is enough.
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ApiKeyLookup finds an active key by its plain value
+type ApiKeyLookup interface {
+	Authenticate(ctx context.Context, key string) (*model.ApiKey, error)
+}
+
+type apiKeyAuthenticator struct {
+	keys ApiKeyLookup
+}
+
+func NewApiKeyAuthenticator(keys ApiKeyLookup) Authenticator {
+	return &apiKeyAuthenticator{keys: keys}
+}
+
+func (a *apiKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	key := r.Header.Get(ApiKeyHeader)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	k, err := a.keys.Authenticate(r.Context(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{Subject: "apikey:" + k.Name, OrgId: k.OrgId, Scopes: k.Scopes}, nil
+}
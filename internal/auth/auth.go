@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+)
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+var (
+	// ErrNoCredentials means the request carries no credentials of this kind
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated caller
+type Principal struct {
+	Subject string
+	// UserId is set for end users (JWT); API keys act as service accounts
+	UserId string
+	// OrgId is the tenant the caller belongs to, empty for the default one
+	OrgId  string
+	Scopes []string
+	// Roles are granted by the credentials themselves, see package policy
+	Roles []string
+}
+
+// Anonymous is used for every request when authentication is disabled
+var Anonymous = &Principal{Subject: "anonymous", Scopes: []string{ScopeAdmin}}
+
+// HasScope reports whether p was granted scope. Admin scope grants all.
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, ScopeAdmin) || slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator extracts a Principal from the request. It returns
+// ErrNoCredentials if the request has none it understands.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+type ctxKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(*Principal)
+	return p, ok
+}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const bearerPrefix = "Bearer "
+
+type JWTConfig struct {
+	// HS256Secret enables HS256 tokens
+	HS256Secret string
+	// JWKSFile is a local JWKS document whose RSA keys enable RS256 tokens
+	JWKSFile string
+	// Issuer and Audience are checked when set
+	Issuer   string
+	Audience string
+}
+
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	// Scope is a space-separated list, as in OAuth 2.0
+	Scope string `json:"scope"`
+	// Org is the tenant of the user, the default one if empty
+	Org string `json:"org"`
+	// Roles are RBAC roles, see package policy
+	Roles []string `json:"roles"`
+}
+
+type jwtAuthenticator struct {
+	parser  *jwt.Parser
+	secret  []byte
+	rsaKeys map[string]*rsa.PublicKey
+}
+
+// NewJWTAuthenticator validates bearer tokens. The "sub" claim is the
+// caller's user id and "org" its organization; tokens without "scope" get
+// read and write access to that user's own subscriptions.
+func NewJWTAuthenticator(cfg JWTConfig) (Authenticator, error) {
+	a := &jwtAuthenticator{}
+	var methods []string
+
+	if cfg.HS256Secret != "" {
+		a.secret = []byte(cfg.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("jwt: neither HS256 secret nor JWKS file is configured")
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+
+	return a, nil
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, bearerPrefix) {
+		return nil, ErrNoCredentials
+	}
+
+	var claims jwtClaims
+	_, err := a.parser.ParseWithClaims(strings.TrimPrefix(header, bearerPrefix), &claims, a.key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: sub claim is empty", ErrInvalidCredentials)
+	}
+
+	scopes := strings.Fields(claims.Scope)
+	if len(scopes) == 0 {
+		scopes = []string{ScopeRead, ScopeWrite}
+	}
+
+	return &Principal{Subject: claims.Subject, UserId: claims.Subject, OrgId: claims.Org, Scopes: scopes, Roles: claims.Roles}, nil
+}
+
+func (a *jwtAuthenticator) key(t *jwt.Token) (any, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return a.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" && len(a.rsaKeys) == 1 {
+			for _, k := range a.rsaKeys {
+				return k, nil
+			}
+		}
+		if k, ok := a.rsaKeys[kid]; ok {
+			return k, nil
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// loadJWKS reads RSA public keys from a JWKS file, other key types are skipped
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: failed to read JWKS: %w", err)
+	}
+
+	var set jwks
+	if err := json.Unmarshal(content, &set); err != nil {
+		return nil, fmt.Errorf("jwt: failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: bad modulus of key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: bad exponent of key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwt: JWKS has no RSA keys")
+	}
+	return keys, nil
+}
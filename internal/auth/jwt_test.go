@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func newTestJWT(t *testing.T) (Authenticator, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	jwks := fmt.Sprintf(`{"keys": [{"kty": "RSA", "kid": "k1", "n": %q, "e": %q}]}`,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()))
+	if err := os.WriteFile(jwksFile, []byte(jwks), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewJWTAuthenticator(JWTConfig{HS256Secret: testSecret, JWKSFile: jwksFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, key
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	a, rsaKey := newTestJWT(t)
+	const user = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	claims := func(scope string, exp *time.Time) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": user, "org": "acme"}
+		if scope != "" {
+			c["scope"] = scope
+		}
+		if exp != nil {
+			c["exp"] = exp.Unix()
+		}
+		return c
+	}
+	later := time.Now().Add(time.Hour)
+	earlier := time.Now().Add(-time.Hour)
+
+	sign := func(method jwt.SigningMethod, key any, c jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, c)
+		token.Header["kid"] = "k1"
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	hs256 := func(c jwt.MapClaims) string { return sign(jwt.SigningMethodHS256, []byte(testSecret), c) }
+
+	tests := []struct {
+		name   string
+		token  string
+		scopes []string // nil means the token is rejected
+	}{
+		{"hs256", hs256(claims("read", &later)), []string{ScopeRead}},
+		{"rs256", sign(jwt.SigningMethodRS256, rsaKey, claims("read", &later)), []string{ScopeRead}},
+		{"scope list", hs256(claims("read write admin", &later)), []string{ScopeRead, ScopeWrite, ScopeAdmin}},
+		{"no scope reads and writes", hs256(claims("", &later)), []string{ScopeRead, ScopeWrite}},
+		{"alg none", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims("", &later)), nil},
+		{"hs512", sign(jwt.SigningMethodHS512, []byte(testSecret), claims("", &later)), nil},
+		{"wrong secret", sign(jwt.SigningMethodHS256, []byte("other"), claims("", &later)), nil},
+		{"no exp", hs256(claims("", nil)), nil},
+		{"expired", hs256(claims("", &earlier)), nil},
+		{"no sub", hs256(jwt.MapClaims{"exp": later.Unix()}), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			p, err := a.Authenticate(r)
+
+			if tt.scopes == nil {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Fatalf("got %v, %v, want ErrInvalidCredentials", p, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Subject != user || p.UserId != user || p.OrgId != "acme" {
+				t.Errorf("got principal %+v", p)
+			}
+			if !slices.Equal(p.Scopes, tt.scopes) {
+				t.Errorf("got scopes %v, want %v", p.Scopes, tt.scopes)
+			}
+		})
+	}
+}
+
+func TestJWTWithoutBearer(t *testing.T) {
+	a, _ := newTestJWT(t)
+	for _, header := range []string{"", "Basic dXNlcjpwYXNz"} {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", header)
+		if _, err := a.Authenticate(r); !errors.Is(err, ErrNoCredentials) {
+			t.Errorf("%q: got %v, want ErrNoCredentials", header, err)
+		}
+	}
+}
@@ -2,24 +2,104 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+	DriverSQLite   = "sqlite"
+)
+
+// Create-time checks of a new subscription overlapping an existing one
+const (
+	OverlapOff    = "off"
+	OverlapWarn   = "warn"
+	OverlapReject = "reject"
+)
+
 type Config struct {
 	//DB
+	DBDriver   string
 	DBHost     string
 	DBPort     string
 	DBUser     string
 	DBPassword string
 	DBName     string
+	SQLitePath string
 
 	// Server
-	ServerPort string
+	ServerPort         string
+	ServerReadTimeout  time.Duration
+	ServerWriteTimeout time.Duration
+	ServerIdleTimeout  time.Duration
+	// ShutdownDelay is how long /readyz fails on SIGTERM/SIGINT before the
+	// server stops accepting connections, so that it is taken out of rotation
+	ShutdownDelay time.Duration
+	// ShutdownTimeout is how long in-flight requests are drained on
+	// SIGTERM/SIGINT before connections are closed
+	ShutdownTimeout time.Duration
+	// LegacySunset is when the unprefixed API paths are removed
+	LegacySunset time.Time
+	// GRPCPort serves the gRPC API, it is off if empty
+	GRPCPort string
+
+	// Auth
+	AuthApiKeys    bool
+	JWTHS256Secret string
+	JWTJWKSFile    string
+	JWTIssuer      string
+	JWTAudience    string
+	// AuthPolicyFile is a JSON RBAC policy, built-in roles are used if empty
+	AuthPolicyFile string
+
+	// Subs
+	// SubsOverlapCheck is one of OverlapOff, OverlapWarn and OverlapReject
+	SubsOverlapCheck string
+
+	// Reminders
+	RemindersEnabled bool
+	ReminderLead     time.Duration
+	ReminderInterval time.Duration
+	// ReminderOrgs limits reminders to these organizations, empty is every one
+	ReminderOrgs []string
+	// ReminderNotifiers are channels: log, webhook, smtp
+	ReminderNotifiers  []string
+	ReminderWebhookURL string
+	SMTPAddr           string
+	SMTPFrom           string
+	SMTPTo             []string
+	SMTPUsername       string
+	SMTPPassword       string
+
+	// GraphQLMaxDepth limits nesting of /graphql queries
+	GraphQLMaxDepth int
+
+	// SubEventsBuffer is how many recent changes /subs/events clients can resume from
+	SubEventsBuffer int
+
+	// Webhooks
+	WebhooksEnabled bool
+	// WebhookOrgs limits webhooks to these organizations, empty is every one
+	WebhookOrgs         []string
+	WebhookPollInterval time.Duration
+	WebhookRetryBackoff time.Duration
+	WebhookMaxAttempts  int
 
 	// Migrations
-	MigrationsDir string
+	MigrationsDir       string
+	SQLiteMigrationsDir string
+}
+
+// JWTEnabled reports whether bearer tokens are accepted
+func (c *Config) JWTEnabled() bool {
+	return c.JWTHS256Secret != "" || c.JWTJWKSFile != ""
 }
 
 func Load() (*Config, error) {
@@ -29,19 +109,108 @@ func Load() (*Config, error) {
 
 	cfg := &Config{
 		// DB
+		DBDriver:   os.Getenv("DB_DRIVER"),
 		DBUser:     os.Getenv("POSTGRES_USER"),
 		DBPassword: os.Getenv("POSTGRES_PASSWORD"),
 		DBHost:     os.Getenv("POSTGRES_HOST"),
 		DBPort:     os.Getenv("POSTGRES_PORT"),
 		DBName:     os.Getenv("POSTGRES_DB"),
+		SQLitePath: os.Getenv("SQLITE_PATH"),
 
 		// SERVER
 		ServerPort: os.Getenv("SERVER_PORT"),
+		GRPCPort:   os.Getenv("GRPC_PORT"),
+
+		// AUTH
+		AuthApiKeys:    os.Getenv("AUTH_API_KEYS") == "true",
+		JWTHS256Secret: os.Getenv("AUTH_JWT_HS256_SECRET"),
+		JWTJWKSFile:    os.Getenv("AUTH_JWT_JWKS_FILE"),
+		JWTIssuer:      os.Getenv("AUTH_JWT_ISSUER"),
+		JWTAudience:    os.Getenv("AUTH_JWT_AUDIENCE"),
+		AuthPolicyFile: os.Getenv("AUTH_POLICY_FILE"),
+
+		// SUBS
+		SubsOverlapCheck: os.Getenv("SUBS_OVERLAP_CHECK"),
+
+		// REMINDERS
+		RemindersEnabled:   os.Getenv("REMINDERS_ENABLED") == "true",
+		ReminderOrgs:       splitList(os.Getenv("REMINDER_ORGS")),
+		ReminderNotifiers:  splitList(os.Getenv("REMINDER_NOTIFIERS")),
+		ReminderWebhookURL: os.Getenv("REMINDER_WEBHOOK_URL"),
+		SMTPAddr:           os.Getenv("SMTP_ADDR"),
+		SMTPFrom:           os.Getenv("SMTP_FROM"),
+		SMTPTo:             splitList(os.Getenv("SMTP_TO")),
+		SMTPUsername:       os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:       os.Getenv("SMTP_PASSWORD"),
+
+		// WEBHOOKS
+		WebhooksEnabled: os.Getenv("WEBHOOKS_ENABLED") == "true",
+		WebhookOrgs:     splitList(os.Getenv("WEBHOOK_ORGS")),
+
+		MigrationsDir:       os.Getenv("MIGRATIONS_DIR"),
+		SQLiteMigrationsDir: os.Getenv("SQLITE_MIGRATIONS_DIR"),
+	}
+
+	switch cfg.DBDriver {
+	case "":
+		cfg.DBDriver = DriverPostgres
+	case DriverPostgres, DriverMemory, DriverSQLite:
+	default:
+		return nil, fmt.Errorf("unknown DB_DRIVER %q", cfg.DBDriver)
+	}
+
+	switch cfg.SubsOverlapCheck {
+	case "":
+		cfg.SubsOverlapCheck = OverlapOff
+	case OverlapOff, OverlapWarn, OverlapReject:
+	default:
+		return nil, fmt.Errorf("unknown SUBS_OVERLAP_CHECK %q", cfg.SubsOverlapCheck)
+	}
+
+	cfg.SubEventsBuffer = 1000
+	if v := os.Getenv("SUBS_EVENTS_BUFFER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("bad SUBS_EVENTS_BUFFER %q", v)
+		}
+		cfg.SubEventsBuffer = n
+	}
+
+	if err := cfg.loadServer(); err != nil {
+		return nil, err
+	}
+
+	cfg.LegacySunset = time.Date(2027, 4, 19, 0, 0, 0, 0, time.UTC)
+	if v := os.Getenv("API_LEGACY_SUNSET"); v != "" {
+		t, err := time.Parse(time.DateOnly, v)
+		if err != nil {
+			return nil, fmt.Errorf("bad API_LEGACY_SUNSET %q, expected YYYY-MM-DD", v)
+		}
+		cfg.LegacySunset = t
+	}
+
+	cfg.GraphQLMaxDepth = 6
+	if v := os.Getenv("GRAPHQL_MAX_DEPTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("bad GRAPHQL_MAX_DEPTH %q", v)
+		}
+		cfg.GraphQLMaxDepth = n
+	}
 
-		MigrationsDir: os.Getenv("MIGRATIONS_DIR"),
+	if cfg.RemindersEnabled {
+		if err := cfg.loadReminders(); err != nil {
+			return nil, err
+		}
 	}
 
-	if cfg.DBUser == "" || cfg.DBPassword == "" {
+	if cfg.WebhooksEnabled {
+		if err := cfg.loadWebhooks(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.DBDriver == DriverPostgres && (cfg.DBUser == "" || cfg.DBPassword == "") {
 		err := errors.New("DB_USER or DB_PASSWORD is empty")
 		return nil, err
 	}
@@ -53,3 +222,115 @@ func Load() (*Config, error) {
 
 	return cfg, nil
 }
+
+// loadServer sets HTTP server timeouts
+func (c *Config) loadServer() error {
+	var err error
+	if c.ServerReadTimeout, err = durationEnv("SERVER_READ_TIMEOUT", 15*time.Second); err != nil {
+		return err
+	}
+	if c.ServerWriteTimeout, err = durationEnv("SERVER_WRITE_TIMEOUT", 30*time.Second); err != nil {
+		return err
+	}
+	if c.ServerIdleTimeout, err = durationEnv("SERVER_IDLE_TIMEOUT", 2*time.Minute); err != nil {
+		return err
+	}
+	if c.ShutdownTimeout, err = durationEnv("SERVER_SHUTDOWN_TIMEOUT", 20*time.Second); err != nil {
+		return err
+	}
+	if v := os.Getenv("SERVER_SHUTDOWN_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return fmt.Errorf("bad SERVER_SHUTDOWN_DELAY %q", v)
+		}
+		c.ShutdownDelay = d
+	}
+	return nil
+}
+
+// loadReminders sets reminder defaults and checks that every notifier is configured
+func (c *Config) loadReminders() error {
+	leadDays := 3
+	if v := os.Getenv("REMINDER_LEAD_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("bad REMINDER_LEAD_DAYS %q", v)
+		}
+		leadDays = n
+	}
+	c.ReminderLead = time.Duration(leadDays) * 24 * time.Hour
+
+	c.ReminderInterval = 24 * time.Hour
+	if v := os.Getenv("REMINDER_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("bad REMINDER_INTERVAL %q", v)
+		}
+		c.ReminderInterval = d
+	}
+
+	if len(c.ReminderNotifiers) == 0 {
+		c.ReminderNotifiers = []string{"log"}
+	}
+	for _, n := range c.ReminderNotifiers {
+		switch n {
+		case "log":
+		case "webhook":
+			if c.ReminderWebhookURL == "" {
+				return errors.New("REMINDER_WEBHOOK_URL is empty")
+			}
+		case "smtp":
+			if c.SMTPAddr == "" || c.SMTPFrom == "" || len(c.SMTPTo) == 0 {
+				return errors.New("SMTP_ADDR, SMTP_FROM or SMTP_TO is empty")
+			}
+		default:
+			return fmt.Errorf("unknown notifier %q in REMINDER_NOTIFIERS", n)
+		}
+	}
+	return nil
+}
+
+// loadWebhooks sets webhook dispatcher defaults
+func (c *Config) loadWebhooks() error {
+	var err error
+	if c.WebhookPollInterval, err = durationEnv("WEBHOOK_POLL_INTERVAL", 5*time.Second); err != nil {
+		return err
+	}
+	if c.WebhookRetryBackoff, err = durationEnv("WEBHOOK_RETRY_BACKOFF", 30*time.Second); err != nil {
+		return err
+	}
+
+	c.WebhookMaxAttempts = 8
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad WEBHOOK_MAX_ATTEMPTS %q", v)
+		}
+		c.WebhookMaxAttempts = n
+	}
+	return nil
+}
+
+// durationEnv parses a positive duration from the environment, def if unset
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("bad %s %q", key, v)
+	}
+	return d, nil
+}
+
+// splitList splits a comma-separated value, skipping empty items
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
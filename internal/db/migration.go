@@ -2,25 +2,176 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 	"github.com/tmozzze/SubChecker/internal/config"
 )
 
+// schema_migrations has the same layout as the golang-migrate one,
+// so `make migrate-status` and the service agree on the version
+const createSchemaMigrations = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		dirty BOOLEAN NOT NULL
+	)
+`
+
+// migrationLockKey is the Postgres advisory lock held while migrating, so
+// that instances starting together don't apply the same migration twice
+const migrationLockKey = 4209180521
+
+// migrationTarget is a database migrations are applied to
+type migrationTarget struct {
+	exec func(query string) error
+	// version returns the applied version, -1 if nothing was applied yet
+	version func() (int64, bool, error)
+}
+
+// MigrationsDir is where the Postgres migrations are
+func MigrationsDir(cfg *config.Config) string {
+	if cfg.MigrationsDir == "" {
+		return "./database/migrations"
+	}
+	return cfg.MigrationsDir
+}
+
+// SQLiteMigrationsDir is where the SQLite migrations are
+func SQLiteMigrationsDir(cfg *config.Config) string {
+	if cfg.SQLiteMigrationsDir == "" {
+		return "./database/sqlite_migrations"
+	}
+	return cfg.SQLiteMigrationsDir
+}
+
+// RunMigration applies new migrations under an advisory lock. The lock
+// belongs to a session, so the version is read and migrations are applied
+// on the one connection that holds it.
 func RunMigration(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config, log *logrus.Logger) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		// Unlock even if ctx is cancelled, a connection still holding the
+		// lock must not go back to the pool
+		unlockCtx := context.WithoutCancel(ctx)
+		if _, unlockErr := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, migrationLockKey); unlockErr != nil {
+			log.WithError(unlockErr).Error("Failed to release migration lock")
+			_ = conn.Conn().Close(unlockCtx)
+		}
+	}()
+
+	return applyMigrations(MigrationsDir(cfg), log, migrationTarget{
+		exec: func(query string) error {
+			_, err := conn.Exec(ctx, query)
+			return err
+		},
+		version: func() (int64, bool, error) {
+			return readVersion(ctx, conn)
+		},
+	})
+}
+
+func RunSQLiteMigration(ctx context.Context, db *sql.DB, cfg *config.Config, log *logrus.Logger) error {
+	return applyMigrations(SQLiteMigrationsDir(cfg), log, migrationTarget{
+		exec: func(query string) error {
+			_, err := db.ExecContext(ctx, query)
+			return err
+		},
+		version: func() (int64, bool, error) {
+			return SQLiteVersion(ctx, db)
+		},
+	})
+}
+
+// Version returns the migration version applied to a Postgres database,
+// -1 if nothing was applied yet
+func Version(ctx context.Context, pool *pgxpool.Pool) (int64, bool, error) {
+	return readVersion(ctx, pool)
+}
+
+// rowQuerier is a pool or one of its connections
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func readVersion(ctx context.Context, db rowQuerier) (int64, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return -1, false, nil
+	}
+	return version, dirty, err
+}
+
+// SQLiteVersion returns the migration version applied to a SQLite
+// database, -1 if nothing was applied yet
+func SQLiteVersion(ctx context.Context, db *sql.DB) (int64, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return -1, false, nil
+	}
+	return version, dirty, err
+}
+
+// LatestVersion is the version of the newest migration in migrationsDir,
+// -1 if there are none
+func LatestVersion(migrationsDir string) (int64, error) {
+	entries, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		return 0, err
+	}
+	latest := int64(-1)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".up.sql") {
+			continue
+		}
+		version, err := migrationVersion(e.Name())
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+func applyMigrations(migrationsDir string, log *logrus.Logger, db migrationTarget) error {
 	log.Info("Starting migrations...")
-	migrationsDir := cfg.MigrationsDir
-	if migrationsDir == "" {
-		migrationsDir = "./database/migrations"
+
+	if err := db.exec(createSchemaMigrations); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	current, dirty, err := db.version()
+	if err != nil {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("database is dirty at version %d, fix it and force the version", current)
 	}
 
-	err := filepath.WalkDir(migrationsDir, func(path string, d fs.DirEntry, err error) error {
+	err = filepath.WalkDir(migrationsDir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -29,14 +180,30 @@ func RunMigration(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config, l
 			return nil
 		}
 
+		version, err := migrationVersion(d.Name())
+		if err != nil {
+			return err
+		}
+		// Уже применённые миграции пропускаем
+		if version <= current {
+			return nil
+		}
+
 		content, err := os.ReadFile(path)
 		if err != nil {
 			return fmt.Errorf("failed to read migration %s: %w", d.Name(), err)
 		}
 
-		if _, err := pool.Exec(ctx, string(content)); err != nil {
+		if err := setVersion(db, version, true); err != nil {
+			return err
+		}
+		if err := db.exec(string(content)); err != nil {
 			return fmt.Errorf("failed to execute migration %s: %w", d.Name(), err)
 		}
+		if err := setVersion(db, version, false); err != nil {
+			return err
+		}
+		current = version
 
 		log.Infof("Migration %s applied", d.Name())
 		return nil
@@ -48,3 +215,21 @@ func RunMigration(ctx context.Context, pool *pgxpool.Pool, cfg *config.Config, l
 
 	return nil
 }
+
+// migrationVersion parses the leading number of e.g. 000001_create_subs.up.sql
+func migrationVersion(name string) (int64, error) {
+	prefix, _, _ := strings.Cut(name, "_")
+	version, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("migration %s has no version prefix", name)
+	}
+	return version, nil
+}
+
+func setVersion(db migrationTarget, version int64, dirty bool) error {
+	query := fmt.Sprintf(`DELETE FROM schema_migrations; INSERT INTO schema_migrations (version, dirty) VALUES (%d, %t)`, version, dirty)
+	if err := db.exec(query); err != nil {
+		return fmt.Errorf("failed to set migration version %d: %w", version, err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/tmozzze/SubChecker/internal/config"
+	_ "modernc.org/sqlite"
+)
+
+func NewSQLite(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	path := cfg.SQLitePath
+	if path == "" {
+		path = "./subchecker.db"
+	}
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite DB: %w", err)
+	}
+	// SQLite has a single writer; one connection keeps transactions serialized
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping SQLite DB: %w", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,58 @@
+package gql
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/service"
+)
+
+// Error codes in the "code" extension of an error
+const (
+	CodeBadInput     = "BAD_USER_INPUT"
+	CodeForbidden    = "FORBIDDEN"
+	CodeNotFound     = "NOT_FOUND"
+	CodeConflict     = "CONFLICT"
+	CodeInternal     = "INTERNAL"
+	CodeUnauthorized = "UNAUTHENTICATED"
+)
+
+// Error is returned to the client with its code in extensions
+type Error struct {
+	Message string
+	Code    string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]any {
+	return map[string]any{"code": e.Code}
+}
+
+func badInput(msg string) error {
+	return &Error{Message: msg, Code: CodeBadInput}
+}
+
+// apiError maps service errors the way the REST handlers do. Unexpected
+// errors are logged with op and hidden from the client.
+func apiError(log *logrus.Logger, op string, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return &Error{Message: "not found", Code: CodeNotFound}
+	case errors.Is(err, service.ErrForbidden):
+		return &Error{Message: "forbidden", Code: CodeForbidden}
+	case errors.Is(err, service.ErrConflict):
+		return &Error{Message: "subscription overlaps existing ones", Code: CodeConflict}
+	case errors.Is(err, service.ErrUnknownService):
+		return badInput("unknown serviceId")
+	case errors.Is(err, service.ErrEmptyServiceName):
+		return badInput("serviceName is empty")
+	case errors.Is(err, service.ErrNoDefaultPrice):
+		return badInput("price is required, the service has no default price")
+	}
+	log.WithError(err).Error(op + " failed")
+	return &Error{Message: "internal", Code: CodeInternal}
+}
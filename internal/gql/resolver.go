@@ -0,0 +1,354 @@
+package gql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/auth"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/service"
+	"github.com/tmozzze/SubChecker/internal/utils"
+)
+
+// Resolver is the root of queries and mutations
+type Resolver struct {
+	subs  service.SubService
+	users service.UserService
+	log   *logrus.Logger
+}
+
+type subFilterInput struct {
+	UserId      *graphql.ID
+	ServiceId   *int32
+	ServiceName *string
+}
+
+func (f *subFilterInput) filter() model.SubFilter {
+	var filter model.SubFilter
+	if f == nil {
+		return filter
+	}
+	if f.UserId != nil {
+		filter.UserId = string(*f.UserId)
+	}
+	if f.ServiceId != nil {
+		filter.ServiceId = int(*f.ServiceId)
+	}
+	if f.ServiceName != nil {
+		filter.ServiceName = *f.ServiceName
+	}
+	return filter
+}
+
+type pageArgs struct {
+	Limit  int32
+	Offset int32
+}
+
+// page is limit and offset normalized like in REST, see utils.Page
+func (a pageArgs) page() (limit, offset int) {
+	return utils.Page(int(a.Limit), int(a.Offset))
+}
+
+type periodArgs struct {
+	Filter     *subFilterInput
+	StartMonth string
+	EndMonth   string
+}
+
+func (a periodArgs) period() (time.Time, time.Time, error) {
+	start, err := utils.ParseMonth(a.StartMonth)
+	if err != nil {
+		return time.Time{}, time.Time{}, badInput("bad startMonth, expected MM-YYYY")
+	}
+	end, err := utils.ParseMonth(a.EndMonth)
+	if err != nil {
+		return time.Time{}, time.Time{}, badInput("bad endMonth, expected MM-YYYY")
+	}
+	return start, end, nil
+}
+
+func (r *Resolver) Subs(ctx context.Context, args struct {
+	Filter *subFilterInput
+	pageArgs
+}) ([]*subResolver, error) {
+	limit, offset := args.page()
+	subs, err := r.subs.List(ctx, args.Filter.filter(), limit, offset)
+	if err != nil {
+		return nil, apiError(r.log, "list subs", err)
+	}
+	return r.subResolvers(subs), nil
+}
+
+func (r *Resolver) Sub(ctx context.Context, args struct{ Id int32 }) (*subResolver, error) {
+	sub, err := r.subs.GetById(ctx, int(args.Id))
+	if err != nil {
+		return nil, apiError(r.log, "get sub", err)
+	}
+	return r.subResolver(*sub), nil
+}
+
+func (r *Resolver) Users(ctx context.Context, args pageArgs) ([]*userResolver, error) {
+	limit, offset := args.page()
+	users, err := r.users.List(ctx, limit, offset)
+	if err != nil {
+		return nil, apiError(r.log, "list users", err)
+	}
+	result := make([]*userResolver, 0, len(users))
+	for _, u := range users {
+		result = append(result, &userResolver{r: r, user: u})
+	}
+	return result, nil
+}
+
+func (r *Resolver) User(ctx context.Context, args struct{ Id graphql.ID }) (*userResolver, error) {
+	user, err := r.users.GetById(ctx, string(args.Id))
+	if err != nil {
+		return nil, apiError(r.log, "get user", err)
+	}
+	return &userResolver{r: r, user: *user}, nil
+}
+
+func (r *Resolver) SumCost(ctx context.Context, args periodArgs) (Int64, error) {
+	start, end, err := args.period()
+	if err != nil {
+		return 0, err
+	}
+	total, err := r.subs.SumCost(ctx, args.Filter.filter(), start, end)
+	if err != nil {
+		return 0, apiError(r.log, "sum cost", err)
+	}
+	return Int64(total), nil
+}
+
+type subInput struct {
+	ServiceId   *int32
+	ServiceName *string
+	Price       *int32
+	UserId      graphql.ID
+	StartDate   string
+	EndDate     *string
+}
+
+// sub checks the input and builds a subscription of it, prefilling the
+// price from the catalog if it is not given
+func (r *Resolver) sub(ctx context.Context, id int, in subInput) (*model.Sub, error) {
+	sub := &model.Sub{SubId: id, UserId: string(in.UserId)}
+	if in.ServiceId != nil {
+		if *in.ServiceId <= 0 {
+			return nil, badInput("invalid serviceId")
+		}
+		sub.ServiceId = int(*in.ServiceId)
+	}
+	if in.ServiceName != nil {
+		sub.ServiceName = *in.ServiceName
+	}
+	if sub.ServiceId == 0 && sub.ServiceName == "" {
+		return nil, badInput("serviceId or serviceName is required")
+	}
+	if _, err := uuid.Parse(sub.UserId); err != nil {
+		return nil, badInput("invalid userId")
+	}
+	sd, err := utils.ParseMonth(in.StartDate)
+	if err != nil {
+		return nil, badInput("bad startDate format, expected MM-YYYY")
+	}
+	sub.StartDate = sd
+	if in.EndDate != nil && *in.EndDate != "" {
+		ed, err := utils.ParseMonth(*in.EndDate)
+		if err != nil {
+			return nil, badInput("bad endDate format, expected MM-YYYY")
+		}
+		sub.EndDate = &ed
+	}
+
+	if in.Price != nil {
+		if *in.Price < 0 {
+			return nil, badInput("price must not be negative")
+		}
+		sub.Price = int(*in.Price)
+		return sub, nil
+	}
+	if err := r.subs.Prefill(ctx, sub); err != nil {
+		return nil, apiError(r.log, "prefill", err)
+	}
+	return sub, nil
+}
+
+type createSubPayload struct {
+	sub      *subResolver
+	overlaps []*subResolver
+}
+
+func (p *createSubPayload) Sub() *subResolver        { return p.sub }
+func (p *createSubPayload) Overlaps() []*subResolver { return p.overlaps }
+
+func (r *Resolver) CreateSub(ctx context.Context, args struct{ Input subInput }) (*createSubPayload, error) {
+	if err := requireScope(ctx, auth.ScopeWrite); err != nil {
+		return nil, err
+	}
+	sub, err := r.sub(ctx, 0, args.Input)
+	if err != nil {
+		return nil, err
+	}
+	overlapping, err := r.subs.Create(ctx, sub)
+	if err != nil {
+		return nil, apiError(r.log, "create sub", err)
+	}
+	return &createSubPayload{sub: r.subResolver(*sub), overlaps: r.subResolvers(overlapping)}, nil
+}
+
+func (r *Resolver) UpdateSub(ctx context.Context, args struct {
+	Id    int32
+	Input subInput
+}) (*subResolver, error) {
+	if err := requireScope(ctx, auth.ScopeWrite); err != nil {
+		return nil, err
+	}
+	sub, err := r.sub(ctx, int(args.Id), args.Input)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.subs.Update(ctx, sub); err != nil {
+		return nil, apiError(r.log, "update", err)
+	}
+	return r.subResolver(*sub), nil
+}
+
+func (r *Resolver) DeleteSub(ctx context.Context, args struct{ Id int32 }) (bool, error) {
+	if err := requireScope(ctx, auth.ScopeWrite); err != nil {
+		return false, err
+	}
+	if err := r.subs.Delete(ctx, int(args.Id)); err != nil {
+		return false, apiError(r.log, "delete", err)
+	}
+	return true, nil
+}
+
+// subResolvers resolves users of all subs with one query
+func (r *Resolver) subResolvers(subs []model.Sub) []*subResolver {
+	batch := &userBatch{r: r}
+	result := make([]*subResolver, 0, len(subs))
+	for _, s := range subs {
+		batch.ids = append(batch.ids, s.UserId)
+		result = append(result, &subResolver{r: r, sub: s, users: batch})
+	}
+	return result
+}
+
+func (r *Resolver) subResolver(sub model.Sub) *subResolver {
+	return r.subResolvers([]model.Sub{sub})[0]
+}
+
+// userBatch loads users of a list of subs the first time one of them is
+// asked for, instead of a query per sub
+type userBatch struct {
+	r   *Resolver
+	ids []string
+
+	once  sync.Once
+	users map[string]model.User
+	err   error
+}
+
+func (b *userBatch) get(ctx context.Context, id string) (*userResolver, error) {
+	b.once.Do(func() {
+		var users []model.User
+		users, b.err = b.r.users.GetByIds(ctx, b.ids)
+		b.users = make(map[string]model.User, len(users))
+		for _, u := range users {
+			b.users[u.UserId] = u
+		}
+	})
+	if b.err != nil {
+		return nil, apiError(b.r.log, "get users", b.err)
+	}
+	user, ok := b.users[id]
+	if !ok {
+		return nil, apiError(b.r.log, "get user", repository.ErrNotFound)
+	}
+	return &userResolver{r: b.r, user: user}, nil
+}
+
+// requireScope checks a scope beyond the read scope of the endpoint
+func requireScope(ctx context.Context, scope string) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return &Error{Message: "missing credentials", Code: CodeUnauthorized}
+	}
+	if !p.HasScope(scope) {
+		return &Error{Message: "insufficient scope, " + scope + " required", Code: CodeForbidden}
+	}
+	return nil
+}
+
+type subResolver struct {
+	r     *Resolver
+	sub   model.Sub
+	users *userBatch
+}
+
+func (s *subResolver) Id() int32           { return int32(s.sub.SubId) }
+func (s *subResolver) OrgId() string       { return s.sub.OrgId }
+func (s *subResolver) ServiceId() int32    { return int32(s.sub.ServiceId) }
+func (s *subResolver) ServiceName() string { return s.sub.ServiceName }
+func (s *subResolver) Price() int32        { return int32(s.sub.Price) }
+func (s *subResolver) UserId() graphql.ID  { return graphql.ID(s.sub.UserId) }
+func (s *subResolver) StartDate() string   { return s.sub.StartDate.Format(time.RFC3339) }
+
+func (s *subResolver) EndDate() *string {
+	if s.sub.EndDate == nil {
+		return nil
+	}
+	d := s.sub.EndDate.Format(time.RFC3339)
+	return &d
+}
+
+func (s *subResolver) User(ctx context.Context) (*userResolver, error) {
+	return s.users.get(ctx, s.sub.UserId)
+}
+
+type userResolver struct {
+	r    *Resolver
+	user model.User
+}
+
+func (u *userResolver) Id() graphql.ID    { return graphql.ID(u.user.UserId) }
+func (u *userResolver) Name() string      { return u.user.Name }
+func (u *userResolver) CreatedAt() string { return u.user.CreatedAt.Format(time.RFC3339) }
+
+func (u *userResolver) Subs(ctx context.Context, args struct {
+	Filter *subFilterInput
+	pageArgs
+}) ([]*subResolver, error) {
+	filter := args.Filter.filter()
+	filter.UserId = u.user.UserId
+	limit, offset := args.page()
+	subs, err := u.r.subs.List(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, apiError(u.r.log, "list subs", err)
+	}
+	return u.r.subResolvers(subs), nil
+}
+
+// Spend is SumCost of the user's subscriptions, null with an error if the
+// caller may not see them
+func (u *userResolver) Spend(ctx context.Context, args periodArgs) (*Int64, error) {
+	start, end, err := args.period()
+	if err != nil {
+		return nil, err
+	}
+	filter := args.Filter.filter()
+	filter.UserId = u.user.UserId
+	total, err := u.r.subs.SumCost(ctx, filter, start, end)
+	if err != nil {
+		return nil, apiError(u.r.log, "sum cost", err)
+	}
+	spend := Int64(total)
+	return &spend, nil
+}
@@ -0,0 +1,172 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/auth"
+	"github.com/tmozzze/SubChecker/internal/config"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/service"
+	"github.com/tmozzze/SubChecker/internal/stream"
+	"github.com/tmozzze/SubChecker/internal/utils"
+)
+
+type nopObserver struct{}
+
+func (nopObserver) ObserveSumCost(time.Duration, int) {}
+
+// countingUsers counts batch lookups of users
+type countingUsers struct {
+	service.UserService
+	getByIds int
+	getById  int
+}
+
+func (u *countingUsers) GetByIds(ctx context.Context, ids []string) ([]model.User, error) {
+	u.getByIds++
+	return u.UserService.GetByIds(ctx, ids)
+}
+
+func (u *countingUsers) GetById(ctx context.Context, id string) (*model.User, error) {
+	u.getById++
+	return u.UserService.GetById(ctx, id)
+}
+
+type testAPI struct {
+	schema *graphql.Schema
+	subs   service.SubService
+	users  *countingUsers
+}
+
+func newTestAPI(t *testing.T, maxDepth int) *testAPI {
+	t.Helper()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	repos := repository.NewMemoryRepositories(repository.NewMemoryDB(), log)
+	subs := service.NewSubService(repos.Subs, repos.Users, repos.Services, repos.PriceChanges, repos.Webhooks,
+		stream.NewBroker(0), repos.Tx, nopObserver{}, config.OverlapOff, log)
+	users := &countingUsers{UserService: service.NewUserService(repos.Users, log)}
+	schema, err := NewSchema(subs, users, maxDepth, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testAPI{schema: schema, subs: subs, users: users}
+}
+
+// exec runs query as a caller with scopes and returns the codes of errors
+func (a *testAPI) exec(t *testing.T, query string, scopes ...string) (json.RawMessage, []string) {
+	t.Helper()
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "apikey:test", Scopes: scopes})
+	resp := a.schema.Exec(ctx, query, "", nil)
+	var codes []string
+	for _, err := range resp.Errors {
+		code, _ := err.Extensions["code"].(string)
+		if code == "" {
+			code = err.Message
+		}
+		codes = append(codes, code)
+	}
+	return resp.Data, codes
+}
+
+func (a *testAPI) createSubs(t *testing.T, n int, users ...string) {
+	t.Helper()
+	for i := range n {
+		_, err := a.subs.Create(context.Background(), &model.Sub{
+			ServiceName: "Netflix",
+			Price:       400,
+			UserId:      users[i%len(users)],
+			StartDate:   time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+const (
+	userA = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	userB = "0c3bd2c1-7a8e-4bd2-9f3a-5d6f0c8b1e2a"
+)
+
+func TestMaxDepth(t *testing.T) {
+	api := newTestAPI(t, 3)
+
+	if _, codes := api.exec(t, `{ subs { user { name } } }`, auth.ScopeRead); len(codes) != 0 {
+		t.Fatalf("3 levels: got errors %v", codes)
+	}
+	_, codes := api.exec(t, `{ subs { user { subs { id } } } }`, auth.ScopeRead)
+	if len(codes) == 0 {
+		t.Fatal("4 levels: got no error")
+	}
+}
+
+func TestMutationsNeedWriteScope(t *testing.T) {
+	api := newTestAPI(t, 6)
+	api.createSubs(t, 1, userA)
+
+	mutations := map[string]string{
+		"createSub": fmt.Sprintf(`mutation { createSub(input: {serviceName: "Netflix", price: 400, userId: %q, startDate: "07-2025"}) { sub { id } } }`, userA),
+		"updateSub": fmt.Sprintf(`mutation { updateSub(id: 1, input: {serviceName: "Netflix", price: 500, userId: %q, startDate: "07-2025"}) { id } }`, userA),
+		"deleteSub": `mutation { deleteSub(id: 1) }`,
+	}
+	for name, mutation := range mutations {
+		t.Run(name, func(t *testing.T) {
+			_, codes := api.exec(t, mutation, auth.ScopeRead)
+			if len(codes) != 1 || codes[0] != CodeForbidden {
+				t.Fatalf("read scope: got errors %v, want %s", codes, CodeForbidden)
+			}
+		})
+	}
+
+	got, err := api.subs.GetById(context.Background(), 1)
+	if err != nil || got.Price != 400 {
+		t.Fatalf("sub was changed without the write scope: %+v, %v", got, err)
+	}
+	if _, codes := api.exec(t, mutations["deleteSub"], auth.ScopeRead, auth.ScopeWrite); len(codes) != 0 {
+		t.Fatalf("write scope: got errors %v", codes)
+	}
+}
+
+func TestSubsLimitIsCapped(t *testing.T) {
+	api := newTestAPI(t, 6)
+	api.createSubs(t, utils.MaxLimit+1, userA)
+
+	for _, query := range []string{
+		`{ subs(limit: 100000) { id } }`,
+		fmt.Sprintf(`{ user(id: %q) { subs(limit: 100000) { id } } }`, userA),
+	} {
+		data, codes := api.exec(t, query, auth.ScopeRead)
+		if len(codes) != 0 {
+			t.Fatalf("%s: got errors %v", query, codes)
+		}
+		if n := strings.Count(string(data), `"id"`); n != utils.MaxLimit {
+			t.Errorf("%s: got %d subs, want %d", query, n, utils.MaxLimit)
+		}
+	}
+}
+
+func TestSubUsersAreLoadedOnce(t *testing.T) {
+	api := newTestAPI(t, 6)
+	api.createSubs(t, 10, userA, userB)
+
+	data, codes := api.exec(t, `{ subs { id user { id } } }`, auth.ScopeRead)
+	if len(codes) != 0 {
+		t.Fatalf("got errors %v", codes)
+	}
+	if n := strings.Count(string(data), userB); n != 5 {
+		t.Errorf("got %d subs of %s, want 5: %s", n, userB, data)
+	}
+	if api.users.getByIds != 1 || api.users.getById != 0 {
+		t.Errorf("got %d batch and %d single lookups, want 1 batch", api.users.getByIds, api.users.getById)
+	}
+}
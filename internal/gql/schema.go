@@ -0,0 +1,53 @@
+// Package gql is the GraphQL schema of subscriptions, users and their
+// spend, resolved with the same services as the REST API
+package gql
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/service"
+)
+
+//go:embed schema.graphql
+var schema string
+
+// NewSchema limits queries to maxDepth nested selections
+func NewSchema(subs service.SubService, users service.UserService, maxDepth int, log *logrus.Logger) (*graphql.Schema, error) {
+	r := &Resolver{subs: subs, users: users, log: log}
+	return graphql.ParseSchema(schema, r, graphql.MaxDepth(maxDepth))
+}
+
+// Int64 is the Int64 scalar, GraphQL Int is 32-bit
+type Int64 int64
+
+func (Int64) ImplementsGraphQLType(name string) bool {
+	return name == "Int64"
+}
+
+func (n *Int64) UnmarshalGraphQL(input any) error {
+	switch v := input.(type) {
+	case int32:
+		*n = Int64(v)
+	case int64:
+		*n = Int64(v)
+	case float64:
+		*n = Int64(v)
+	case string:
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return err
+		}
+		*n = Int64(i)
+	default:
+		return fmt.Errorf("wrong type for Int64: %T", input)
+	}
+	return nil
+}
+
+func (n Int64) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(n), 10), nil
+}
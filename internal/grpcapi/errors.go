@@ -0,0 +1,39 @@
+package grpcapi
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusError maps service errors to gRPC statuses the way the REST
+// handlers map them to HTTP statuses. Unexpected errors are logged with
+// op and hidden from the caller.
+func statusError(log *logrus.Logger, op string, err error) error {
+	var overlap *service.OverlapError
+	switch {
+	case errors.As(err, &overlap):
+		return status.Errorf(codes.AlreadyExists, "subscription overlaps existing ones: %s", subIds(overlap.Subs))
+	case errors.Is(err, service.ErrConflict):
+		return status.Error(codes.AlreadyExists, "subscription overlaps existing ones")
+	case errors.Is(err, repository.ErrConflict):
+		// e.g. the service was deleted while the subscription was written
+		return status.Error(codes.FailedPrecondition, "subscription conflicts with the current state")
+	case errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, "subscription not found")
+	case errors.Is(err, service.ErrForbidden):
+		return status.Error(codes.PermissionDenied, "forbidden")
+	case errors.Is(err, service.ErrUnknownService):
+		return status.Error(codes.InvalidArgument, "unknown service_id")
+	case errors.Is(err, service.ErrEmptyServiceName):
+		return status.Error(codes.InvalidArgument, "service_name is empty")
+	case errors.Is(err, service.ErrNoDefaultPrice):
+		return status.Error(codes.InvalidArgument, "price is required, the service has no default price")
+	}
+	log.WithError(err).Error(op + " failed")
+	return status.Error(codes.Internal, "internal")
+}
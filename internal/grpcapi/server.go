@@ -0,0 +1,130 @@
+// Package grpcapi serves the subchecker.v1 gRPC API on top of the same
+// service layer as the REST handlers
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	pb "github.com/tmozzze/SubChecker/api/subchecker/v1"
+	"github.com/tmozzze/SubChecker/internal/auth"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+	"github.com/tmozzze/SubChecker/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIdKey is the metadata key of the request ID, the same as the REST header
+const RequestIdKey = "x-request-id"
+
+// methodScopes is the scope every method requires, like the REST routes
+var methodScopes = map[string]string{
+	pb.SubService_CreateSub_FullMethodName: auth.ScopeWrite,
+	pb.SubService_GetSub_FullMethodName:    auth.ScopeRead,
+	pb.SubService_UpdateSub_FullMethodName: auth.ScopeWrite,
+	pb.SubService_DeleteSub_FullMethodName: auth.ScopeWrite,
+	pb.SubService_ListSubs_FullMethodName:  auth.ScopeRead,
+	pb.SubService_SumCost_FullMethodName:   auth.ScopeRead,
+}
+
+// NewServer serves svc to callers resolved by authenticators
+func NewServer(svc service.SubService, log *logrus.Logger, authenticators ...auth.Authenticator) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		RequestContext(),
+		Authenticate(log, authenticators...),
+		RequireScope(),
+	))
+	pb.RegisterSubServiceServer(srv, NewSubServer(svc, log))
+	return srv
+}
+
+// RequestContext puts the request ID from metadata into the context
+func RequestContext() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		requestId := first(md, RequestIdKey)
+		if requestId == "" {
+			requestId = reqctx.NewRequestId()
+		}
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIdKey, requestId))
+
+		return handler(reqctx.WithRequestId(ctx, requestId), req)
+	}
+}
+
+// Authenticate resolves the caller like the REST middleware. Metadata is
+// passed to authenticators as request headers, e.g. x-api-key and authorization.
+func Authenticate(log *logrus.Logger, authenticators ...auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if len(authenticators) == 0 {
+			return handler(withPrincipal(ctx, auth.Anonymous), req)
+		}
+
+		r, err := http.NewRequestWithContext(ctx, http.MethodPost, info.FullMethod, nil)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "internal")
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		for k, values := range md {
+			for _, v := range values {
+				r.Header.Add(k, v)
+			}
+		}
+
+		for _, a := range authenticators {
+			p, err := a.Authenticate(r)
+			if errors.Is(err, auth.ErrNoCredentials) {
+				continue
+			}
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				log.WithError(err).Warn("authentication failed")
+				return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+			}
+			if err != nil {
+				log.WithError(err).Error("authentication failed")
+				return nil, status.Error(codes.Internal, "internal")
+			}
+
+			return handler(withPrincipal(ctx, p), req)
+		}
+
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+}
+
+// withPrincipal records p as the caller and the actor of the audit log
+func withPrincipal(ctx context.Context, p *auth.Principal) context.Context {
+	ctx = auth.WithPrincipal(ctx, p)
+	ctx = reqctx.WithActor(ctx, p.Subject)
+	return reqctx.WithOrgId(ctx, p.OrgId)
+}
+
+// RequireScope rejects callers that were not granted the scope of the method
+func RequireScope() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		p, ok := auth.FromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing credentials")
+		}
+		scope, ok := methodScopes[info.FullMethod]
+		if !ok {
+			scope = auth.ScopeAdmin
+		}
+		if !p.HasScope(scope) {
+			return nil, status.Error(codes.PermissionDenied, "insufficient scope, "+scope+" required")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
@@ -0,0 +1,214 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	pb "github.com/tmozzze/SubChecker/api/subchecker/v1"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/service"
+	"github.com/tmozzze/SubChecker/internal/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type SubServer struct {
+	pb.UnimplementedSubServiceServer
+	svc service.SubService
+	log *logrus.Logger
+}
+
+func NewSubServer(s service.SubService, l *logrus.Logger) *SubServer {
+	return &SubServer{svc: s, log: l}
+}
+
+// subFields are what create and update requests set
+type subFields struct {
+	ServiceId   int64
+	ServiceName string
+	Price       *int64
+	UserId      string
+	StartDate   string
+	EndDate     string
+}
+
+// sub checks fields and builds a subscription of them, prefilling the price
+// from the catalog if it is not given
+func (h *SubServer) sub(ctx context.Context, id int, f subFields) (*model.Sub, error) {
+	if f.ServiceId < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid service_id")
+	}
+	if f.ServiceId == 0 && f.ServiceName == "" {
+		return nil, status.Error(codes.InvalidArgument, "service_id or service_name is required")
+	}
+	if _, err := uuid.Parse(f.UserId); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user_id")
+	}
+	sd, err := utils.ParseMonth(f.StartDate)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "bad start_date format, expected MM-YYYY")
+	}
+	var ed *time.Time
+	if f.EndDate != "" {
+		t, err := utils.ParseMonth(f.EndDate)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "bad end_date format, expected MM-YYYY")
+		}
+		ed = &t
+	}
+
+	sub := &model.Sub{
+		SubId:       id,
+		ServiceId:   int(f.ServiceId),
+		ServiceName: f.ServiceName,
+		UserId:      f.UserId,
+		StartDate:   sd,
+		EndDate:     ed,
+	}
+	if f.Price != nil {
+		if *f.Price < 0 {
+			return nil, status.Error(codes.InvalidArgument, "price must not be negative")
+		}
+		sub.Price = int(*f.Price)
+		return sub, nil
+	}
+	if err := h.svc.Prefill(ctx, sub); err != nil {
+		return nil, statusError(h.log, "prefill", err)
+	}
+	return sub, nil
+}
+
+func (h *SubServer) CreateSub(ctx context.Context, req *pb.CreateSubRequest) (*pb.CreateSubResponse, error) {
+	sub, err := h.sub(ctx, 0, subFields{
+		ServiceId:   req.GetServiceId(),
+		ServiceName: req.GetServiceName(),
+		Price:       req.Price,
+		UserId:      req.GetUserId(),
+		StartDate:   req.GetStartDate(),
+		EndDate:     req.GetEndDate(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	overlapping, err := h.svc.Create(ctx, sub)
+	if err != nil {
+		return nil, statusError(h.log, "create sub", err)
+	}
+	warnOverlap(ctx, overlapping)
+	return &pb.CreateSubResponse{Sub: toPb(sub)}, nil
+}
+
+// warnOverlap sets the warning header for a written subscription that
+// overlaps others, like the REST handlers
+func warnOverlap(ctx context.Context, overlapping []model.Sub) {
+	if len(overlapping) == 0 {
+		return
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs("warning", fmt.Sprintf(`299 - "subscription overlaps subscriptions %s"`, subIds(overlapping))))
+}
+
+// subIds lists ids of subs separated by commas
+func subIds(subs []model.Sub) string {
+	ids := make([]string, 0, len(subs))
+	for _, s := range subs {
+		ids = append(ids, strconv.Itoa(s.SubId))
+	}
+	return strings.Join(ids, ", ")
+}
+
+func (h *SubServer) GetSub(ctx context.Context, req *pb.GetSubRequest) (*pb.GetSubResponse, error) {
+	sub, err := h.svc.GetById(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, statusError(h.log, "get sub", err)
+	}
+	return &pb.GetSubResponse{Sub: toPb(sub)}, nil
+}
+
+func (h *SubServer) UpdateSub(ctx context.Context, req *pb.UpdateSubRequest) (*pb.UpdateSubResponse, error) {
+	sub, err := h.sub(ctx, int(req.GetId()), subFields{
+		ServiceId:   req.GetServiceId(),
+		ServiceName: req.GetServiceName(),
+		Price:       req.Price,
+		UserId:      req.GetUserId(),
+		StartDate:   req.GetStartDate(),
+		EndDate:     req.GetEndDate(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	overlapping, err := h.svc.Update(ctx, sub)
+	if err != nil {
+		return nil, statusError(h.log, "update", err)
+	}
+	warnOverlap(ctx, overlapping)
+	return &pb.UpdateSubResponse{Sub: toPb(sub)}, nil
+}
+
+func (h *SubServer) DeleteSub(ctx context.Context, req *pb.DeleteSubRequest) (*pb.DeleteSubResponse, error) {
+	if err := h.svc.Delete(ctx, int(req.GetId())); err != nil {
+		return nil, statusError(h.log, "delete", err)
+	}
+	return &pb.DeleteSubResponse{}, nil
+}
+
+func (h *SubServer) ListSubs(ctx context.Context, req *pb.ListSubsRequest) (*pb.ListSubsResponse, error) {
+	limit, offset := utils.Page(int(req.GetLimit()), int(req.GetOffset()))
+
+	subs, err := h.svc.List(ctx, toFilter(req.GetFilter()), limit, offset)
+	if err != nil {
+		return nil, statusError(h.log, "list subs", err)
+	}
+	resp := &pb.ListSubsResponse{Subs: make([]*pb.Sub, 0, len(subs))}
+	for i := range subs {
+		resp.Subs = append(resp.Subs, toPb(&subs[i]))
+	}
+	return resp, nil
+}
+
+func (h *SubServer) SumCost(ctx context.Context, req *pb.SumCostRequest) (*pb.SumCostResponse, error) {
+	pStart, err := utils.ParseMonth(req.GetStartMonth())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "bad start_month")
+	}
+	pEnd, err := utils.ParseMonth(req.GetEndMonth())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "bad end_month")
+	}
+
+	total, err := h.svc.SumCost(ctx, toFilter(req.GetFilter()), pStart, pEnd)
+	if err != nil {
+		return nil, statusError(h.log, "sum cost", err)
+	}
+	return &pb.SumCostResponse{TotalRub: total}, nil
+}
+
+func toFilter(f *pb.SubFilter) model.SubFilter {
+	return model.SubFilter{
+		UserId:      f.GetUserId(),
+		ServiceId:   int(f.GetServiceId()),
+		ServiceName: f.GetServiceName(),
+	}
+}
+
+func toPb(s *model.Sub) *pb.Sub {
+	sub := &pb.Sub{
+		Id:          int64(s.SubId),
+		OrgId:       s.OrgId,
+		ServiceId:   int64(s.ServiceId),
+		ServiceName: s.ServiceName,
+		Price:       int64(s.Price),
+		UserId:      s.UserId,
+		StartDate:   s.StartDate.Format(utils.MonthLayout),
+	}
+	if s.EndDate != nil {
+		sub.EndDate = s.EndDate.Format(utils.MonthLayout)
+	}
+	return sub
+}
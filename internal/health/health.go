@@ -0,0 +1,151 @@
+// Package health tells whether the service is ready to serve requests
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check fails if a dependency of the service is not usable
+type Check func(ctx context.Context) error
+
+// Status of a check and of the whole report
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+)
+
+// errShuttingDown fails readiness once the server starts shutting down
+var errShuttingDown = errors.New("shutting down")
+
+// Result is the outcome of one check
+type Result struct {
+	Status string `json:"status" example:"ok"`
+	Error  string `json:"error,omitempty" example:""`
+	// DurationMs is how long the check took
+	DurationMs int64 `json:"duration_ms" example:"2"`
+}
+
+// Report is the outcome of all checks, it is ok if every check is
+type Report struct {
+	Status string            `json:"status" example:"ok"`
+	Checks map[string]Result `json:"checks"`
+}
+
+func (r Report) Ok() bool {
+	return r.Status == StatusOk
+}
+
+// Checker runs readiness checks. Checks are added at start and run
+// concurrently, each limited by timeout.
+type Checker struct {
+	timeout  time.Duration
+	checks   map[string]Check
+	draining atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: make(map[string]Check)}
+}
+
+// Add registers a check, it must not be called once the server is running
+func (c *Checker) Add(name string, check Check) {
+	c.checks[name] = check
+}
+
+// Drain makes the service not ready for good, so that it is taken out of
+// rotation while it shuts down
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready runs the checks
+func (c *Checker) Ready(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]Result, len(c.checks)+1)
+	)
+	for name, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started := time.Now()
+			err := check(ctx)
+			r := Result{Status: StatusOk, DurationMs: time.Since(started).Milliseconds()}
+			if err != nil {
+				r.Status = StatusFail
+				r.Error = err.Error()
+			}
+			mu.Lock()
+			results[name] = r
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if c.draining.Load() {
+		results["shutdown"] = Result{Status: StatusFail, Error: errShuttingDown.Error()}
+	}
+
+	report := Report{Status: StatusOk, Checks: results}
+	for _, r := range results {
+		if r.Status != StatusOk {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// Workers tracks background workers, a worker that returned while the
+// service is running has stopped doing its job
+type Workers struct {
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func NewWorkers() *Workers {
+	return &Workers{running: make(map[string]bool)}
+}
+
+// Go runs a worker until it returns
+func (w *Workers) Go(name string, run func()) {
+	w.mu.Lock()
+	w.running[name] = true
+	w.mu.Unlock()
+
+	go func() {
+		defer func() {
+			w.mu.Lock()
+			w.running[name] = false
+			w.mu.Unlock()
+		}()
+		run()
+	}()
+}
+
+// Check fails if a worker is not running
+func (w *Workers) Check(context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var stopped []string
+	for name, running := range w.running {
+		if !running {
+			stopped = append(stopped, name)
+		}
+	}
+	if len(stopped) > 0 {
+		sort.Strings(stopped)
+		return errors.New("stopped: " + strings.Join(stopped, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/auth"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+// Authenticate resolves the caller with the first authenticator that finds
+// credentials in the request. Without authenticators every caller is
+// auth.Anonymous, which keeps the API open as before.
+func Authenticate(log *logrus.Logger, authenticators ...auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(authenticators) == 0 {
+			setPrincipal(c, auth.Anonymous)
+			c.Next()
+			return
+		}
+
+		for _, a := range authenticators {
+			p, err := a.Authenticate(c.Request)
+			if errors.Is(err, auth.ErrNoCredentials) {
+				continue
+			}
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				log.WithError(err).Warn("authentication failed")
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+				return
+			}
+			if err != nil {
+				log.WithError(err).Error("authentication failed")
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+				return
+			}
+
+			setPrincipal(c, p)
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	}
+}
+
+func setPrincipal(c *gin.Context, p *auth.Principal) {
+	ctx := auth.WithPrincipal(c.Request.Context(), p)
+	ctx = reqctx.WithActor(ctx, p.Subject)
+	ctx = reqctx.WithOrgId(ctx, p.OrgId)
+	c.Request = c.Request.WithContext(ctx)
+}
+
+// RequireScope rejects callers that were not granted scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := auth.FromContext(c.Request.Context())
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+			return
+		}
+		if !p.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope, " + scope + " required"})
+			return
+		}
+		c.Next()
+	}
+}
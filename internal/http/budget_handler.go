@@ -0,0 +1,293 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/service"
+	"github.com/tmozzze/SubChecker/internal/utils"
+)
+
+// maxReportMonths limits the period of a budget report
+const maxReportMonths = 120
+
+type BudgetHandler struct {
+	svc service.BudgetService
+	log *logrus.Logger
+}
+
+func NewBudgetHandler(s service.BudgetService, l *logrus.Logger) *BudgetHandler {
+	return &BudgetHandler{svc: s, log: l}
+}
+
+// createBudgetReq sets either user_id or team
+type createBudgetReq struct {
+	UserId    string `json:"user_id,omitempty" binding:"omitempty,uuid"`
+	Team      string `json:"team,omitempty"`
+	AmountRub *int   `json:"amount_rub" binding:"required,min=0"`
+}
+
+type updateBudgetReq struct {
+	AmountRub *int `json:"amount_rub" binding:"required,min=0"`
+}
+
+type budgetReportReq struct {
+	StartMonth string `form:"start_month" binding:"required"` // MM-YYYY
+	EndMonth   string `form:"end_month" binding:"required"`   // MM-YYYY
+}
+
+func budgetId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("budget_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid budget_id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateBudget godoc
+// @Summary Create budget
+// @Description Set a monthly budget for a user or for a team from the access policy
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param body body createBudgetReq true "Budget"
+// @Success 201 {object} model.Budget
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /budgets [post]
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	var req createBudgetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.WithError(err).Warn("invalid create budget request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	b := &model.Budget{UserId: req.UserId, Team: req.Team, AmountRub: *req.AmountRub}
+	err := h.svc.Create(c.Request.Context(), b)
+	if errors.Is(err, service.ErrInvalidBudget) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "either user_id or team is required"})
+		return
+	}
+	if errors.Is(err, service.ErrUnknownTeam) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown team"})
+		return
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "budget already exists"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("create budget failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusCreated, b)
+}
+
+// GetBudgetById godoc
+// @Summary Get budget by ID
+// @Tags budgets
+// @Produce json
+// @Param id path int true "Budget ID"
+// @Success 200 {object} model.Budget
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /budgets/{id} [get]
+func (h *BudgetHandler) GetBudgetById(c *gin.Context) {
+	id, ok := budgetId(c)
+	if !ok {
+		return
+	}
+
+	b, err := h.svc.GetById(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "budget not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("get budget failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// ListBudgets godoc
+// @Summary List budgets
+// @Description Get paginated list of budgets ordered by ID. Under /api/v2 the list is wrapped in http.ListResponse.
+// @Tags budgets
+// @Produce json
+// @Param limit query int false "limit (default 50, at most 500)"
+// @Param offset query int false "offset (default 0)"
+// @Success 200 {array} model.Budget
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /budgets [get]
+func (h *BudgetHandler) ListBudgets(c *gin.Context) {
+	limit, offset := parsePage(c)
+
+	budgets, err := h.svc.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.log.WithError(err).Error("list budgets failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	err = writePage(c, budgets, limit, offset, func() (int, error) {
+		return h.svc.Count(c.Request.Context())
+	})
+	if err != nil {
+		h.log.WithError(err).Error("count budgets failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+	}
+}
+
+// UpdateBudget godoc
+// @Summary Update budget
+// @Description Change the monthly amount of a budget
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param id path int true "Budget ID"
+// @Param body body updateBudgetReq true "Budget"
+// @Success 200 {object} model.Budget
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /budgets/{id} [put]
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	id, ok := budgetId(c)
+	if !ok {
+		return
+	}
+
+	var req updateBudgetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.WithError(err).Warn("invalid update budget request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	b := &model.Budget{BudgetId: id, AmountRub: *req.AmountRub}
+	err := h.svc.Update(c.Request.Context(), b)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "budget not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("update budget failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, b)
+}
+
+// DeleteBudget godoc
+// @Summary Delete budget
+// @Tags budgets
+// @Produce json
+// @Param id path int true "Budget ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /budgets/{id} [delete]
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	id, ok := budgetId(c)
+	if !ok {
+		return
+	}
+
+	err := h.svc.Delete(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "budget not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("delete budget failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetBudgetReport godoc
+// @Summary Budget report
+// @Description Compare spend of every month of the period (inclusive, at most 120 months) with the budget. Spend is computed the same way as /subs/sum.
+// @Tags budgets
+// @Produce json
+// @Param id path int true "Budget ID"
+// @Param start_month query string true "MM-YYYY"
+// @Param end_month query string true "MM-YYYY"
+// @Success 200 {object} model.BudgetReport
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /budgets/{id}/report [get]
+func (h *BudgetHandler) GetBudgetReport(c *gin.Context) {
+	id, ok := budgetId(c)
+	if !ok {
+		return
+	}
+
+	var q budgetReportReq
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pStart, err := utils.ParseMonth(q.StartMonth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad start_month"})
+		return
+	}
+	pEnd, err := utils.ParseMonth(q.EndMonth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad end_month"})
+		return
+	}
+	if pEnd.Before(pStart) || !pEnd.Before(pStart.AddDate(0, maxReportMonths, 0)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_month must be within 120 months after start_month"})
+		return
+	}
+
+	report, err := h.svc.Report(c.Request.Context(), id, pStart, pEnd)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "budget not found"})
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("budget report failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,257 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/service"
+)
+
+type CatalogHandler struct {
+	svc service.CatalogService
+	log *logrus.Logger
+}
+
+func NewCatalogHandler(s service.CatalogService, l *logrus.Logger) *CatalogHandler {
+	return &CatalogHandler{svc: s, log: l}
+}
+
+type serviceReq struct {
+	Name          string `json:"name" binding:"required"`
+	Category      string `json:"category"`
+	DefaultPrice  *int   `json:"default_price,omitempty" binding:"omitempty,min=0"`
+	BillingPeriod string `json:"billing_period,omitempty" binding:"omitempty,oneof=month year"`
+	Website       string `json:"website,omitempty" binding:"omitempty,url"`
+	LogoUrl       string `json:"logo_url,omitempty" binding:"omitempty,url"`
+}
+
+func (r serviceReq) service(id int) *model.Service {
+	return &model.Service{
+		ServiceId:     id,
+		Name:          r.Name,
+		Category:      r.Category,
+		DefaultPrice:  r.DefaultPrice,
+		BillingPeriod: r.BillingPeriod,
+		Website:       r.Website,
+		LogoUrl:       r.LogoUrl,
+	}
+}
+
+// serviceError returns the message for a rejected catalog entry
+func serviceError(err error) (string, bool) {
+	switch {
+	case errors.Is(err, service.ErrEmptyServiceName):
+		return "name is empty", true
+	case errors.Is(err, service.ErrInvalidBillingPeriod):
+		return "billing_period must be month or year", true
+	}
+	return "", false
+}
+
+func serviceId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("service_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid service_id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateService godoc
+// @Summary Create service
+// @Description Add a service to the catalog. Names are unique ignoring case and extra spaces. default_price is per billing_period (month by default).
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param body body serviceReq true "Service"
+// @Success 201 {object} model.Service
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /services [post]
+func (h *CatalogHandler) CreateService(c *gin.Context) {
+	var req serviceReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.WithError(err).Warn("invalid create service request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s := req.service(0)
+	err := h.svc.Create(c.Request.Context(), s)
+	if msg, ok := serviceError(err); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "service already exists"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("create service failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+// GetServiceById godoc
+// @Summary Get service by ID
+// @Tags services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 200 {object} model.Service
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /services/{id} [get]
+func (h *CatalogHandler) GetServiceById(c *gin.Context) {
+	id, ok := serviceId(c)
+	if !ok {
+		return
+	}
+
+	s, err := h.svc.GetById(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "service not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("get service failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// ListServices godoc
+// @Summary List services
+// @Description Get paginated list of services ordered by ID. Under /api/v2 the list is wrapped in http.ListResponse.
+// @Tags services
+// @Produce json
+// @Param limit query int false "limit (default 50, at most 500)"
+// @Param offset query int false "offset (default 0)"
+// @Success 200 {array} model.Service
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /services [get]
+func (h *CatalogHandler) ListServices(c *gin.Context) {
+	limit, offset := parsePage(c)
+
+	services, err := h.svc.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.log.WithError(err).Error("list services failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	err = writePage(c, services, limit, offset, func() (int, error) {
+		return h.svc.Count(c.Request.Context())
+	})
+	if err != nil {
+		h.log.WithError(err).Error("count services failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+	}
+}
+
+// UpdateService godoc
+// @Summary Update service
+// @Description Replace name and details of a service. Subscriptions follow a rename, their prices don't change.
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param id path int true "Service ID"
+// @Param body body serviceReq true "Service"
+// @Success 200 {object} model.Service
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /services/{id} [put]
+func (h *CatalogHandler) UpdateService(c *gin.Context) {
+	id, ok := serviceId(c)
+	if !ok {
+		return
+	}
+
+	var req serviceReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.WithError(err).Warn("invalid update service request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s := req.service(id)
+	err := h.svc.Update(c.Request.Context(), s)
+	if msg, ok := serviceError(err); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "service not found"})
+		return
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "service already exists"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("update service failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// DeleteService godoc
+// @Summary Delete service
+// @Description Delete a service without subscriptions
+// @Tags services
+// @Produce json
+// @Param id path int true "Service ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /services/{id} [delete]
+func (h *CatalogHandler) DeleteService(c *gin.Context) {
+	id, ok := serviceId(c)
+	if !ok {
+		return
+	}
+
+	err := h.svc.Delete(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "service not found"})
+		return
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "service has subscriptions"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("delete service failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
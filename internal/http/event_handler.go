@@ -0,0 +1,111 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/service"
+)
+
+// keepAlive is how often an idle stream sends a comment, so that proxies
+// don't close it
+const keepAlive = 15 * time.Second
+
+// resetEvent tells a resuming client that changes were missed and it
+// should reload subscriptions
+const resetEvent = "reset"
+
+type subEventsReq struct {
+	UserId      string `form:"user_id"`
+	ServiceId   int    `form:"service_id" binding:"min=0"`
+	ServiceName string `form:"service_name"`
+	// LastEventId is for clients that can't set the Last-Event-ID header
+	LastEventId int64 `form:"last_event_id" binding:"min=0"`
+}
+
+// SubEvents godoc
+// @Summary Stream subscription changes
+// @Description Server-Sent Events of committed changes: sub.created, sub.updated, sub.ended, sub.deleted. An update matches filters by the state before or after it. A client resumes after the Last-Event-ID header (or last_event_id) from a bounded buffer of recent events; if some were dropped a "reset" event is sent first and the subscriptions should be reloaded, as well as for an unknown id. With PostgreSQL event ids are global and a client may resume on any server instance.
+// @Tags subs
+// @Produce text/event-stream
+// @Param user_id query string false "UUID"
+// @Param service_id query int false "service ID"
+// @Param service_name query string false "service name, case-insensitive"
+// @Param last_event_id query int false "resume after this event id"
+// @Param Last-Event-ID header int false "resume after this event id"
+// @Success 200 {object} model.SubEvent
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /subs/events [get]
+func (h *SubHandler) SubEvents(c *gin.Context) {
+	var q subEventsReq
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	afterId := q.LastEventId
+	if v := c.GetHeader("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		afterId = id
+	}
+
+	filter := model.SubFilter{UserId: q.UserId, ServiceId: q.ServiceId, ServiceName: q.ServiceName}
+	sub, err := h.svc.Watch(c.Request.Context(), filter, afterId)
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("watch subscriptions failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Don't let nginx buffer the stream
+	c.Header("X-Accel-Buffering", "no")
+	// The stream outlives the server write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.log.WithError(err).Warn("failed to lift write deadline of the stream")
+	}
+	c.Status(http.StatusOK)
+	if sub.Missed {
+		c.Render(-1, sse.Event{Event: resetEvent, Data: ""})
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case e, ok := <-sub.Events:
+			if !ok {
+				// Fell behind, the client reconnects and resumes
+				return false
+			}
+			c.Render(-1, sse.Event{Id: strconv.FormatInt(e.Id, 10), Event: e.Type, Data: e})
+			return true
+		case <-ticker.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
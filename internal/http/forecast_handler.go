@@ -0,0 +1,210 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/service"
+	"github.com/tmozzze/SubChecker/internal/utils"
+)
+
+type priceChangeReq struct {
+	Month string `json:"month" binding:"required"` // MM-YYYY
+	Price *int   `json:"price" binding:"required,min=0"`
+}
+
+// Forecast godoc
+// @Summary Forecast cost
+// @Description Spend of every month of the period (inclusive, at most 120 months). Months up to the current one are actual and counted like /subs/sum. Later months are projected: open subscriptions continue, end dates and scheduled price changes apply. Filters: user_id, service_id, service_name
+// @Tags subs
+// @Produce json
+// @Param start_month query string true "MM-YYYY"
+// @Param end_month query string true "MM-YYYY"
+// @Param user_id query string false "UUID"
+// @Param service_id query int false "service ID"
+// @Param service_name query string false "service name, case-insensitive"
+// @Success 200 {object} model.Forecast
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /subs/forecast [get]
+func (h *SubHandler) Forecast(c *gin.Context) {
+	var q sumReq
+	if err := c.ShouldBindQuery(&q); err != nil {
+		h.log.WithError(err).Warn("invalid forecast request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pStart, err := utils.ParseMonth(q.StartMonth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad start_month"})
+		return
+	}
+	pEnd, err := utils.ParseMonth(q.EndMonth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad end_month"})
+		return
+	}
+	if pEnd.Before(pStart) || !pEnd.Before(pStart.AddDate(0, maxReportMonths, 0)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_month must be within 120 months after start_month"})
+		return
+	}
+
+	filter := model.SubFilter{UserId: q.UserId, ServiceId: q.ServiceId, ServiceName: q.ServiceName}
+	forecast, err := h.svc.Forecast(c.Request.Context(), filter, pStart, pEnd)
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("forecast failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, forecast)
+}
+
+// SchedulePrice godoc
+// @Summary Schedule price change
+// @Description Set a new price of the subscription from a future month. The price field of the subscription stays the same, but from that month every total (sum, by category, by month, forecast) uses the new price.
+// @Tags subs
+// @Accept json
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param body body priceChangeReq true "Price change"
+// @Success 201 {object} model.PriceChange
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /subs/{id}/price-changes [post]
+func (h *SubHandler) SchedulePrice(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("sub_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sub_id"})
+		return
+	}
+
+	var req priceChangeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.WithError(err).Warn("invalid price change request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m, err := utils.ParseMonth(req.Month)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad month"})
+		return
+	}
+
+	change := &model.PriceChange{SubId: id, Month: m, Price: *req.Price}
+	err = h.svc.SchedulePrice(c.Request.Context(), change)
+	if errors.Is(err, service.ErrPastPriceChange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		return
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "price change for this month already exists"})
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("schedule price change failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusCreated, change)
+}
+
+// ListPriceChanges godoc
+// @Summary List price changes
+// @Description Scheduled price changes of the subscription, oldest month first. Under /api/v2 the list is wrapped in http.ListResponse.
+// @Tags subs
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {array} model.PriceChange
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /subs/{id}/price-changes [get]
+func (h *SubHandler) ListPriceChanges(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("sub_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sub_id"})
+		return
+	}
+
+	changes, err := h.svc.PriceChanges(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("list price changes failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	writeAll(c, changes)
+}
+
+// CancelPriceChange godoc
+// @Summary Cancel price change
+// @Tags subs
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Param change_id path int true "Price change ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /subs/{id}/price-changes/{change_id} [delete]
+func (h *SubHandler) CancelPriceChange(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("sub_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sub_id"})
+		return
+	}
+	changeId, err := strconv.Atoi(c.Param("change_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid change_id"})
+		return
+	}
+
+	err = h.svc.CancelPriceChange(c.Request.Context(), id, changeId)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "price change not found"})
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("cancel price change failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+)
+
+type GraphQLHandler struct {
+	schema *graphql.Schema
+	log    *logrus.Logger
+}
+
+func NewGraphQLHandler(schema *graphql.Schema, l *logrus.Logger) *GraphQLHandler {
+	return &GraphQLHandler{schema: schema, log: l}
+}
+
+type graphqlReq struct {
+	Query         string         `json:"query" binding:"required" example:"{ users { id subs { id price } spend(startMonth: \"01-2025\", endMonth: \"12-2025\") } }"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Query godoc
+// @Summary GraphQL query
+// @Description Query subscriptions, users and their spend in one request, mutations createSub, updateSub and deleteSub need the write scope. Queries nested deeper than GRAPHQL_MAX_DEPTH are rejected. Errors are in "errors" with a code in extensions, the status is 200 unless the request itself is bad.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param body body graphqlReq true "GraphQL request"
+// @Success 200 {object} object
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /graphql [post]
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	var req graphqlReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.WithError(err).Warn("invalid graphql request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := h.schema.Exec(c.Request.Context(), req.Query, req.OperationName, req.Variables)
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tmozzze/SubChecker/internal/health"
+)
+
+// HealthHandler serves the probes of the orchestrator. They are outside of
+// the API versions and need no authentication.
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+// Live is GET /healthz: the process is alive and serves requests. The
+// probes are not in the Swagger docs, they aren't under its base path.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, health.Report{Status: health.StatusOk, Checks: map[string]health.Result{}})
+}
+
+// Ready is GET /readyz: every check passes and the server isn't shutting
+// down, 503 with the failed checks otherwise
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.checker.Ready(c.Request.Context())
+	if !report.Ok() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
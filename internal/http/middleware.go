@@ -0,0 +1,58 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tmozzze/SubChecker/internal/metrics"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+const RequestIdHeader = "X-Request-ID"
+
+// RequestContext puts the request ID into the request context so that lower
+// layers (e.g. audit log) can record it. The actor is set by Authenticate.
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(RequestIdHeader)
+		if requestId == "" {
+			requestId = reqctx.NewRequestId()
+		}
+		c.Header(RequestIdHeader, requestId)
+
+		c.Request = c.Request.WithContext(reqctx.WithRequestId(c.Request.Context(), requestId))
+
+		c.Next()
+	}
+}
+
+// Metrics counts requests by the route they matched, requests that
+// matched none are counted under "unmatched"
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(started))
+	}
+}
+
+// Deprecated marks responses of deprecated paths: Deprecation (RFC 9745)
+// since deprecatedAt, Sunset (RFC 8594) and a Link to the same path under
+// successorPrefix
+func Deprecated(deprecatedAt, sunset time.Time, successorPrefix string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	sunsetAt := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		c.Header("Sunset", sunsetAt)
+		c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, successorPrefix, c.Request.URL.Path))
+		c.Next()
+	}
+}
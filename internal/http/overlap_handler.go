@@ -0,0 +1,87 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/service"
+)
+
+type overlapReq struct {
+	UserId      string `form:"user_id"`
+	ServiceId   int    `form:"service_id" binding:"min=0"`
+	ServiceName string `form:"service_name"`
+}
+
+// Overlaps godoc
+// @Summary Overlapping subscriptions
+// @Description Pairs of subscriptions of one user to one service paid in the same months, up to the current month. The wasted amount is the cheaper price of a pair for every common month. Filters: user_id, service_id, service_name
+// @Tags subs
+// @Produce json
+// @Param user_id query string false "UUID"
+// @Param service_id query int false "service ID"
+// @Param service_name query string false "service name, case-insensitive"
+// @Success 200 {object} model.OverlapReport
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /subs/overlaps [get]
+func (h *SubHandler) Overlaps(c *gin.Context) {
+	var q overlapReq
+	if err := c.ShouldBindQuery(&q); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := model.SubFilter{UserId: q.UserId, ServiceId: q.ServiceId, ServiceName: q.ServiceName}
+	report, err := h.svc.Overlaps(c.Request.Context(), filter)
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("find overlaps failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// overlapConflict answers 409 if err rejects a subscription for overlapping
+// others, and returns false if it doesn't
+func overlapConflict(c *gin.Context, err error) bool {
+	var overlap *service.OverlapError
+	if !errors.As(err, &overlap) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "subscription overlaps existing ones", "sub_ids": subIds(overlap.Subs)})
+	return true
+}
+
+// warnOverlap sets the Warning header for a written subscription that
+// overlaps others
+func warnOverlap(c *gin.Context, overlapping []model.Sub) {
+	if len(overlapping) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(overlapping))
+	for _, id := range subIds(overlapping) {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	c.Header("Warning", fmt.Sprintf(`299 - "subscription overlaps subscriptions %s"`, strings.Join(ids, ", ")))
+}
+
+func subIds(subs []model.Sub) []int {
+	ids := make([]int, 0, len(subs))
+	for _, s := range subs {
+		ids = append(ids, s.SubId)
+	}
+	return ids
+}
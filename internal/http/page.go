@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// envelopeKey marks requests whose collections are wrapped in ListResponse
+const envelopeKey = "envelope"
+
+// Page is where a page is in the collection
+type Page struct {
+	Limit  int `json:"limit" example:"50"`
+	Offset int `json:"offset" example:"0"`
+	Total  int `json:"total" example:"120"`
+}
+
+// Links are relative URLs of the neighbouring pages, empty at the ends
+type Links struct {
+	Next string `json:"next,omitempty" example:"/api/v2/subs?limit=50&offset=50"`
+	Prev string `json:"prev,omitempty" example:""`
+}
+
+// ListResponse is how collections are returned since v2
+type ListResponse struct {
+	Data  any   `json:"data"`
+	Page  Page  `json:"page"`
+	Links Links `json:"links"`
+}
+
+// Envelope makes collections be returned as ListResponse instead of bare
+// arrays
+func Envelope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(envelopeKey, true)
+		c.Next()
+	}
+}
+
+// writePage writes a page of a collection found at limit and offset.
+// count is the size of the whole collection, it is only called for the
+// envelope and only if the page doesn't tell the size itself.
+func writePage[T any](c *gin.Context, items []T, limit, offset int, count func() (int, error)) error {
+	if !c.GetBool(envelopeKey) {
+		c.JSON(http.StatusOK, items)
+		return nil
+	}
+
+	total := offset + len(items)
+	if len(items) == limit || (len(items) == 0 && offset > 0) {
+		var err error
+		if total, err = count(); err != nil {
+			return err
+		}
+	}
+	if items == nil {
+		items = []T{}
+	}
+
+	resp := ListResponse{
+		Data: items,
+		Page: Page{Limit: limit, Offset: offset, Total: total},
+	}
+	if offset+len(items) < total {
+		resp.Links.Next = pageURL(c, limit, offset+limit)
+	}
+	if offset > 0 {
+		resp.Links.Prev = pageURL(c, limit, max(0, min(offset, total)-limit))
+	}
+	c.JSON(http.StatusOK, resp)
+	return nil
+}
+
+// writeAll writes a collection that isn't paginated, all of it is one page
+func writeAll[T any](c *gin.Context, items []T) {
+	_ = writePage(c, items, len(items), 0, func() (int, error) { return len(items), nil })
+}
+
+// pageURL is the request URL moved to another page
+func pageURL(c *gin.Context, limit, offset int) string {
+	u := *c.Request.URL
+	q := u.Query()
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
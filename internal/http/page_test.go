@@ -0,0 +1,125 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageRouter serves a page of 0..total-1 at /items, counting calls of count
+func pageRouter(total int, counted *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/items", Envelope(), func(c *gin.Context) {
+		limit, offset := parsePage(c)
+		var items []int
+		for i := offset; i < min(offset+limit, total); i++ {
+			items = append(items, i)
+		}
+		err := writePage(c, items, limit, offset, func() (int, error) {
+			*counted++
+			return total, nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		}
+	})
+	return r
+}
+
+func TestWritePage(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		items  int
+		total  int
+		next   string
+		prev   string
+		counts int
+	}{
+		{"first", "?limit=2", 2, 5, "/items?limit=2&offset=2", "", 1},
+		{"middle", "?limit=2&offset=2", 2, 5, "/items?limit=2&offset=4", "/items?limit=2&offset=0", 1},
+		// A short page tells the total itself
+		{"last", "?limit=2&offset=4", 1, 5, "", "/items?limit=2&offset=2", 0},
+		{"past the end", "?limit=2&offset=10", 0, 5, "", "/items?limit=2&offset=3", 1},
+		{"everything", "", 5, 5, "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counted := 0
+			w := httptest.NewRecorder()
+			pageRouter(5, &counted).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("got status %d", w.Code)
+			}
+
+			var resp struct {
+				Data  []int `json:"data"`
+				Page  Page  `json:"page"`
+				Links Links `json:"links"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Data == nil || len(resp.Data) != tt.items {
+				t.Errorf("got data %v, want %d items", resp.Data, tt.items)
+			}
+			if resp.Page.Total != tt.total {
+				t.Errorf("got total %d, want %d", resp.Page.Total, tt.total)
+			}
+			if resp.Links.Next != tt.next || resp.Links.Prev != tt.prev {
+				t.Errorf("got next %q prev %q, want %q and %q", resp.Links.Next, resp.Links.Prev, tt.next, tt.prev)
+			}
+			if counted != tt.counts {
+				t.Errorf("count called %d times, want %d", counted, tt.counts)
+			}
+		})
+	}
+}
+
+func TestWritePageKeepsFilters(t *testing.T) {
+	counted := 0
+	w := httptest.NewRecorder()
+	pageRouter(5, &counted).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?user_id=u1&limit=2", nil))
+
+	var resp ListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/items?limit=2&offset=2&user_id=u1"; resp.Links.Next != want {
+		t.Errorf("got next %q, want %q", resp.Links.Next, want)
+	}
+}
+
+func TestWritePageWithoutEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/items", func(c *gin.Context) {
+		_ = writePage(c, []int{1, 2}, 2, 0, func() (int, error) { return 0, errors.New("must not be counted") })
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items", nil))
+	if got := w.Body.String(); got != "[1,2]" {
+		t.Errorf("got %s, want a bare array", got)
+	}
+}
+
+func TestWritePageCountError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	failed := errors.New("count failed")
+	r.GET("/items", Envelope(), func(c *gin.Context) {
+		err := writePage(c, []int{1, 2}, 2, 0, func() (int, error) { return 0, failed })
+		c.String(http.StatusInternalServerError, strconv.FormatBool(errors.Is(err, failed)))
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items", nil))
+	if w.Body.String() != "true" {
+		t.Errorf("count error was not returned: %s", w.Body.String())
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -8,7 +9,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
 	"github.com/tmozzze/SubChecker/internal/service"
+	"github.com/tmozzze/SubChecker/internal/utils"
 )
 
 type SubHandler struct {
@@ -20,31 +23,72 @@ func NewSubHandler(s service.SubService, l *logrus.Logger) *SubHandler {
 	return &SubHandler{svc: s, log: l}
 }
 
+// createSubReq refers to a service by service_id or by service_name,
+// a new name is added to the catalog. Without price the default price
+// of the service is used.
 type createSubReq struct {
-	ServiceName string `json:"service_name" binding:"required"`
-	Price       int    `json:"price" binding:"required,min=0"`
+	ServiceId   int    `json:"service_id,omitempty" binding:"min=0"`
+	ServiceName string `json:"service_name,omitempty"`
+	Price       *int   `json:"price,omitempty" binding:"omitempty,min=0"`
 	UserId      string `json:"user_id" binding:"required,uuid"`
 	StartDate   string `json:"start_date" binding:"required"` // MM-YYYY
 	EndDate     string `json:"end_date,omitempty"`            // Optional
 }
 
-func parseMonth(s string) (time.Time, error) {
-	t, err := time.Parse("01-2006", s)
-	if err == nil {
-		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+// setPrice sets the price of sub, prefilling it from the catalog if it is
+// not given. It writes the response and returns false on failure.
+func (h *SubHandler) setPrice(c *gin.Context, sub *model.Sub, price *int) bool {
+	if price != nil {
+		sub.Price = *price
+		return true
 	}
-	return time.Time{}, err
+
+	err := h.svc.Prefill(c.Request.Context(), sub)
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not allowed for this user_id"})
+		return false
+	}
+	if errors.Is(err, service.ErrNoDefaultPrice) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price is required, the service has no default price"})
+		return false
+	}
+	if msg, ok := serviceRefError(err); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return false
+	}
+	if err != nil {
+		h.log.WithError(err).Error("prefill failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return false
+	}
+	return true
+}
+
+// serviceRefError returns the message for a bad service reference of a sub
+func serviceRefError(err error) (string, bool) {
+	switch {
+	case errors.Is(err, service.ErrUnknownService):
+		return "unknown service_id", true
+	case errors.Is(err, service.ErrEmptyServiceName):
+		return "service_name is empty", true
+	}
+	return "", false
 }
 
 // CreateSub godoc
 // @Summary Create subscription
-// @Description Create subscription record
+// @Description Create subscription record. With SUBS_OVERLAP_CHECK=warn or reject a subscription overlapping the user's subscriptions to the same service gets a Warning header or 409.
 // @Tags subs
 // @Accept json
 // @Produce json
 // @Param body body createSubReq true "Subscription"
 // @Success 201 {object} model.Sub
 // @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /subs [post]
 func (h *SubHandler) CreateSub(c *gin.Context) {
 	var req createSubReq
@@ -53,8 +97,12 @@ func (h *SubHandler) CreateSub(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.ServiceId == 0 && req.ServiceName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "service_id or service_name is required"})
+		return
+	}
 
-	sd, err := parseMonth(req.StartDate)
+	sd, err := utils.ParseMonth(req.StartDate)
 	if err != nil {
 		h.log.WithError(err).Warn("invalid parse month for start_date")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad start_date format, expected MM-YYYY"})
@@ -63,7 +111,7 @@ func (h *SubHandler) CreateSub(c *gin.Context) {
 
 	var ed *time.Time
 	if req.EndDate != "" {
-		t, err := parseMonth(req.EndDate)
+		t, err := utils.ParseMonth(req.EndDate)
 		if err != nil {
 			h.log.WithError(err).Warn("invalid create request for end_date")
 			c.JSON(http.StatusBadRequest, gin.H{"error": "bad end_date format, expected MM-YYYY"})
@@ -73,38 +121,60 @@ func (h *SubHandler) CreateSub(c *gin.Context) {
 	}
 
 	sub := &model.Sub{
+		ServiceId:   req.ServiceId,
 		ServiceName: req.ServiceName,
-		Price:       req.Price,
 		UserId:      req.UserId,
 		StartDate:   sd,
 		EndDate:     ed,
 	}
-	if err := h.svc.Create(c.Request.Context(), sub); err != nil {
+	if !h.setPrice(c, sub, req.Price) {
+		return
+	}
+	overlapping, err := h.svc.Create(c.Request.Context(), sub)
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not allowed for this user_id"})
+		return
+	}
+	if overlapConflict(c, err) {
+		return
+	}
+	if msg, ok := serviceRefError(err); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if err != nil {
 		h.log.WithError(err).Error("failed create sub")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
 		return
 	}
+	warnOverlap(c, overlapping)
 	c.JSON(http.StatusCreated, sub)
 }
 
 type sumReq struct {
 	UserId      string `form:"user_id"`
+	ServiceId   int    `form:"service_id" binding:"min=0"`
 	ServiceName string `form:"service_name"`
 	StartMonth  string `form:"start_month" binding:"required"` // MM-YYYY
 	EndMonth    string `form:"end_month" binding:"required"`   // MM-YYYY
 }
 
 // @Summary Sum cost
-// @Description Sum total cost for period (inclusive months). Filters: user_id, service_name
+// @Description Sum total cost for period (inclusive months). Filters: user_id, service_id, service_name
 // @Tags subs
 // @Accept json
 // @Produce json
 // @Param start_month query string true "MM-YYYY"
 // @Param end_month query string true "MM-YYYY"
 // @Param user_id query string false "UUID"
-// @Param service_name query string false "service name"
+// @Param service_id query int false "service ID"
+// @Param service_name query string false "service name, case-insensitive"
 // @Success 200 {object} http.ErrorResponse
 // @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /subs/sum [get]
 func (h *SubHandler) SumCost(c *gin.Context) {
 	var q sumReq
@@ -113,20 +183,24 @@ func (h *SubHandler) SumCost(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	pStart, err := parseMonth(q.StartMonth)
+	pStart, err := utils.ParseMonth(q.StartMonth)
 	if err != nil {
 		h.log.WithError(err).Warn("invalid parse month for start_month")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad start_month"})
 		return
 	}
-	pEnd, err := parseMonth(q.EndMonth)
+	pEnd, err := utils.ParseMonth(q.EndMonth)
 	if err != nil {
 		h.log.WithError(err).Warn("invalid parse month for end_month")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad end_month"})
 		return
 	}
 
-	total, err := h.svc.SumCost(c.Request.Context(), q.UserId, q.ServiceName, pStart, pEnd)
+	total, err := h.svc.SumCost(c.Request.Context(), model.SubFilter{UserId: q.UserId, ServiceId: q.ServiceId, ServiceName: q.ServiceName}, pStart, pEnd)
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
 	if err != nil {
 		h.log.WithError(err).Error("sum cost failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
@@ -135,6 +209,55 @@ func (h *SubHandler) SumCost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"total_rub": total})
 }
 
+// SumByCategory godoc
+// @Summary Sum cost by category
+// @Description Sum total cost for period (inclusive months) grouped by service category, largest first. Services without a category are reported as "uncategorized". Under /api/v2 the list is wrapped in http.ListResponse.
+// @Tags subs
+// @Produce json
+// @Param start_month query string true "MM-YYYY"
+// @Param end_month query string true "MM-YYYY"
+// @Param user_id query string false "UUID"
+// @Param service_id query int false "service ID"
+// @Param service_name query string false "service name, case-insensitive"
+// @Success 200 {array} model.CategoryCost
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /subs/sum/categories [get]
+func (h *SubHandler) SumByCategory(c *gin.Context) {
+	var q sumReq
+	if err := c.ShouldBindQuery(&q); err != nil {
+		h.log.WithError(err).Warn("invalid sum by category request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pStart, err := utils.ParseMonth(q.StartMonth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad start_month"})
+		return
+	}
+	pEnd, err := utils.ParseMonth(q.EndMonth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad end_month"})
+		return
+	}
+
+	filter := model.SubFilter{UserId: q.UserId, ServiceId: q.ServiceId, ServiceName: q.ServiceName}
+	totals, err := h.svc.SumByCategory(c.Request.Context(), filter, pStart, pEnd)
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("sum by category failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	writeAll(c, totals)
+}
+
 // GetSubByID godoc
 // @Summary Get subscription by ID
 // @Description Get subscription by its ID
@@ -144,6 +267,10 @@ func (h *SubHandler) SumCost(c *gin.Context) {
 // @Success 200 {object} model.Sub
 // @Failure 400 {object} http.ErrorResponse
 // @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /subs/{id} [get]
 func (h *SubHandler) GetSubById(c *gin.Context) {
 	idStr := c.Param("sub_id")
@@ -155,11 +282,16 @@ func (h *SubHandler) GetSubById(c *gin.Context) {
 	}
 
 	sub, err := h.svc.GetById(c.Request.Context(), id)
-	if err != nil {
+	if errors.Is(err, repository.ErrNotFound) {
 		h.log.WithError(err).Warn("get by id failed")
 		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
 		return
 	}
+	if err != nil {
+		h.log.WithError(err).Error("get by id failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
 
 	c.JSON(http.StatusOK, sub)
 
@@ -167,39 +299,96 @@ func (h *SubHandler) GetSubById(c *gin.Context) {
 
 // ListSubs godoc
 // @Summary List subscriptions
-// @Description Get paginated list of subscriptions
+// @Description Get paginated list of subscriptions. Under /api/v2 the list is wrapped in http.ListResponse.
 // @Tags subs
 // @Produce json
-// @Param limit query int false "limit (default 50)"
+// @Param limit query int false "limit (default 50, at most 500)"
 // @Param offset query int false "offset (default 0)"
+// @Param user_id query string false "UUID"
+// @Param service_id query int false "service ID"
+// @Param service_name query string false "service name, case-insensitive"
 // @Success 200 {array} model.Sub
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /subs [get]
 func (h *SubHandler) ListSubs(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "50")
-	offsetStr := c.DefaultQuery("offset", "0")
+	limit, offset := parsePage(c)
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 50
+	filter := model.SubFilter{
+		UserId:      c.Query("user_id"),
+		ServiceName: c.Query("service_name"),
 	}
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	if v := c.Query("service_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid service_id"})
+			return
+		}
+		filter.ServiceId = id
 	}
 
-	subs, err := h.svc.List(c.Request.Context(), limit, offset)
+	subs, err := h.svc.List(c.Request.Context(), filter, limit, offset)
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
 	if err != nil {
 		h.log.WithError(err).Error("list subs failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
 		return
 	}
 
-	c.JSON(http.StatusOK, subs)
+	err = writePage(c, subs, limit, offset, func() (int, error) {
+		return h.svc.Count(c.Request.Context(), filter)
+	})
+	if err != nil {
+		h.log.WithError(err).Error("count subs failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+	}
+}
+
+// GetSubHistory godoc
+// @Summary Get subscription history
+// @Description Get audit log of all changes of a subscription, oldest first. Under /api/v2 the list is wrapped in http.ListResponse.
+// @Tags subs
+// @Produce json
+// @Param id path int true "Subscription ID"
+// @Success 200 {array} model.SubAudit
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /subs/{id}/history [get]
+func (h *SubHandler) GetSubHistory(c *gin.Context) {
+	idStr := c.Param("sub_id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		h.log.WithError(err).Warn("invalid sub_id")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sub_id"})
+		return
+	}
+
+	history, err := h.svc.History(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("get history failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+
+	writeAll(c, history)
 }
 
 // UpdateSub godoc
 // @Summary Update subscription
-// @Description Update existing subscription by ID
+// @Description Update existing subscription by ID. Overlaps are checked like on create.
 // @Tags subs
 // @Accept json
 // @Produce json
@@ -208,6 +397,11 @@ func (h *SubHandler) ListSubs(c *gin.Context) {
 // @Success 200 {object} model.Sub
 // @Failure 400 {object} http.ErrorResponse
 // @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /subs/{id} [put]
 func (h *SubHandler) UpdateSub(c *gin.Context) {
 	idStr := c.Param("sub_id")
@@ -223,8 +417,12 @@ func (h *SubHandler) UpdateSub(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.ServiceId == 0 && req.ServiceName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "service_id or service_name is required"})
+		return
+	}
 
-	sd, err := parseMonth(req.StartDate)
+	sd, err := utils.ParseMonth(req.StartDate)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad start_date format, expected MM-YYYY"})
 		return
@@ -232,7 +430,7 @@ func (h *SubHandler) UpdateSub(c *gin.Context) {
 
 	var ed *time.Time
 	if req.EndDate != "" {
-		t, err := parseMonth(req.EndDate)
+		t, err := utils.ParseMonth(req.EndDate)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "bad end_date format, expected MM-YYYY"})
 			return
@@ -242,19 +440,39 @@ func (h *SubHandler) UpdateSub(c *gin.Context) {
 
 	sub := &model.Sub{
 		SubId:       id,
+		ServiceId:   req.ServiceId,
 		ServiceName: req.ServiceName,
-		Price:       req.Price,
 		UserId:      req.UserId,
 		StartDate:   sd,
 		EndDate:     ed,
 	}
+	if !h.setPrice(c, sub, req.Price) {
+		return
+	}
 
-	if err := h.svc.Update(c.Request.Context(), sub); err != nil {
+	overlapping, err := h.svc.Update(c.Request.Context(), sub)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "not allowed for this user_id"})
+		return
+	}
+	if overlapConflict(c, err) {
+		return
+	}
+	if msg, ok := serviceRefError(err); ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if err != nil {
 		h.log.WithError(err).Error("update failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
 		return
 	}
 
+	warnOverlap(c, overlapping)
 	c.JSON(http.StatusOK, sub)
 }
 
@@ -266,6 +484,11 @@ func (h *SubHandler) UpdateSub(c *gin.Context) {
 // @Param id path int true "Subscription ID"
 // @Success 204 {object} nil
 // @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /subs/{id} [delete]
 func (h *SubHandler) DeleteSub(c *gin.Context) {
 	idStr := c.Param("sub_id")
@@ -275,7 +498,16 @@ func (h *SubHandler) DeleteSub(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+	err = h.svc.Delete(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		return
+	}
+	if errors.Is(err, service.ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	if err != nil {
 		h.log.WithError(err).Error("delete failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
 		return
@@ -283,3 +515,11 @@ func (h *SubHandler) DeleteSub(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// parsePage reads limit and offset query params, see utils.Page
+func parsePage(c *gin.Context) (limit, offset int) {
+	// A malformed value is the same as a missing one
+	limit, _ = strconv.Atoi(c.Query("limit"))
+	offset, _ = strconv.Atoi(c.Query("offset"))
+	return utils.Page(limit, offset)
+}
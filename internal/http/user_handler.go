@@ -0,0 +1,222 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/service"
+)
+
+type UserHandler struct {
+	svc service.UserService
+	log *logrus.Logger
+}
+
+func NewUserHandler(s service.UserService, l *logrus.Logger) *UserHandler {
+	return &UserHandler{svc: s, log: l}
+}
+
+type createUserReq struct {
+	UserId string `json:"id" binding:"required,uuid"`
+	Name   string `json:"name"`
+}
+
+type updateUserReq struct {
+	Name string `json:"name"`
+}
+
+// userId returns the user_id path param if it is a UUID
+func userId(c *gin.Context) (string, bool) {
+	id := c.Param("user_id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return "", false
+	}
+	return id, true
+}
+
+// CreateUser godoc
+// @Summary Create user
+// @Description Register a user. Users are also registered by their first subscription.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param body body createUserReq true "User"
+// @Success 201 {object} model.User
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users [post]
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req createUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.WithError(err).Warn("invalid create user request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u := &model.User{UserId: req.UserId, Name: req.Name}
+	err := h.svc.Create(c.Request.Context(), u)
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "user already exists"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("create user failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusCreated, u)
+}
+
+// GetUserById godoc
+// @Summary Get user by ID
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} model.User
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users/{id} [get]
+func (h *UserHandler) GetUserById(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+
+	u, err := h.svc.GetById(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("get user failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description Get paginated list of users ordered by ID. Under /api/v2 the list is wrapped in http.ListResponse.
+// @Tags users
+// @Produce json
+// @Param limit query int false "limit (default 50, at most 500)"
+// @Param offset query int false "offset (default 0)"
+// @Success 200 {array} model.User
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users [get]
+func (h *UserHandler) ListUsers(c *gin.Context) {
+	limit, offset := parsePage(c)
+
+	users, err := h.svc.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.log.WithError(err).Error("list users failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	err = writePage(c, users, limit, offset, func() (int, error) {
+		return h.svc.Count(c.Request.Context())
+	})
+	if err != nil {
+		h.log.WithError(err).Error("count users failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+	}
+}
+
+// UpdateUser godoc
+// @Summary Update user
+// @Description Change name of a user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Param body body updateUserReq true "User"
+// @Success 200 {object} model.User
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users/{id} [put]
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+
+	var req updateUserReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.WithError(err).Warn("invalid update user request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u := &model.User{UserId: id, Name: req.Name}
+	err := h.svc.Update(c.Request.Context(), u)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("update user failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// DeleteUser godoc
+// @Summary Delete user
+// @Description Delete a user without subscriptions
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Success 204 {object} nil
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 409 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, ok := userId(c)
+	if !ok {
+		return
+	}
+
+	err := h.svc.Delete(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	if errors.Is(err, repository.ErrConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "user has subscriptions"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("delete user failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
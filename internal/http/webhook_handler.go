@@ -0,0 +1,211 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/service"
+)
+
+type WebhookHandler struct {
+	svc service.WebhookService
+	log *logrus.Logger
+}
+
+func NewWebhookHandler(s service.WebhookService, l *logrus.Logger) *WebhookHandler {
+	return &WebhookHandler{svc: s, log: l}
+}
+
+// createWebhookReq subscribes to every event if events is empty
+type createWebhookReq struct {
+	Url    string   `json:"url" binding:"required" example:"https://billing.example.com/hooks/subchecker"`
+	Events []string `json:"events,omitempty" example:"sub.created,sub.deleted"`
+}
+
+func webhookId(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook_id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// CreateWebhook godoc
+// @Summary Register webhook endpoint
+// @Description Subscribe an endpoint to subscription events: sub.created, sub.updated, sub.ended, sub.deleted (every event if empty). Events are POSTed as JSON with an X-SubChecker-Signature header, "sha256=" and HMAC-SHA256 of "<X-SubChecker-Timestamp>.<body>" keyed with the secret. The secret is only returned here.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param body body createWebhookReq true "Endpoint"
+// @Success 201 {object} model.WebhookEndpoint
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req createWebhookReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.log.WithError(err).Warn("invalid create webhook request")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	e := &model.WebhookEndpoint{Url: req.Url, Events: req.Events}
+	err := h.svc.Create(c.Request.Context(), e)
+	if errors.Is(err, service.ErrInvalidWebhookUrl) || errors.Is(err, service.ErrUnknownEvent) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("create webhook failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusCreated, e)
+}
+
+// GetWebhookById godoc
+// @Summary Get webhook endpoint by ID
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} model.WebhookEndpoint
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhookById(c *gin.Context) {
+	id, ok := webhookId(c)
+	if !ok {
+		return
+	}
+
+	e, err := h.svc.GetById(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("get webhook failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+// ListWebhooks godoc
+// @Summary List webhook endpoints
+// @Description Get paginated list of webhook endpoints ordered by ID. Under /api/v2 the list is wrapped in http.ListResponse.
+// @Tags webhooks
+// @Produce json
+// @Param limit query int false "limit (default 50, at most 500)"
+// @Param offset query int false "offset (default 0)"
+// @Success 200 {array} model.WebhookEndpoint
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	limit, offset := parsePage(c)
+
+	endpoints, err := h.svc.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.log.WithError(err).Error("list webhooks failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	err = writePage(c, endpoints, limit, offset, func() (int, error) {
+		return h.svc.Count(c.Request.Context())
+	})
+	if err != nil {
+		h.log.WithError(err).Error("count webhooks failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+	}
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook endpoint
+// @Description Delete an endpoint together with its delivery log
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := webhookId(c)
+	if !ok {
+		return
+	}
+
+	err := h.svc.Delete(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("delete webhook failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries godoc
+// @Summary Webhook delivery log
+// @Description Get paginated deliveries of events to the endpoint, newest first. Under /api/v2 the list is wrapped in http.ListResponse.
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "limit (default 50, at most 500)"
+// @Param offset query int false "offset (default 0)"
+// @Success 200 {array} model.WebhookDelivery
+// @Failure 400 {object} http.ErrorResponse
+// @Failure 404 {object} http.ErrorResponse
+// @Failure 401 {object} http.ErrorResponse
+// @Failure 403 {object} http.ErrorResponse
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListWebhookDeliveries(c *gin.Context) {
+	id, ok := webhookId(c)
+	if !ok {
+		return
+	}
+	limit, offset := parsePage(c)
+
+	deliveries, err := h.svc.Deliveries(c.Request.Context(), id, limit, offset)
+	if errors.Is(err, repository.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("list webhook deliveries failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		return
+	}
+	err = writePage(c, deliveries, limit, offset, func() (int, error) {
+		return h.svc.CountDeliveries(c.Request.Context(), id)
+	})
+	if err != nil {
+		h.log.WithError(err).Error("count webhook deliveries failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+	}
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+	"github.com/tmozzze/SubChecker/internal/utils"
+)
+
+// countTimeout limits the queries of a scrape
+const countTimeout = 5 * time.Second
+
+// subsCollector counts subscriptions at every scrape, one organization at
+// a time since repositories only see one
+type subsCollector struct {
+	subs repository.SubRepository
+	orgs *repository.OrgSelector
+	log  *logrus.Logger
+
+	active *prometheus.Desc
+}
+
+// RegisterSubs exports business gauges of every organization orgs finds
+func (m *Metrics) RegisterSubs(subs repository.SubRepository, orgs *repository.OrgSelector, log *logrus.Logger) {
+	m.registry.MustRegister(&subsCollector{
+		subs: subs,
+		orgs: orgs,
+		log:  log,
+		active: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "active_subscriptions"),
+			"Subscriptions paid for in the current month.", []string{"org_id"}, nil),
+	})
+}
+
+func (c *subsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.active
+}
+
+// Collect skips an organization it fails to count, so that the rest of
+// the metrics are still scraped
+func (c *subsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), countTimeout)
+	defer cancel()
+
+	orgs, err := c.orgs.List(ctx)
+	if err != nil {
+		c.log.WithError(err).Error("Failed to list organizations for metrics")
+		return
+	}
+
+	current := utils.TruncateToMonth(time.Now().UTC())
+	for _, org := range orgs {
+		ctx := reqctx.WithActor(reqctx.WithOrgId(ctx, org), "metrics")
+		n, err := c.subs.CountActive(ctx, current)
+		if err != nil {
+			c.log.WithError(err).WithField("org_id", org).Error("Failed to count active subscriptions for metrics")
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(n), org)
+	}
+}
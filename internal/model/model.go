@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 /*
 {
@@ -13,9 +16,245 @@ import "time"
 
 type Sub struct {
 	SubId       int        `json:"id" example:"1"`
+	OrgId       string     `json:"org_id" example:"default"`
+	ServiceId   int        `json:"service_id" example:"1"`
 	ServiceName string     `json:"service_name" example:"Yandex Plus"`
 	Price       int        `json:"price" example:"400"`
 	UserId      string     `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
 	StartDate   time.Time  `json:"start_date" example:"2025-07-01T00:00:00Z"`
 	EndDate     *time.Time `json:"end_date,omitempty" example:"2025-10-01T00:00:00Z"`
 }
+
+// SubFilter narrows subscription lists, empty fields match everything
+type SubFilter struct {
+	UserId string
+	// UserIds restricts the list to these users unless nil. Empty but not nil matches nothing.
+	UserIds     []string
+	ServiceId   int
+	ServiceName string
+}
+
+type SubAudit struct {
+	AuditId   int64           `json:"id" example:"1"`
+	SubId     int             `json:"sub_id" example:"1"`
+	OrgId     string          `json:"org_id" example:"default"`
+	Action    string          `json:"action" example:"update"`
+	Before    json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After     json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	Actor     string          `json:"actor" example:"anonymous"`
+	RequestId string          `json:"request_id" example:"4f1c2a9e8b7d6c5f4e3d2c1b0a998877"`
+	ChangedAt time.Time       `json:"changed_at" example:"2025-07-01T12:00:00Z"`
+}
+
+type ApiKey struct {
+	KeyId     int        `json:"id" example:"1"`
+	Name      string     `json:"name" example:"billing-export"`
+	OrgId     string     `json:"org_id" example:"default"`
+	Prefix    string     `json:"prefix" example:"sck_3f9a"`
+	Scopes    []string   `json:"scopes" example:"read,write"`
+	CreatedAt time.Time  `json:"created_at" example:"2025-07-01T12:00:00Z"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" example:"2025-08-01T12:00:00Z"`
+}
+
+// User is a subscriber. Ids come from outside (e.g. the identity provider).
+type User struct {
+	UserId    string    `json:"id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	OrgId     string    `json:"org_id" example:"default"`
+	Name      string    `json:"name" example:"Ivan Petrov"`
+	CreatedAt time.Time `json:"created_at" example:"2025-07-01T12:00:00Z"`
+}
+
+// Billing periods of a service's default price
+const (
+	BillingMonth = "month"
+	BillingYear  = "year"
+)
+
+// Service is a catalog entry subscriptions refer to
+type Service struct {
+	ServiceId int    `json:"id" example:"1"`
+	OrgId     string `json:"org_id" example:"default"`
+	Name      string `json:"name" example:"Yandex Plus"`
+	Category  string `json:"category" example:"video"`
+	// DefaultPrice is per BillingPeriod, nil if the service has no usual price
+	DefaultPrice  *int      `json:"default_price,omitempty" example:"400"`
+	BillingPeriod string    `json:"billing_period" example:"month"`
+	Website       string    `json:"website,omitempty" example:"https://plus.yandex.ru"`
+	LogoUrl       string    `json:"logo_url,omitempty" example:"https://plus.yandex.ru/logo.png"`
+	CreatedAt     time.Time `json:"created_at" example:"2025-07-01T12:00:00Z"`
+}
+
+// CategoryCost is spend on services of one category
+type CategoryCost struct {
+	Category string `json:"category" example:"video"`
+	TotalRub int64  `json:"total_rub" example:"1200"`
+}
+
+// Budget is a monthly spending limit of one user or of one team
+type Budget struct {
+	BudgetId  int       `json:"id" example:"1"`
+	OrgId     string    `json:"org_id" example:"default"`
+	UserId    string    `json:"user_id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Team      string    `json:"team,omitempty" example:"platform"`
+	AmountRub int       `json:"amount_rub" example:"1500"`
+	CreatedAt time.Time `json:"created_at" example:"2025-07-01T12:00:00Z"`
+}
+
+// MonthCost is spend in one calendar month
+type MonthCost struct {
+	Month    time.Time `json:"month" example:"2025-07-01T00:00:00Z"`
+	TotalRub int64     `json:"total_rub" example:"1200"`
+}
+
+type BudgetMonth struct {
+	Month      time.Time `json:"month" example:"2025-07-01T00:00:00Z"`
+	SpentRub   int64     `json:"spent_rub" example:"1800"`
+	BudgetRub  int64     `json:"budget_rub" example:"1500"`
+	OverBudget bool      `json:"over_budget" example:"true"`
+	OverByRub  int64     `json:"over_by_rub" example:"300"`
+}
+
+// BudgetReport compares spend of every month of a period with the budget
+type BudgetReport struct {
+	Budget     Budget        `json:"budget"`
+	Months     []BudgetMonth `json:"months"`
+	OverMonths int           `json:"over_months" example:"1"`
+}
+
+// PriceChange is a price a subscription is scheduled to have from Month on
+type PriceChange struct {
+	ChangeId  int       `json:"id" example:"1"`
+	SubId     int       `json:"sub_id" example:"1"`
+	OrgId     string    `json:"org_id" example:"default"`
+	Month     time.Time `json:"month" example:"2026-01-01T00:00:00Z"`
+	Price     int       `json:"price" example:"500"`
+	CreatedAt time.Time `json:"created_at" example:"2025-07-01T12:00:00Z"`
+}
+
+// ForecastMonth is spend in one month: actual up to the current month, projected after it
+type ForecastMonth struct {
+	Month        time.Time `json:"month" example:"2026-01-01T00:00:00Z"`
+	ActualRub    int64     `json:"actual_rub" example:"0"`
+	ProjectedRub int64     `json:"projected_rub" example:"1200"`
+}
+
+// Forecast is spend of a period split into actual and projected amounts
+type Forecast struct {
+	ActualRub    int64           `json:"actual_rub" example:"2400"`
+	ProjectedRub int64           `json:"projected_rub" example:"3600"`
+	Months       []ForecastMonth `json:"months"`
+}
+
+// Overlap is two subscriptions of one user to one service paid in the same months
+type Overlap struct {
+	UserId      string `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceId   int    `json:"service_id" example:"1"`
+	ServiceName string `json:"service_name" example:"Yandex Plus"`
+	SubIds      []int  `json:"sub_ids" example:"1,3"`
+	Months      int    `json:"months" example:"4"`
+	// WastedRub is the cheaper of the two prices paid for every common month
+	WastedRub int64 `json:"wasted_rub" example:"1600"`
+}
+
+type OverlapReport struct {
+	Overlaps  []Overlap `json:"overlaps"`
+	WastedRub int64     `json:"wasted_rub" example:"1600"`
+}
+
+const (
+	ReminderRenewal = "renewal"
+	ReminderEnd     = "end"
+)
+
+// Reminder tells that a subscription renews or ends on DueDate
+type Reminder struct {
+	OrgId       string    `json:"org_id" example:"default"`
+	SubId       int       `json:"sub_id" example:"1"`
+	UserId      string    `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceName string    `json:"service_name" example:"Yandex Plus"`
+	Price       int       `json:"price" example:"400"`
+	Kind        string    `json:"kind" example:"renewal"`
+	DueDate     time.Time `json:"due_date" example:"2025-08-01T00:00:00Z"`
+}
+
+// Subscription events sent to webhooks
+const (
+	EventSubCreated = "sub.created"
+	EventSubUpdated = "sub.updated"
+	// EventSubEnded is an update that sets end_date of an open subscription
+	EventSubEnded   = "sub.ended"
+	EventSubDeleted = "sub.deleted"
+)
+
+// WebhookEndpoint receives subscription events of its organization
+type WebhookEndpoint struct {
+	EndpointId int    `json:"id" example:"1"`
+	OrgId      string `json:"org_id" example:"default"`
+	Url        string `json:"url" example:"https://billing.example.com/hooks/subchecker"`
+	// Events the endpoint is subscribed to, empty for every event
+	Events []string `json:"events" example:"sub.created,sub.deleted"`
+	// Secret signs payloads, it is only shown when the endpoint is created
+	Secret    string    `json:"secret,omitempty" example:"whsec_3f9a1c0d"`
+	CreatedAt time.Time `json:"created_at" example:"2025-07-01T12:00:00Z"`
+}
+
+// Wants reports whether the endpoint is subscribed to events of type t
+func (e WebhookEndpoint) Wants(t string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, event := range e.Events {
+		if event == t {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is a subscription change written to the outbox in the
+// same transaction as the change itself
+type WebhookEvent struct {
+	EventId   int64           `json:"id" example:"1"`
+	OrgId     string          `json:"org_id" example:"default"`
+	Type      string          `json:"type" example:"sub.created"`
+	SubId     int             `json:"sub_id" example:"1"`
+	Data      json.RawMessage `json:"data" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at" example:"2025-07-01T12:00:00Z"`
+}
+
+// Statuses of a webhook delivery
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryFailed means every attempt failed, the delivery is not retried
+	DeliveryFailed = "failed"
+)
+
+// WebhookDelivery is sending of one event to one endpoint
+type WebhookDelivery struct {
+	DeliveryId int64  `json:"id" example:"1"`
+	EventId    int64  `json:"event_id" example:"1"`
+	EndpointId int    `json:"endpoint_id" example:"1"`
+	EventType  string `json:"event_type" example:"sub.created"`
+	Status     string `json:"status" example:"pending"`
+	Attempts   int    `json:"attempts" example:"2"`
+	// ResponseStatus is the HTTP status of the last attempt, 0 if there was no response
+	ResponseStatus int        `json:"response_status,omitempty" example:"503"`
+	LastError      string     `json:"last_error,omitempty" example:"webhook responded 503 Service Unavailable"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at" example:"2025-07-01T12:01:00Z"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" example:"2025-07-01T12:00:01Z"`
+	CreatedAt      time.Time  `json:"created_at" example:"2025-07-01T12:00:00Z"`
+}
+
+// SubEvent is a committed subscription change streamed to clients
+type SubEvent struct {
+	// Id is global with PostgreSQL and per instance otherwise, clients
+	// resume after it
+	Id    int64  `json:"id" example:"42"`
+	Type  string `json:"type" example:"sub.updated"`
+	OrgId string `json:"org_id" example:"default"`
+	// Sub is the state after the change, before it for sub.deleted
+	Sub      Sub       `json:"sub"`
+	Previous *Sub      `json:"previous,omitempty"`
+	At       time.Time `json:"at" example:"2025-07-01T12:00:00Z"`
+}
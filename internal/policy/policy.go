@@ -0,0 +1,192 @@
+// Package policy decides what an authenticated caller may do with
+// subscriptions, based on the caller's roles and teams.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/tmozzze/SubChecker/internal/auth"
+)
+
+// Actions on subscriptions
+const (
+	ActionRead  = "read"
+	ActionWrite = "write"
+	ActionSum   = "sum"
+)
+
+var actions = []string{ActionRead, ActionWrite, ActionSum}
+
+const (
+	RoleViewer  = "viewer"
+	RoleEditor  = "editor"
+	RoleFinance = "finance"
+	RoleAdmin   = "admin"
+	// RoleUser is given to end users without any role
+	RoleUser = "user"
+	// RoleService is given to service accounts (API keys) without any role
+	RoleService = "service"
+)
+
+// Reach is whose subscriptions an action may target
+type Reach int
+
+const (
+	ReachNone Reach = iota
+	// ReachOwn is the caller's own subscriptions
+	ReachOwn
+	// ReachTeam is subscriptions of the caller and their teammates
+	ReachTeam
+	// ReachAll is every subscription of the organization
+	ReachAll
+)
+
+var reachNames = map[string]Reach{"none": ReachNone, "own": ReachOwn, "team": ReachTeam, "all": ReachAll}
+
+func defaultRoles() map[string]map[string]Reach {
+	return map[string]map[string]Reach{
+		RoleViewer:  {ActionRead: ReachTeam},
+		RoleEditor:  {ActionRead: ReachTeam, ActionWrite: ReachTeam},
+		RoleFinance: {ActionRead: ReachAll, ActionSum: ReachAll},
+		RoleAdmin:   {ActionRead: ReachAll, ActionWrite: ReachAll, ActionSum: ReachAll},
+		RoleUser:    {ActionRead: ReachOwn, ActionWrite: ReachOwn, ActionSum: ReachOwn},
+		RoleService: {ActionRead: ReachAll, ActionWrite: ReachAll, ActionSum: ReachAll},
+	}
+}
+
+type Policy struct {
+	roles map[string]map[string]Reach
+	// teams maps a team name to its members' user ids
+	teams map[string][]string
+	// subjects maps a principal subject to roles granted to it
+	subjects map[string][]string
+}
+
+// Default returns the built-in roles with no teams and no role assignments
+func Default() *Policy {
+	return &Policy{roles: defaultRoles(), teams: map[string][]string{}, subjects: map[string][]string{}}
+}
+
+// policyFile is the JSON layout of a policy file:
+//
+//	{
+//	  "roles":    {"auditor": {"read": "all"}},
+//	  "teams":    {"platform": ["<user_id>", "<user_id>"]},
+//	  "subjects": {"<user_id>": ["editor"], "apikey:billing-export": ["finance"]}
+//	}
+//
+// Roles listed in the file replace built-in roles of the same name.
+type policyFile struct {
+	Roles    map[string]map[string]string `json:"roles"`
+	Teams    map[string][]string          `json:"teams"`
+	Subjects map[string][]string          `json:"subjects"`
+}
+
+// Load reads a policy file on top of the built-in roles
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("policy: %w", err)
+	}
+	var f policyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("policy: parse %s: %w", path, err)
+	}
+
+	p := Default()
+	for role, rules := range f.Roles {
+		parsed := make(map[string]Reach, len(rules))
+		for action, reach := range rules {
+			if !slices.Contains(actions, action) {
+				return nil, fmt.Errorf("policy: role %q: unknown action %q", role, action)
+			}
+			r, ok := reachNames[reach]
+			if !ok {
+				return nil, fmt.Errorf("policy: role %q: unknown reach %q", role, reach)
+			}
+			parsed[action] = r
+		}
+		p.roles[role] = parsed
+	}
+	for subject, roles := range f.Subjects {
+		for _, role := range roles {
+			if _, ok := p.roles[role]; !ok {
+				return nil, fmt.Errorf("policy: subject %q: unknown role %q", subject, role)
+			}
+		}
+		p.subjects[subject] = roles
+	}
+	for team, members := range f.Teams {
+		p.teams[team] = members
+	}
+	return p, nil
+}
+
+// Roles returns roles of the caller: from its credentials, from the policy
+// file and admin for the admin scope. A caller without any gets RoleUser
+// or RoleService.
+func (p *Policy) Roles(pr *auth.Principal) []string {
+	roles := slices.Clone(pr.Roles)
+	roles = append(roles, p.subjects[pr.Subject]...)
+	if slices.Contains(pr.Scopes, auth.ScopeAdmin) {
+		roles = append(roles, RoleAdmin)
+	}
+
+	if len(roles) == 0 {
+		if pr.UserId != "" {
+			return []string{RoleUser}
+		}
+		return []string{RoleService}
+	}
+	slices.Sort(roles)
+	return slices.Compact(roles)
+}
+
+// Reach returns the widest reach of action over the caller's roles.
+// Unknown roles grant nothing.
+func (p *Policy) Reach(pr *auth.Principal, action string) Reach {
+	reach := ReachNone
+	for _, role := range p.Roles(pr) {
+		reach = max(reach, p.roles[role][action])
+	}
+	return reach
+}
+
+// Teammates returns userId and members of every team userId belongs to
+func (p *Policy) Teammates(userId string) []string {
+	result := []string{userId}
+	for _, members := range p.teams {
+		if slices.Contains(members, userId) {
+			result = append(result, members...)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// Team returns members of the team, false if there is no such team
+func (p *Policy) Team(name string) ([]string, bool) {
+	members, ok := p.teams[name]
+	return slices.Clone(members), ok
+}
+
+// Users returns whose subscriptions the caller may target with action.
+// all is true if there is no restriction.
+func (p *Policy) Users(pr *auth.Principal, action string) (users []string, all bool) {
+	switch p.Reach(pr, action) {
+	case ReachAll:
+		return nil, true
+	case ReachTeam:
+		if pr.UserId != "" {
+			return p.Teammates(pr.UserId), false
+		}
+	case ReachOwn:
+		if pr.UserId != "" {
+			return []string{pr.UserId}, false
+		}
+	}
+	return []string{}, false
+}
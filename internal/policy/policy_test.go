@@ -0,0 +1,100 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tmozzze/SubChecker/internal/auth"
+)
+
+func TestReach(t *testing.T) {
+	p := Default()
+	user := func(roles ...string) *auth.Principal {
+		return &auth.Principal{Subject: "u1", UserId: "u1", Roles: roles}
+	}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		read      Reach
+		write     Reach
+		sum       Reach
+	}{
+		{"user without roles", user(), ReachOwn, ReachOwn, ReachOwn},
+		{"api key without roles", &auth.Principal{Subject: "apikey:export"}, ReachAll, ReachAll, ReachAll},
+		{"viewer", user(RoleViewer), ReachTeam, ReachNone, ReachNone},
+		{"editor", user(RoleEditor), ReachTeam, ReachTeam, ReachNone},
+		{"finance", user(RoleFinance), ReachAll, ReachNone, ReachAll},
+		{"admin", user(RoleAdmin), ReachAll, ReachAll, ReachAll},
+		{"admin scope", &auth.Principal{Subject: "u1", UserId: "u1", Scopes: []string{auth.ScopeAdmin}}, ReachAll, ReachAll, ReachAll},
+		{"widest of roles", user(RoleViewer, RoleFinance), ReachAll, ReachNone, ReachAll},
+		{"unknown role", user("auditor"), ReachNone, ReachNone, ReachNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for action, want := range map[string]Reach{ActionRead: tt.read, ActionWrite: tt.write, ActionSum: tt.sum} {
+				if got := p.Reach(tt.principal, action); got != want {
+					t.Errorf("%s: got reach %d, want %d", action, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	file := `{
+		"roles":    {"auditor": {"read": "all"}},
+		"teams":    {"platform": ["u1", "u2"], "data": ["u1", "u3"]},
+		"subjects": {"u1": ["editor"], "u4": ["auditor"]}
+	}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal := func(id string) *auth.Principal { return &auth.Principal{Subject: id, UserId: id} }
+
+	tests := []struct {
+		name   string
+		caller *auth.Principal
+		action string
+		users  []string
+		all    bool
+	}{
+		{"team from the policy file", principal("u1"), ActionWrite, []string{"u1", "u2", "u3"}, false},
+		{"own without a role", principal("u2"), ActionWrite, []string{"u2"}, false},
+		{"role from the policy file", principal("u4"), ActionRead, nil, true},
+		{"none", principal("u4"), ActionWrite, []string{}, false},
+		{"team reach of an api key", &auth.Principal{Subject: "apikey:x", Roles: []string{RoleViewer}}, ActionRead, []string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, all := p.Users(tt.caller, tt.action)
+			if all != tt.all || !slices.Equal(users, tt.users) {
+				t.Errorf("got %v, %v, want %v, %v", users, all, tt.users, tt.all)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsUnknownNames(t *testing.T) {
+	for name, file := range map[string]string{
+		"action": `{"roles": {"r": {"delete": "all"}}}`,
+		"reach":  `{"roles": {"r": {"read": "everyone"}}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Fatal("got no error")
+			}
+		})
+	}
+}
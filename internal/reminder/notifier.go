@@ -0,0 +1,187 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+// Notifier delivers reminders through one channel
+type Notifier interface {
+	// Name identifies the channel, a reminder is sent once per channel
+	Name() string
+	Notify(ctx context.Context, r model.Reminder) error
+}
+
+// text is a human readable reminder
+func text(r model.Reminder) string {
+	day := r.DueDate.Format("02.01.2006")
+	if r.Kind == model.ReminderEnd {
+		return fmt.Sprintf("Subscription #%d to %s ends on %s", r.SubId, r.ServiceName, day)
+	}
+	return fmt.Sprintf("Subscription #%d to %s renews on %s for %d rub", r.SubId, r.ServiceName, day, r.Price)
+}
+
+type logNotifier struct {
+	log *logrus.Logger
+}
+
+// NewLogNotifier writes reminders to the log
+func NewLogNotifier(log *logrus.Logger) Notifier {
+	return &logNotifier{log: log}
+}
+
+func (n *logNotifier) Name() string { return "log" }
+
+func (n *logNotifier) Notify(ctx context.Context, r model.Reminder) error {
+	n.log.WithFields(logrus.Fields{
+		"org_id":   r.OrgId,
+		"sub_id":   r.SubId,
+		"user_id":  r.UserId,
+		"kind":     r.Kind,
+		"due_date": r.DueDate.Format(time.DateOnly),
+	}).Info(text(r))
+	return nil
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier POSTs every reminder as JSON to url and expects a 2xx response
+func NewWebhookNotifier(url string) Notifier {
+	return &webhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *webhookNotifier) Name() string { return "webhook" }
+
+func (n *webhookNotifier) Notify(ctx context.Context, r model.Reminder) error {
+	body, err := json.Marshal(struct {
+		model.Reminder
+		Text string `json:"text"`
+	}{r, text(r)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTPConfig is where and from whom reminders are mailed. Auth is used
+// only if Username is set.
+type SMTPConfig struct {
+	Addr     string
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+type smtpNotifier struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPNotifier mails every reminder to cfg.To
+func NewSMTPNotifier(cfg SMTPConfig) Notifier {
+	return &smtpNotifier{cfg: cfg}
+}
+
+func (n *smtpNotifier) Name() string { return "smtp" }
+
+func (n *smtpNotifier) Notify(ctx context.Context, r model.Reminder) error {
+	subject := text(r)
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s.\r\nUser: %s\r\n", subject, r.UserId)
+
+	err := n.send(ctx, []byte(msg.String()))
+	if err != nil && ctx.Err() != nil {
+		// Report why the connection was closed rather than the closed connection
+		return ctx.Err()
+	}
+	return err
+}
+
+// send is smtp.SendMail over a connection that is closed once ctx is done,
+// so a stuck server can't hold the scheduler
+func (n *smtpNotifier) send(ctx context.Context, msg []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", n.cfg.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	host, _, _ := strings.Cut(n.cfg.Addr, ":")
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.cfg.Username != "" {
+		auth := smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, host)
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range n.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,145 @@
+package reminder
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+	"github.com/tmozzze/SubChecker/internal/utils"
+)
+
+// Scheduler periodically finds subscriptions renewing or ending within the
+// lead time and sends reminders about them through every notifier.
+//
+// Subscriptions are paid monthly, so an active one renews on the first day
+// of the next month, and one whose end_date is this month ends that day.
+type Scheduler struct {
+	subs      repository.SubRepository
+	reminders repository.ReminderRepository
+	notifiers []Notifier
+	// orgs are checked one by one, repositories only see one organization
+	orgs     *repository.OrgSelector
+	lead     time.Duration
+	interval time.Duration
+	log      *logrus.Logger
+}
+
+func NewScheduler(subs repository.SubRepository, reminders repository.ReminderRepository, notifiers []Notifier,
+	orgs *repository.OrgSelector, lead, interval time.Duration, log *logrus.Logger) *Scheduler {
+	return &Scheduler{
+		subs:      subs,
+		reminders: reminders,
+		notifiers: notifiers,
+		orgs:      orgs,
+		lead:      lead,
+		interval:  interval,
+		log:       log,
+	}
+}
+
+// Run checks subscriptions right away and then every interval until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	s.log.WithFields(logrus.Fields{
+		"lead":     s.lead.String(),
+		"interval": s.interval.String(),
+	}).Info("Starting reminder scheduler")
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends reminders due as of now. Failures are logged, a reminder
+// that failed is sent again on the next run.
+func (s *Scheduler) RunOnce(ctx context.Context, now time.Time) {
+	orgs, err := s.orgs.List(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to list organizations for reminders")
+		return
+	}
+	for _, org := range orgs {
+		ctx := reqctx.WithActor(reqctx.WithOrgId(ctx, org), "reminder-scheduler")
+
+		subs, err := s.subs.SumCost(ctx, model.SubFilter{})
+		if err != nil {
+			s.log.WithError(err).WithField("org_id", org).Error("Failed to list subscriptions for reminders")
+			continue
+		}
+		for _, sub := range subs {
+			if r, ok := due(sub, now, s.lead); ok {
+				s.send(ctx, r)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) send(ctx context.Context, r model.Reminder) {
+	for _, n := range s.notifiers {
+		fields := logrus.Fields{
+			"org_id":   r.OrgId,
+			"sub_id":   r.SubId,
+			"kind":     r.Kind,
+			"channel":  n.Name(),
+			"due_date": r.DueDate.Format(time.DateOnly),
+		}
+
+		claimed, err := s.reminders.Claim(ctx, r, n.Name())
+		if err != nil {
+			s.log.WithError(err).WithFields(fields).Error("Failed to claim reminder")
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := n.Notify(ctx, r); err != nil {
+			s.log.WithError(err).WithFields(fields).Error("Failed to send reminder")
+			if err := s.reminders.Release(ctx, r, n.Name()); err != nil {
+				s.log.WithError(err).WithFields(fields).Error("Reminder won't be sent again")
+			}
+			continue
+		}
+		s.log.WithFields(fields).Debug("Reminder sent")
+	}
+}
+
+// due returns a reminder if sub renews or ends within lead of now
+func due(sub model.Sub, now time.Time, lead time.Duration) (model.Reminder, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	current := utils.TruncateToMonth(today)
+	next := current.AddDate(0, 1, 0)
+	if next.Sub(today) > lead || utils.TruncateToMonth(sub.StartDate).After(current) {
+		return model.Reminder{}, false
+	}
+
+	r := model.Reminder{
+		OrgId:       sub.OrgId,
+		SubId:       sub.SubId,
+		UserId:      sub.UserId,
+		ServiceName: sub.ServiceName,
+		Price:       sub.Price,
+		Kind:        model.ReminderRenewal,
+		DueDate:     next,
+	}
+	if sub.EndDate != nil {
+		end := utils.TruncateToMonth(*sub.EndDate)
+		if end.Before(current) {
+			return model.Reminder{}, false
+		}
+		if end.Equal(current) {
+			r.Kind = model.ReminderEnd
+		}
+	}
+	return r, true
+}
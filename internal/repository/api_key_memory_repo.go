@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+type memoryApiKey struct {
+	key  model.ApiKey
+	hash string
+}
+
+type memoryApiKeyRepository struct {
+	db  *MemoryDB
+	log *logrus.Logger
+}
+
+func NewMemoryApiKeyRepository(db *MemoryDB, log *logrus.Logger) ApiKeyRepository {
+	return &memoryApiKeyRepository{db: db, log: log}
+}
+
+func (r *memoryApiKeyRepository) Create(ctx context.Context, k *model.ApiKey, hash string) error {
+	r.log.WithFields(logrus.Fields{
+		"name": k.Name,
+	}).Debug("Creating api key")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	k.KeyId = r.db.nextApiKeyId
+	k.CreatedAt = time.Now().UTC()
+	r.db.nextApiKeyId++
+
+	stored := *k
+	stored.Scopes = slices.Clone(k.Scopes)
+	r.db.apiKeys[k.KeyId] = memoryApiKey{key: stored, hash: hash}
+	return nil
+}
+
+func (r *memoryApiKeyRepository) GetByHash(ctx context.Context, hash string) (*model.ApiKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, k := range r.db.apiKeys {
+		if k.hash == hash {
+			key := k.key
+			return &key, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryApiKeyRepository) Revoke(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"key_id": id,
+	}).Debug("Revoking api key")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	k, ok := r.db.apiKeys[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if k.key.RevokedAt == nil {
+		now := time.Now().UTC()
+		k.key.RevokedAt = &now
+		r.db.apiKeys[id] = k
+	}
+	return nil
+}
+
+func (r *memoryApiKeyRepository) List(ctx context.Context) ([]model.ApiKey, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var result []model.ApiKey
+	for _, k := range r.db.apiKeys {
+		result = append(result, k.key)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].KeyId < result[j].KeyId })
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+type ApiKeyRepository interface {
+	Create(ctx context.Context, k *model.ApiKey, hash string) error
+	GetByHash(ctx context.Context, hash string) (*model.ApiKey, error)
+	Revoke(ctx context.Context, id int) error
+	List(ctx context.Context) ([]model.ApiKey, error)
+}
+
+type apiKeyRepository struct {
+	pool *pgxpool.Pool
+	log  *logrus.Logger
+}
+
+func NewApiKeyRepository(pool *pgxpool.Pool, log *logrus.Logger) ApiKeyRepository {
+	return &apiKeyRepository{pool: pool, log: log}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, k *model.ApiKey, hash string) error {
+	r.log.WithFields(logrus.Fields{
+		"name": k.Name,
+	}).Debug("Creating api key")
+
+	query := `
+		INSERT INTO api_keys (name, org_id, key_prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING key_id, created_at
+	`
+	err := conn(ctx, r.pool).QueryRow(ctx, query, k.Name, k.OrgId, k.Prefix, hash, k.Scopes).Scan(&k.KeyId, &k.CreatedAt)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "INSERT INTO api_keys",
+			"name":  k.Name,
+		}).Error("Failed to create api key")
+	}
+	return err
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (*model.ApiKey, error) {
+	var k model.ApiKey
+	query := `
+		SELECT key_id, name, org_id, key_prefix, scopes, created_at, revoked_at
+		FROM api_keys WHERE key_hash = $1
+	`
+	err := conn(ctx, r.pool).QueryRow(ctx, query, hash).Scan(&k.KeyId, &k.Name, &k.OrgId, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.RevokedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM api_keys",
+		}).Error("Failed to get api key")
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"key_id": id,
+	}).Debug("Revoking api key")
+
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now()) WHERE key_id = $1`
+	tag, err := conn(ctx, r.pool).Exec(ctx, query, id)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "UPDATE api_keys",
+			"key_id": id,
+		}).Error("Failed to revoke api key")
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]model.ApiKey, error) {
+	rows, err := conn(ctx, r.pool).Query(ctx, `
+		SELECT key_id, name, org_id, key_prefix, scopes, created_at, revoked_at
+		FROM api_keys ORDER BY key_id
+	`)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM api_keys",
+		}).Error("Failed to list api keys")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.ApiKey
+	for rows.Next() {
+		var k model.ApiKey
+		if err := rows.Scan(&k.KeyId, &k.Name, &k.OrgId, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.RevokedAt); err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for api keys")
+			return nil, err
+		}
+		result = append(result, k)
+	}
+	return result, nil
+}
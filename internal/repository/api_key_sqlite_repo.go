@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+type sqliteApiKeyRepository struct {
+	db  *sql.DB
+	log *logrus.Logger
+}
+
+func NewSQLiteApiKeyRepository(db *sql.DB, log *logrus.Logger) ApiKeyRepository {
+	return &sqliteApiKeyRepository{db: db, log: log}
+}
+
+const sqliteApiKeyColumns = `key_id, name, org_id, key_prefix, scopes, created_at, revoked_at`
+
+func scanSQLiteApiKey(row sqliteRowScanner) (model.ApiKey, error) {
+	var (
+		k         model.ApiKey
+		scopes    string
+		createdAt string
+		revokedAt sql.NullString
+	)
+	if err := row.Scan(&k.KeyId, &k.Name, &k.OrgId, &k.Prefix, &scopes, &createdAt, &revokedAt); err != nil {
+		return k, err
+	}
+
+	k.Scopes = strings.Split(scopes, ",")
+	var err error
+	if k.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return k, err
+	}
+	if k.RevokedAt, err = parseNullTimestamp(revokedAt); err != nil {
+		return k, err
+	}
+	return k, nil
+}
+
+func (r *sqliteApiKeyRepository) Create(ctx context.Context, k *model.ApiKey, hash string) error {
+	r.log.WithFields(logrus.Fields{
+		"name": k.Name,
+	}).Debug("Creating api key")
+
+	k.CreatedAt = time.Now().UTC()
+	query := `
+		INSERT INTO api_keys (name, org_id, key_prefix, key_hash, scopes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	res, err := sqlConn(ctx, r.db).ExecContext(ctx, query,
+		k.Name, k.OrgId, k.Prefix, hash, strings.Join(k.Scopes, ","), formatTimestamp(k.CreatedAt))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "INSERT INTO api_keys",
+			"name":  k.Name,
+		}).Error("Failed to create api key")
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	k.KeyId = int(id)
+	return nil
+}
+
+func (r *sqliteApiKeyRepository) GetByHash(ctx context.Context, hash string) (*model.ApiKey, error) {
+	query := `SELECT ` + sqliteApiKeyColumns + ` FROM api_keys WHERE key_hash = ?`
+	k, err := scanSQLiteApiKey(sqlConn(ctx, r.db).QueryRowContext(ctx, query, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM api_keys",
+		}).Error("Failed to get api key")
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *sqliteApiKeyRepository) Revoke(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"key_id": id,
+	}).Debug("Revoking api key")
+
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE key_id = ?`
+	res, err := sqlConn(ctx, r.db).ExecContext(ctx, query, formatTimestamp(time.Now().UTC()), id)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "UPDATE api_keys",
+			"key_id": id,
+		}).Error("Failed to revoke api key")
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteApiKeyRepository) List(ctx context.Context) ([]model.ApiKey, error) {
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `SELECT `+sqliteApiKeyColumns+` FROM api_keys ORDER BY key_id`)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM api_keys",
+		}).Error("Failed to list api keys")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.ApiKey
+	for rows.Next() {
+		k, err := scanSQLiteApiKey(rows)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for api keys")
+			return nil, err
+		}
+		result = append(result, k)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type memoryBudgetRepository struct {
+	db  *MemoryDB
+	log *logrus.Logger
+}
+
+func NewMemoryBudgetRepository(db *MemoryDB, log *logrus.Logger) BudgetRepository {
+	return &memoryBudgetRepository{db: db, log: log}
+}
+
+func (r *memoryBudgetRepository) Create(ctx context.Context, b *model.Budget) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": b.UserId,
+		"team":    b.Team,
+	}).Debug("Creating budget")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	orgId := reqctx.OrgId(ctx)
+	for _, other := range r.db.budgets {
+		if other.OrgId == orgId && ((b.UserId != "" && other.UserId == b.UserId) || (b.Team != "" && other.Team == b.Team)) {
+			return ErrConflict
+		}
+	}
+	b.BudgetId = r.db.nextBudgetId
+	b.OrgId = orgId
+	b.CreatedAt = time.Now().UTC()
+	r.db.nextBudgetId++
+	r.db.budgets[b.BudgetId] = *b
+	return nil
+}
+
+func (r *memoryBudgetRepository) GetById(ctx context.Context, id int) (*model.Budget, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	b, ok := r.db.budgets[id]
+	if !ok || b.OrgId != reqctx.OrgId(ctx) {
+		return nil, ErrNotFound
+	}
+	return &b, nil
+}
+
+func (r *memoryBudgetRepository) Update(ctx context.Context, b *model.Budget) error {
+	r.log.WithFields(logrus.Fields{
+		"budget_id": b.BudgetId,
+	}).Debug("Updating budget")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.budgets[b.BudgetId]
+	if !ok || stored.OrgId != reqctx.OrgId(ctx) {
+		return ErrNotFound
+	}
+	stored.AmountRub = b.AmountRub
+	r.db.budgets[b.BudgetId] = stored
+	*b = stored
+	return nil
+}
+
+func (r *memoryBudgetRepository) Delete(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"budget_id": id,
+	}).Debug("Deleting budget")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	b, ok := r.db.budgets[id]
+	if !ok || b.OrgId != reqctx.OrgId(ctx) {
+		return ErrNotFound
+	}
+	delete(r.db.budgets, id)
+	return nil
+}
+
+func (r *memoryBudgetRepository) List(ctx context.Context, limit, offset int) ([]model.Budget, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var result []model.Budget
+	for _, b := range r.db.budgets {
+		if b.OrgId == orgId {
+			result = append(result, b)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].BudgetId < result[j].BudgetId })
+	return page(result, limit, offset), nil
+}
+
+func (r *memoryBudgetRepository) Count(ctx context.Context) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	n := 0
+	for _, v := range r.db.budgets {
+		if v.OrgId == orgId {
+			n++
+		}
+	}
+	return n, nil
+}
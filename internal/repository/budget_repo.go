@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+// BudgetRepository only sees budgets of the organization from reqctx.OrgId.
+// A user or a team has at most one budget.
+type BudgetRepository interface {
+	// Create returns ErrConflict if the user or the team already has a budget
+	Create(ctx context.Context, b *model.Budget) error
+	GetById(ctx context.Context, id int) (*model.Budget, error)
+	// Update changes the amount of the budget
+	Update(ctx context.Context, b *model.Budget) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, limit, offset int) ([]model.Budget, error)
+	Count(ctx context.Context) (int, error)
+}
+
+type budgetRepository struct {
+	pool *pgxpool.Pool
+	txm  TxManager
+	log  *logrus.Logger
+}
+
+func NewBudgetRepository(pool *pgxpool.Pool, log *logrus.Logger) BudgetRepository {
+	return &budgetRepository{pool: pool, txm: NewTxManager(pool, log), log: log}
+}
+
+const budgetColumns = `budget_id, org_id, COALESCE(user_id::text, ''), COALESCE(team, ''), amount, created_at`
+
+func scanBudget(row pgx.Row, b *model.Budget) error {
+	return row.Scan(&b.BudgetId, &b.OrgId, &b.UserId, &b.Team, &b.AmountRub, &b.CreatedAt)
+}
+
+// nullIfEmpty stores an empty string as NULL
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func (r *budgetRepository) Create(ctx context.Context, b *model.Budget) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": b.UserId,
+		"team":    b.Team,
+	}).Debug("Creating budget")
+
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO budgets (org_id, user_id, team, amount)
+			VALUES ($1, $2, $3, $4)
+			RETURNING ` + budgetColumns
+		return scanBudget(conn(ctx, r.pool).QueryRow(ctx, query,
+			reqctx.OrgId(ctx), nullIfEmpty(b.UserId), nullIfEmpty(b.Team), b.AmountRub), b)
+	})
+	if isConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "INSERT INTO budgets",
+			"user_id": b.UserId,
+			"team":    b.Team,
+		}).Error("Failed to create budget")
+	}
+	return err
+}
+
+func (r *budgetRepository) GetById(ctx context.Context, id int) (*model.Budget, error) {
+	var b model.Budget
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `SELECT ` + budgetColumns + ` FROM budgets WHERE org_id = $1 AND budget_id = $2`
+		return scanBudget(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), id), &b)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":     "SELECT FROM budgets",
+			"budget_id": id,
+		}).Error("Failed to get budget")
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *budgetRepository) Update(ctx context.Context, b *model.Budget) error {
+	r.log.WithFields(logrus.Fields{
+		"budget_id": b.BudgetId,
+	}).Debug("Updating budget")
+
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			UPDATE budgets SET amount = $1
+			WHERE org_id = $2 AND budget_id = $3
+			RETURNING ` + budgetColumns
+		return scanBudget(conn(ctx, r.pool).QueryRow(ctx, query, b.AmountRub, reqctx.OrgId(ctx), b.BudgetId), b)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":     "UPDATE budgets",
+			"budget_id": b.BudgetId,
+		}).Error("Failed to update budget")
+	}
+	return err
+}
+
+func (r *budgetRepository) Delete(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"budget_id": id,
+	}).Debug("Deleting budget")
+
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM budgets WHERE org_id = $1 AND budget_id = $2`, reqctx.OrgId(ctx), id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":     "DELETE FROM budgets",
+			"budget_id": id,
+		}).Error("Failed to delete budget")
+	}
+	return err
+}
+
+func (r *budgetRepository) List(ctx context.Context, limit, offset int) ([]model.Budget, error) {
+	var result []model.Budget
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			SELECT `+budgetColumns+`
+			FROM budgets WHERE org_id = $1 ORDER BY budget_id LIMIT $2 OFFSET $3
+		`, reqctx.OrgId(ctx), limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var b model.Budget
+			if err := scanBudget(rows, &b); err != nil {
+				return err
+			}
+			result = append(result, b)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM budgets",
+		}).Error("Failed to list budgets")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *budgetRepository) Count(ctx context.Context) (int, error) {
+	n, err := count(ctx, r.pool, r.txm, `SELECT count(*) FROM budgets WHERE org_id = $1`, reqctx.OrgId(ctx))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM budgets",
+		}).Error("Failed to count budgets")
+	}
+	return n, err
+}
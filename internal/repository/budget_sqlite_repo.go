@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type sqliteBudgetRepository struct {
+	db  *sql.DB
+	log *logrus.Logger
+}
+
+func NewSQLiteBudgetRepository(db *sql.DB, log *logrus.Logger) BudgetRepository {
+	return &sqliteBudgetRepository{db: db, log: log}
+}
+
+const sqliteBudgetColumns = `budget_id, org_id, COALESCE(user_id, ''), COALESCE(team, ''), amount, created_at`
+
+func scanSQLiteBudget(row sqliteRowScanner) (model.Budget, error) {
+	var (
+		b         model.Budget
+		createdAt string
+	)
+	if err := row.Scan(&b.BudgetId, &b.OrgId, &b.UserId, &b.Team, &b.AmountRub, &createdAt); err != nil {
+		return b, err
+	}
+	var err error
+	b.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	return b, err
+}
+
+func (r *sqliteBudgetRepository) Create(ctx context.Context, b *model.Budget) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": b.UserId,
+		"team":    b.Team,
+	}).Debug("Creating budget")
+
+	query := `
+		INSERT INTO budgets (org_id, user_id, team, amount, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING ` + sqliteBudgetColumns
+	created, err := scanSQLiteBudget(sqlConn(ctx, r.db).QueryRowContext(ctx, query,
+		reqctx.OrgId(ctx), nullIfEmpty(b.UserId), nullIfEmpty(b.Team), b.AmountRub, formatTimestamp(time.Now())))
+	if isSQLiteConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "INSERT INTO budgets",
+			"user_id": b.UserId,
+			"team":    b.Team,
+		}).Error("Failed to create budget")
+		return err
+	}
+	*b = created
+	return nil
+}
+
+func (r *sqliteBudgetRepository) GetById(ctx context.Context, id int) (*model.Budget, error) {
+	query := `SELECT ` + sqliteBudgetColumns + ` FROM budgets WHERE org_id = ? AND budget_id = ?`
+	b, err := scanSQLiteBudget(sqlConn(ctx, r.db).QueryRowContext(ctx, query, reqctx.OrgId(ctx), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":     "SELECT FROM budgets",
+			"budget_id": id,
+		}).Error("Failed to get budget")
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (r *sqliteBudgetRepository) Update(ctx context.Context, b *model.Budget) error {
+	r.log.WithFields(logrus.Fields{
+		"budget_id": b.BudgetId,
+	}).Debug("Updating budget")
+
+	query := `
+		UPDATE budgets SET amount = ?
+		WHERE org_id = ? AND budget_id = ?
+		RETURNING ` + sqliteBudgetColumns
+	updated, err := scanSQLiteBudget(sqlConn(ctx, r.db).QueryRowContext(ctx, query, b.AmountRub, reqctx.OrgId(ctx), b.BudgetId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":     "UPDATE budgets",
+			"budget_id": b.BudgetId,
+		}).Error("Failed to update budget")
+		return err
+	}
+	*b = updated
+	return nil
+}
+
+func (r *sqliteBudgetRepository) Delete(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"budget_id": id,
+	}).Debug("Deleting budget")
+
+	res, err := sqlConn(ctx, r.db).ExecContext(ctx, `DELETE FROM budgets WHERE org_id = ? AND budget_id = ?`, reqctx.OrgId(ctx), id)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":     "DELETE FROM budgets",
+			"budget_id": id,
+		}).Error("Failed to delete budget")
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteBudgetRepository) List(ctx context.Context, limit, offset int) ([]model.Budget, error) {
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+sqliteBudgetColumns+`
+		FROM budgets WHERE org_id = ? ORDER BY budget_id LIMIT ? OFFSET ?
+	`, reqctx.OrgId(ctx), limit, offset)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM budgets",
+		}).Error("Failed to list budgets")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.Budget
+	for rows.Next() {
+		b, err := scanSQLiteBudget(rows)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for budgets")
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, rows.Err()
+}
+
+func (r *sqliteBudgetRepository) Count(ctx context.Context) (int, error) {
+	n, err := sqliteCount(ctx, r.db, `SELECT count(*) FROM budgets WHERE org_id = ?`, reqctx.OrgId(ctx))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM budgets",
+		}).Error("Failed to count budgets")
+	}
+	return n, err
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+// MemoryDB is an in-process storage shared by in-memory repositories,
+// the same way *pgxpool.Pool is shared by PostgreSQL ones
+type MemoryDB struct {
+	mu   sync.RWMutex
+	txMu sync.Mutex
+
+	*memoryTables
+}
+
+// memoryTables holds all data of MemoryDB, one field per table
+type memoryTables struct {
+	subs        map[int]model.Sub
+	nextSubId   int
+	audit       []model.SubAudit
+	nextAuditId int64
+
+	apiKeys      map[int]memoryApiKey
+	nextApiKeyId int
+
+	users         map[memoryUserKey]model.User
+	services      map[int]model.Service
+	nextServiceId int
+
+	budgets      map[int]model.Budget
+	nextBudgetId int
+
+	priceChanges      map[int]model.PriceChange
+	nextPriceChangeId int
+
+	reminders map[memoryReminderKey]struct{}
+
+	webhookEndpoints  map[int]model.WebhookEndpoint
+	nextEndpointId    int
+	webhookOutbox     map[int64]memoryWebhookEvent
+	nextEventId       int64
+	webhookDeliveries map[int64]memoryWebhookDelivery
+	nextDeliveryId    int64
+}
+
+type memoryUserKey struct {
+	orgId  string
+	userId string
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		memoryTables: &memoryTables{
+			subs:              make(map[int]model.Sub),
+			nextSubId:         1,
+			nextAuditId:       1,
+			apiKeys:           make(map[int]memoryApiKey),
+			nextApiKeyId:      1,
+			users:             make(map[memoryUserKey]model.User),
+			services:          make(map[int]model.Service),
+			nextServiceId:     1,
+			budgets:           make(map[int]model.Budget),
+			nextBudgetId:      1,
+			priceChanges:      make(map[int]model.PriceChange),
+			nextPriceChangeId: 1,
+			reminders:         make(map[memoryReminderKey]struct{}),
+			webhookEndpoints:  make(map[int]model.WebhookEndpoint),
+			nextEndpointId:    1,
+			webhookOutbox:     make(map[int64]memoryWebhookEvent),
+			nextEventId:       1,
+			webhookDeliveries: make(map[int64]memoryWebhookDelivery),
+			nextDeliveryId:    1,
+		},
+	}
+}
+
+// clone copies the tables so that later writes don't affect the copy
+func (t *memoryTables) clone() *memoryTables {
+	c := *t
+	c.subs = cloneMap(t.subs)
+	c.audit = append([]model.SubAudit(nil), t.audit...)
+	c.apiKeys = cloneMap(t.apiKeys)
+	c.users = cloneMap(t.users)
+	c.services = cloneMap(t.services)
+	c.budgets = cloneMap(t.budgets)
+	c.priceChanges = cloneMap(t.priceChanges)
+	c.reminders = cloneMap(t.reminders)
+	c.webhookEndpoints = cloneMap(t.webhookEndpoints)
+	c.webhookOutbox = cloneMap(t.webhookOutbox)
+	c.webhookDeliveries = cloneMap(t.webhookDeliveries)
+	return &c
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	c := make(map[K]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func (db *MemoryDB) snapshot() *memoryTables {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.memoryTables.clone()
+}
+
+func (db *MemoryDB) restore(t *memoryTables) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.memoryTables = t
+}
+
+type memoryTxKey struct{}
+
+type memoryTxManager struct {
+	db  *MemoryDB
+	log *logrus.Logger
+}
+
+// NewMemoryTxManager returns a TxManager for MemoryDB. Transactions are
+// serialized and a failed one restores the state it started from.
+// Reads outside a transaction may observe uncommitted writes.
+func NewMemoryTxManager(db *MemoryDB, log *logrus.Logger) TxManager {
+	return &memoryTxManager{db: db, log: log}
+}
+
+func (m *memoryTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if ctx.Value(memoryTxKey{}) != nil {
+		return fn(ctx)
+	}
+
+	m.db.txMu.Lock()
+	defer m.db.txMu.Unlock()
+
+	before := m.db.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			m.db.restore(before)
+			panic(p)
+		}
+		if err != nil {
+			m.db.restore(before)
+		}
+	}()
+
+	return fn(context.WithValue(ctx, memoryTxKey{}, true))
+}
@@ -0,0 +1,23 @@
+package repository_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/repository/repotest"
+)
+
+// discardLogger keeps repository logs out of the test output
+func discardLogger() *logrus.Logger {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return log
+}
+
+func TestMemoryRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Repositories {
+		return repository.NewMemoryRepositories(repository.NewMemoryDB(), discardLogger())
+	})
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+type memoryOrgRepository struct {
+	db  *MemoryDB
+	log *logrus.Logger
+}
+
+func NewMemoryOrgRepository(db *MemoryDB, log *logrus.Logger) OrgRepository {
+	return &memoryOrgRepository{db: db, log: log}
+}
+
+func (r *memoryOrgRepository) List(context.Context) ([]string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	seen := make(map[string]struct{})
+	for k := range r.db.users {
+		seen[k.orgId] = struct{}{}
+	}
+	for _, e := range r.db.webhookEndpoints {
+		seen[e.OrgId] = struct{}{}
+	}
+	orgs := make([]string, 0, len(seen))
+	for org := range seen {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+	return orgs, nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// OrgRepository finds organizations across all of them, for background
+// jobs that work through every organization
+type OrgRepository interface {
+	// List returns organizations that have users or webhook endpoints,
+	// ordered by id
+	List(ctx context.Context) ([]string, error)
+}
+
+type orgRepository struct {
+	pool *pgxpool.Pool
+	txm  TxManager
+	log  *logrus.Logger
+}
+
+func NewOrgRepository(pool *pgxpool.Pool, log *logrus.Logger) OrgRepository {
+	return &orgRepository{pool: pool, txm: NewTxManager(pool, log), log: log}
+}
+
+func (r *orgRepository) List(ctx context.Context) ([]string, error) {
+	orgs := []string{}
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `SELECT org_id FROM orgs ORDER BY org_id`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var org string
+			if err := rows.Scan(&org); err != nil {
+				return err
+			}
+			orgs = append(orgs, org)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithField("query", "SELECT FROM orgs").Error("Failed to list organizations")
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// OrgSelector picks the organizations a background job works through:
+// every one in the repository, or only those allowed if any are
+type OrgSelector struct {
+	orgs    OrgRepository
+	allowed map[string]bool
+	job     string
+	log     *logrus.Logger
+
+	mu      sync.Mutex
+	skipped map[string]bool
+}
+
+func NewOrgSelector(orgs OrgRepository, allowed []string, job string, log *logrus.Logger) *OrgSelector {
+	s := &OrgSelector{orgs: orgs, job: job, log: log, skipped: make(map[string]bool)}
+	if len(allowed) > 0 {
+		s.allowed = make(map[string]bool, len(allowed))
+		for _, org := range allowed {
+			s.allowed[org] = true
+		}
+	}
+	return s
+}
+
+// List returns the organizations to work through now. An organization
+// that is left out is logged the first time it is seen.
+func (s *OrgSelector) List(ctx context.Context) ([]string, error) {
+	all, err := s.orgs.List(ctx)
+	if err != nil || s.allowed == nil {
+		return all, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orgs := make([]string, 0, len(all))
+	for _, org := range all {
+		if s.allowed[org] {
+			orgs = append(orgs, org)
+			continue
+		}
+		if !s.skipped[org] {
+			s.skipped[org] = true
+			s.log.WithFields(logrus.Fields{
+				"job":    s.job,
+				"org_id": org,
+			}).Warn("Organization is not in the allowed list, skipping it")
+		}
+	}
+	return orgs, nil
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sirupsen/logrus"
+)
+
+type sqliteOrgRepository struct {
+	db  *sql.DB
+	log *logrus.Logger
+}
+
+func NewSQLiteOrgRepository(db *sql.DB, log *logrus.Logger) OrgRepository {
+	return &sqliteOrgRepository{db: db, log: log}
+}
+
+func (r *sqliteOrgRepository) List(ctx context.Context) ([]string, error) {
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT org_id FROM users UNION SELECT org_id FROM webhook_endpoints ORDER BY org_id
+	`)
+	if err != nil {
+		r.log.WithError(err).WithField("query", "SELECT org_id FROM users UNION webhook_endpoints").Error("Failed to list organizations")
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []string{}
+	for rows.Next() {
+		var org string
+		if err := rows.Scan(&org); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
@@ -0,0 +1,49 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/tmozzze/SubChecker/internal/config"
+	database "github.com/tmozzze/SubChecker/internal/db"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/repository/repotest"
+)
+
+// postgresDSNEnv points the suite at a PostgreSQL database. It must be
+// disposable, all tables are truncated before every check.
+const postgresDSNEnv = "REPOTEST_POSTGRES_DSN"
+
+// resetTables are all tables of the schema, a check must not see rows
+// left by another one
+const resetTables = `subs, sub_audit, api_keys, users, services, budgets, sub_price_changes,
+	sent_reminders, webhook_endpoints, webhook_outbox, webhook_deliveries, orgs`
+
+func TestPostgresRepositories(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skip(postgresDSNEnv + " is not set")
+	}
+
+	ctx := context.Background()
+	log := discardLogger()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	cfg := &config.Config{MigrationsDir: "../../database/migrations"}
+	if err := database.RunMigration(ctx, pool, cfg, log); err != nil {
+		t.Fatal(err)
+	}
+
+	repotest.Run(t, func(t *testing.T) *repository.Repositories {
+		if _, err := pool.Exec(ctx, `TRUNCATE `+resetTables+` RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatal(err)
+		}
+		return repository.NewPostgresRepositories(pool, log)
+	})
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type memoryPriceChangeRepository struct {
+	db  *MemoryDB
+	log *logrus.Logger
+}
+
+func NewMemoryPriceChangeRepository(db *MemoryDB, log *logrus.Logger) PriceChangeRepository {
+	return &memoryPriceChangeRepository{db: db, log: log}
+}
+
+func (r *memoryPriceChangeRepository) Create(ctx context.Context, c *model.PriceChange) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": c.SubId,
+		"month":  c.Month,
+	}).Debug("Creating price change")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	orgId := reqctx.OrgId(ctx)
+	if s, ok := r.db.subs[c.SubId]; !ok || s.OrgId != orgId {
+		return ErrNotFound
+	}
+	month := truncateToDay(c.Month)
+	for _, other := range r.db.priceChanges {
+		if other.SubId == c.SubId && other.Month.Equal(month) {
+			return ErrConflict
+		}
+	}
+	c.ChangeId = r.db.nextPriceChangeId
+	c.OrgId = orgId
+	c.Month = month
+	c.CreatedAt = time.Now().UTC()
+	r.db.nextPriceChangeId++
+	r.db.priceChanges[c.ChangeId] = *c
+	return nil
+}
+
+func (r *memoryPriceChangeRepository) Delete(ctx context.Context, subId, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id":    subId,
+		"change_id": id,
+	}).Debug("Deleting price change")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.db.priceChanges[id]
+	if !ok || c.OrgId != reqctx.OrgId(ctx) || c.SubId != subId {
+		return ErrNotFound
+	}
+	delete(r.db.priceChanges, id)
+	return nil
+}
+
+func (r *memoryPriceChangeRepository) List(ctx context.Context, subIds []int) ([]model.PriceChange, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var result []model.PriceChange
+	for _, c := range r.db.priceChanges {
+		if c.OrgId == orgId && slices.Contains(subIds, c.SubId) {
+			result = append(result, c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SubId != result[j].SubId {
+			return result[i].SubId < result[j].SubId
+		}
+		return result[i].Month.Before(result[j].Month)
+	})
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+// PriceChangeRepository only sees price changes of the organization from
+// reqctx.OrgId. Changes are deleted together with their subscription.
+type PriceChangeRepository interface {
+	// Create returns ErrNotFound if there is no such subscription and
+	// ErrConflict if it already has a change in that month
+	Create(ctx context.Context, c *model.PriceChange) error
+	Delete(ctx context.Context, subId, id int) error
+	// List returns changes of the subscriptions ordered by subscription and month
+	List(ctx context.Context, subIds []int) ([]model.PriceChange, error)
+}
+
+type priceChangeRepository struct {
+	pool *pgxpool.Pool
+	txm  TxManager
+	log  *logrus.Logger
+}
+
+func NewPriceChangeRepository(pool *pgxpool.Pool, log *logrus.Logger) PriceChangeRepository {
+	return &priceChangeRepository{pool: pool, txm: NewTxManager(pool, log), log: log}
+}
+
+const priceChangeColumns = `change_id, sub_id, org_id, effective_month, price, created_at`
+
+func scanPriceChange(row pgx.Row, c *model.PriceChange) error {
+	return row.Scan(&c.ChangeId, &c.SubId, &c.OrgId, &c.Month, &c.Price, &c.CreatedAt)
+}
+
+func (r *priceChangeRepository) Create(ctx context.Context, c *model.PriceChange) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": c.SubId,
+		"month":  c.Month,
+	}).Debug("Creating price change")
+
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		// The subscription must be in the organization, a foreign key alone doesn't check that
+		query := `
+			INSERT INTO sub_price_changes (org_id, sub_id, effective_month, price)
+			SELECT org_id, sub_id, $3, $4 FROM subs WHERE org_id = $1 AND sub_id = $2
+			RETURNING ` + priceChangeColumns
+		return scanPriceChange(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), c.SubId, c.Month, c.Price), c)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if isConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "INSERT INTO sub_price_changes",
+			"sub_id": c.SubId,
+		}).Error("Failed to create price change")
+	}
+	return err
+}
+
+func (r *priceChangeRepository) Delete(ctx context.Context, subId, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id":    subId,
+		"change_id": id,
+	}).Debug("Deleting price change")
+
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		tag, err := conn(ctx, r.pool).Exec(ctx, `
+			DELETE FROM sub_price_changes WHERE org_id = $1 AND sub_id = $2 AND change_id = $3
+		`, reqctx.OrgId(ctx), subId, id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":     "DELETE FROM sub_price_changes",
+			"change_id": id,
+		}).Error("Failed to delete price change")
+	}
+	return err
+}
+
+func (r *priceChangeRepository) List(ctx context.Context, subIds []int) ([]model.PriceChange, error) {
+	var result []model.PriceChange
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			SELECT `+priceChangeColumns+`
+			FROM sub_price_changes WHERE org_id = $1 AND sub_id = ANY($2)
+			ORDER BY sub_id, effective_month
+		`, reqctx.OrgId(ctx), subIds)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c model.PriceChange
+			if err := scanPriceChange(rows, &c); err != nil {
+				return err
+			}
+			result = append(result, c)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM sub_price_changes",
+		}).Error("Failed to list price changes")
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type sqlitePriceChangeRepository struct {
+	db  *sql.DB
+	log *logrus.Logger
+}
+
+func NewSQLitePriceChangeRepository(db *sql.DB, log *logrus.Logger) PriceChangeRepository {
+	return &sqlitePriceChangeRepository{db: db, log: log}
+}
+
+func scanSQLitePriceChange(row sqliteRowScanner) (model.PriceChange, error) {
+	var (
+		c                model.PriceChange
+		month, createdAt string
+	)
+	if err := row.Scan(&c.ChangeId, &c.SubId, &c.OrgId, &month, &c.Price, &createdAt); err != nil {
+		return c, err
+	}
+	var err error
+	if c.Month, err = parseDate(month); err != nil {
+		return c, err
+	}
+	c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	return c, err
+}
+
+func (r *sqlitePriceChangeRepository) Create(ctx context.Context, c *model.PriceChange) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": c.SubId,
+		"month":  c.Month,
+	}).Debug("Creating price change")
+
+	query := `
+		INSERT INTO sub_price_changes (org_id, sub_id, effective_month, price, created_at)
+		SELECT org_id, sub_id, ?, ?, ? FROM subs WHERE org_id = ? AND sub_id = ?
+		RETURNING ` + priceChangeColumns
+	created, err := scanSQLitePriceChange(sqlConn(ctx, r.db).QueryRowContext(ctx, query,
+		formatDate(c.Month), c.Price, formatTimestamp(time.Now()), reqctx.OrgId(ctx), c.SubId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if isSQLiteConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "INSERT INTO sub_price_changes",
+			"sub_id": c.SubId,
+		}).Error("Failed to create price change")
+		return err
+	}
+	*c = created
+	return nil
+}
+
+func (r *sqlitePriceChangeRepository) Delete(ctx context.Context, subId, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id":    subId,
+		"change_id": id,
+	}).Debug("Deleting price change")
+
+	res, err := sqlConn(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM sub_price_changes WHERE org_id = ? AND sub_id = ? AND change_id = ?
+	`, reqctx.OrgId(ctx), subId, id)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":     "DELETE FROM sub_price_changes",
+			"change_id": id,
+		}).Error("Failed to delete price change")
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqlitePriceChangeRepository) List(ctx context.Context, subIds []int) ([]model.PriceChange, error) {
+	args := []any{reqctx.OrgId(ctx)}
+	for _, id := range subIds {
+		args = append(args, id)
+	}
+	// IN () is valid in SQLite and matches nothing
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+priceChangeColumns+`
+		FROM sub_price_changes
+		WHERE org_id = ? AND sub_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(subIds)), ",")+`)
+		ORDER BY sub_id, effective_month
+	`, args...)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM sub_price_changes",
+		}).Error("Failed to list price changes")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.PriceChange
+	for rows.Next() {
+		c, err := scanSQLitePriceChange(rows)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for price changes")
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type memoryReminderKey struct {
+	orgId   string
+	subId   int
+	kind    string
+	dueDate string
+	channel string
+}
+
+type memoryReminderRepository struct {
+	db  *MemoryDB
+	log *logrus.Logger
+}
+
+func NewMemoryReminderRepository(db *MemoryDB, log *logrus.Logger) ReminderRepository {
+	return &memoryReminderRepository{db: db, log: log}
+}
+
+func reminderKey(ctx context.Context, r model.Reminder, channel string) memoryReminderKey {
+	return memoryReminderKey{
+		orgId:   reqctx.OrgId(ctx),
+		subId:   r.SubId,
+		kind:    r.Kind,
+		dueDate: r.DueDate.Format(sqliteDateLayout),
+		channel: channel,
+	}
+}
+
+func (r *memoryReminderRepository) Claim(ctx context.Context, rem model.Reminder, channel string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := reminderKey(ctx, rem, channel)
+	if _, ok := r.db.reminders[key]; ok {
+		return false, nil
+	}
+	r.db.reminders[key] = struct{}{}
+	return true, nil
+}
+
+func (r *memoryReminderRepository) Release(ctx context.Context, rem model.Reminder, channel string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.reminders, reminderKey(ctx, rem, channel))
+	return nil
+}
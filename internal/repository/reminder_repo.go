@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+// ReminderRepository records reminders sent in the organization from reqctx.OrgId,
+// one record per reminder and channel
+type ReminderRepository interface {
+	// Claim records that r is being sent through channel. It returns false
+	// if it has been recorded before.
+	Claim(ctx context.Context, r model.Reminder, channel string) (bool, error)
+	// Release forgets a claim, so that a reminder that failed is sent again
+	Release(ctx context.Context, r model.Reminder, channel string) error
+}
+
+type reminderRepository struct {
+	pool *pgxpool.Pool
+	txm  TxManager
+	log  *logrus.Logger
+}
+
+func NewReminderRepository(pool *pgxpool.Pool, log *logrus.Logger) ReminderRepository {
+	return &reminderRepository{pool: pool, txm: NewTxManager(pool, log), log: log}
+}
+
+func (r *reminderRepository) Claim(ctx context.Context, rem model.Reminder, channel string) (bool, error) {
+	var id int64
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO sent_reminders (org_id, sub_id, kind, due_date, channel)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT DO NOTHING
+			RETURNING reminder_id
+		`
+		return conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), rem.SubId, rem.Kind, rem.DueDate, channel).Scan(&id)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "INSERT INTO sent_reminders",
+			"sub_id": rem.SubId,
+		}).Error("Failed to claim reminder")
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *reminderRepository) Release(ctx context.Context, rem model.Reminder, channel string) error {
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := conn(ctx, r.pool).Exec(ctx, `
+			DELETE FROM sent_reminders
+			WHERE org_id = $1 AND sub_id = $2 AND kind = $3 AND due_date = $4 AND channel = $5
+		`, reqctx.OrgId(ctx), rem.SubId, rem.Kind, rem.DueDate, channel)
+		return err
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "DELETE FROM sent_reminders",
+			"sub_id": rem.SubId,
+		}).Error("Failed to release reminder")
+	}
+	return err
+}
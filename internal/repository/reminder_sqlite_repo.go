@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type sqliteReminderRepository struct {
+	db  *sql.DB
+	log *logrus.Logger
+}
+
+func NewSQLiteReminderRepository(db *sql.DB, log *logrus.Logger) ReminderRepository {
+	return &sqliteReminderRepository{db: db, log: log}
+}
+
+func (r *sqliteReminderRepository) Claim(ctx context.Context, rem model.Reminder, channel string) (bool, error) {
+	res, err := sqlConn(ctx, r.db).ExecContext(ctx, `
+		INSERT OR IGNORE INTO sent_reminders (org_id, sub_id, kind, due_date, channel, sent_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, reqctx.OrgId(ctx), rem.SubId, rem.Kind, formatDate(rem.DueDate), channel, formatTimestamp(time.Now()))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "INSERT INTO sent_reminders",
+			"sub_id": rem.SubId,
+		}).Error("Failed to claim reminder")
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *sqliteReminderRepository) Release(ctx context.Context, rem model.Reminder, channel string) error {
+	_, err := sqlConn(ctx, r.db).ExecContext(ctx, `
+		DELETE FROM sent_reminders
+		WHERE org_id = ? AND sub_id = ? AND kind = ? AND due_date = ? AND channel = ?
+	`, reqctx.OrgId(ctx), rem.SubId, rem.Kind, formatDate(rem.DueDate), channel)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "DELETE FROM sent_reminders",
+			"sub_id": rem.SubId,
+		}).Error("Failed to release reminder")
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// Repositories bundles all repositories of one storage backend
+type Repositories struct {
+	Subs         SubRepository
+	Users        UserRepository
+	Services     ServiceRepository
+	Budgets      BudgetRepository
+	PriceChanges PriceChangeRepository
+	Reminders    ReminderRepository
+	Webhooks     WebhookRepository
+	ApiKeys      ApiKeyRepository
+	Orgs         OrgRepository
+	Tx           TxManager
+}
+
+func NewPostgresRepositories(pool *pgxpool.Pool, log *logrus.Logger) *Repositories {
+	return &Repositories{
+		Subs:         NewSubRepository(pool, log),
+		Users:        NewUserRepository(pool, log),
+		Services:     NewServiceRepository(pool, log),
+		Budgets:      NewBudgetRepository(pool, log),
+		PriceChanges: NewPriceChangeRepository(pool, log),
+		Reminders:    NewReminderRepository(pool, log),
+		Webhooks:     NewWebhookRepository(pool, log),
+		ApiKeys:      NewApiKeyRepository(pool, log),
+		Orgs:         NewOrgRepository(pool, log),
+		Tx:           NewTxManager(pool, log),
+	}
+}
+
+func NewMemoryRepositories(db *MemoryDB, log *logrus.Logger) *Repositories {
+	return &Repositories{
+		Subs:         NewMemorySubRepository(db, log),
+		Users:        NewMemoryUserRepository(db, log),
+		Services:     NewMemoryServiceRepository(db, log),
+		Budgets:      NewMemoryBudgetRepository(db, log),
+		PriceChanges: NewMemoryPriceChangeRepository(db, log),
+		Reminders:    NewMemoryReminderRepository(db, log),
+		Webhooks:     NewMemoryWebhookRepository(db, log),
+		ApiKeys:      NewMemoryApiKeyRepository(db, log),
+		Orgs:         NewMemoryOrgRepository(db, log),
+		Tx:           NewMemoryTxManager(db, log),
+	}
+}
+
+func NewSQLiteRepositories(db *sql.DB, log *logrus.Logger) *Repositories {
+	return &Repositories{
+		Subs:         NewSQLiteSubRepository(db, log),
+		Users:        NewSQLiteUserRepository(db, log),
+		Services:     NewSQLiteServiceRepository(db, log),
+		Budgets:      NewSQLiteBudgetRepository(db, log),
+		PriceChanges: NewSQLitePriceChangeRepository(db, log),
+		Reminders:    NewSQLiteReminderRepository(db, log),
+		Webhooks:     NewSQLiteWebhookRepository(db, log),
+		ApiKeys:      NewSQLiteApiKeyRepository(db, log),
+		Orgs:         NewSQLiteOrgRepository(db, log),
+		Tx:           NewSQLiteTxManager(db, log),
+	}
+}
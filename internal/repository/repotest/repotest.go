@@ -0,0 +1,1343 @@
+// Package repotest is a conformance suite for repository implementations.
+// Every backend must pass it so that behaviour can't drift between them,
+// the tests of internal/repository run it for each of them.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+// Factory returns repositories over an empty storage, it fails t if it
+// can't and releases the storage with t.Cleanup
+type Factory func(t *testing.T) *repository.Repositories
+
+type check struct {
+	name string
+	fn   func(ctx context.Context, repos *repository.Repositories) error
+}
+
+var checks = []check{
+	{"create and get", checkCreateGet},
+	{"get missing", checkGetMissing},
+	{"update", checkUpdate},
+	{"update missing", checkUpdateMissing},
+	{"delete", checkDelete},
+	{"delete missing", checkDeleteMissing},
+	{"list pagination", checkListPagination},
+	{"list filtering", checkListFiltering},
+	{"sum cost filtering", checkSumCostFiltering},
+	{"count active", checkCountActive},
+	{"history", checkHistory},
+	{"org isolation", checkOrgIsolation},
+	{"tx rollback on error", checkTxRollbackOnError},
+	{"tx rollback on panic", checkTxRollbackOnPanic},
+	{"api key lifecycle", checkApiKeyLifecycle},
+	{"users", checkUsers},
+	{"services", checkServices},
+	{"service details", checkServiceDetails},
+	{"list by service", checkListByService},
+	{"sub references", checkSubReferences},
+	{"budgets", checkBudgets},
+	{"price changes", checkPriceChanges},
+	{"reminders", checkReminders},
+	{"webhooks", checkWebhooks},
+	{"orgs", checkOrgs},
+}
+
+// Run executes every check as a subtest against fresh repositories from
+// newRepos
+func Run(t *testing.T, newRepos Factory) {
+	for _, c := range checks {
+		t.Run(c.name, func(t *testing.T) {
+			repos := newRepos(t)
+			if err := c.fn(context.Background(), repos); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+const (
+	userA = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	userB = "7d3f2a10-5c4b-4e8a-9f1d-2b6c8e0a4f37"
+)
+
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func newSub(service string, price int, user string, start time.Time, end *time.Time) *model.Sub {
+	return &model.Sub{
+		ServiceName: service,
+		Price:       price,
+		UserId:      user,
+		StartDate:   start,
+		EndDate:     end,
+	}
+}
+
+func sameSub(got, want model.Sub) error {
+	if got.SubId != want.SubId || got.ServiceId != want.ServiceId || got.ServiceName != want.ServiceName || got.Price != want.Price ||
+		got.UserId != want.UserId || !got.StartDate.Equal(want.StartDate) {
+		return fmt.Errorf("got %+v, want %+v", got, want)
+	}
+	if (got.EndDate == nil) != (want.EndDate == nil) {
+		return fmt.Errorf("end_date: got %v, want %v", got.EndDate, want.EndDate)
+	}
+	if got.EndDate != nil && !got.EndDate.Equal(*want.EndDate) {
+		return fmt.Errorf("end_date: got %v, want %v", *got.EndDate, *want.EndDate)
+	}
+	return nil
+}
+
+func ids(subs []model.Sub) []int {
+	result := make([]int, 0, len(subs))
+	for _, s := range subs {
+		result = append(result, s.SubId)
+	}
+	return result
+}
+
+func sameIds(got []model.Sub, want ...int) error {
+	g := ids(got)
+	if len(g) != len(want) {
+		return fmt.Errorf("got ids %v, want %v", g, want)
+	}
+	for i := range g {
+		if g[i] != want[i] {
+			return fmt.Errorf("got ids %v, want %v", g, want)
+		}
+	}
+	return nil
+}
+
+// withRefs registers the user of s and points it to the service named
+// s.ServiceName, the way SubService does before writing a sub
+func withRefs(ctx context.Context, repos *repository.Repositories, s *model.Sub) error {
+	if err := repos.Users.Ensure(ctx, s.UserId); err != nil {
+		return fmt.Errorf("ensure user: %w", err)
+	}
+	svc, err := repos.Services.Ensure(ctx, s.ServiceName)
+	if err != nil {
+		return fmt.Errorf("ensure service %s: %w", s.ServiceName, err)
+	}
+	s.ServiceId = svc.ServiceId
+	s.ServiceName = svc.Name
+	return nil
+}
+
+func createAll(ctx context.Context, repos *repository.Repositories, subs ...*model.Sub) error {
+	for _, s := range subs {
+		if err := withRefs(ctx, repos, s); err != nil {
+			return err
+		}
+		if err := repos.Subs.Create(ctx, s); err != nil {
+			return fmt.Errorf("create %s: %w", s.ServiceName, err)
+		}
+	}
+	return nil
+}
+
+func checkCreateGet(ctx context.Context, repos *repository.Repositories) error {
+	end := month(2025, time.December)
+	first := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	second := newSub("Spotify", 300, userA, month(2025, time.August), &end)
+	if err := createAll(ctx, repos, first, second); err != nil {
+		return err
+	}
+	if first.SubId <= 0 || second.SubId <= first.SubId {
+		return fmt.Errorf("ids must be positive and increasing, got %d, %d", first.SubId, second.SubId)
+	}
+
+	for _, want := range []*model.Sub{first, second} {
+		got, err := repos.Subs.GetById(ctx, want.SubId)
+		if err != nil {
+			return fmt.Errorf("get %d: %w", want.SubId, err)
+		}
+		if err := sameSub(*got, *want); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkGetMissing(ctx context.Context, repos *repository.Repositories) error {
+	_, err := repos.Subs.GetById(ctx, 424242)
+	if !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("got %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func checkUpdate(ctx context.Context, repos *repository.Repositories) error {
+	s := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, s); err != nil {
+		return err
+	}
+
+	end := month(2026, time.January)
+	s.Price = 700
+	s.ServiceName = "Netflix Premium"
+	s.EndDate = &end
+	if err := withRefs(ctx, repos, s); err != nil {
+		return err
+	}
+	if err := repos.Subs.Update(ctx, s); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	got, err := repos.Subs.GetById(ctx, s.SubId)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if err := sameSub(*got, *s); err != nil {
+		return err
+	}
+
+	// Clearing end_date must persist too
+	s.EndDate = nil
+	if err := repos.Subs.Update(ctx, s); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	got, err = repos.Subs.GetById(ctx, s.SubId)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	return sameSub(*got, *s)
+}
+
+func checkUpdateMissing(ctx context.Context, repos *repository.Repositories) error {
+	s := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	s.SubId = 424242
+	if err := repos.Subs.Update(ctx, s); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("got %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func checkDelete(ctx context.Context, repos *repository.Repositories) error {
+	keep := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	drop := newSub("Spotify", 300, userA, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, keep, drop); err != nil {
+		return err
+	}
+
+	if err := repos.Subs.Delete(ctx, drop.SubId); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if _, err := repos.Subs.GetById(ctx, drop.SubId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("get deleted: got %v, want ErrNotFound", err)
+	}
+	if _, err := repos.Subs.GetById(ctx, keep.SubId); err != nil {
+		return fmt.Errorf("get kept: %w", err)
+	}
+	return nil
+}
+
+func checkDeleteMissing(ctx context.Context, repos *repository.Repositories) error {
+	if err := repos.Subs.Delete(ctx, 424242); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("got %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func checkListPagination(ctx context.Context, repos *repository.Repositories) error {
+	list, err := repos.Subs.List(ctx, model.SubFilter{}, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list empty: %w", err)
+	}
+	if len(list) != 0 {
+		return fmt.Errorf("list empty: got %d subs", len(list))
+	}
+
+	var all []int
+	for i := 0; i < 5; i++ {
+		s := newSub(fmt.Sprintf("Service %d", i), 100*(i+1), userA, month(2025, time.July), nil)
+		if err := createAll(ctx, repos, s); err != nil {
+			return err
+		}
+		all = append(all, s.SubId)
+	}
+
+	pages := []struct {
+		limit, offset int
+		want          []int
+	}{
+		{limit: 2, offset: 0, want: all[0:2]},
+		{limit: 2, offset: 2, want: all[2:4]},
+		{limit: 2, offset: 4, want: all[4:5]},
+		{limit: 2, offset: 5, want: nil},
+		{limit: 2, offset: 100, want: nil},
+		{limit: 100, offset: 0, want: all},
+		{limit: 1, offset: 4, want: all[4:5]},
+	}
+	for _, p := range pages {
+		list, err := repos.Subs.List(ctx, model.SubFilter{}, p.limit, p.offset)
+		if err != nil {
+			return fmt.Errorf("list limit=%d offset=%d: %w", p.limit, p.offset, err)
+		}
+		if err := sameIds(list, p.want...); err != nil {
+			return fmt.Errorf("list limit=%d offset=%d: %w", p.limit, p.offset, err)
+		}
+	}
+	return nil
+}
+
+func checkListFiltering(ctx context.Context, repos *repository.Repositories) error {
+	a1 := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	b1 := newSub("Netflix", 600, userB, month(2025, time.July), nil)
+	a2 := newSub("Spotify", 300, userA, month(2025, time.July), nil)
+	a3 := newSub("Netflix", 700, userA, month(2025, time.August), nil)
+	if err := createAll(ctx, repos, a1, b1, a2, a3); err != nil {
+		return err
+	}
+
+	cases := []struct {
+		filter        model.SubFilter
+		limit, offset int
+		want          []int
+		// total is what Count reports regardless of the page
+		total int
+	}{
+		{filter: model.SubFilter{UserId: userA}, limit: 10, want: []int{a1.SubId, a2.SubId, a3.SubId}, total: 3},
+		{filter: model.SubFilter{UserId: userA}, limit: 2, offset: 1, want: []int{a2.SubId, a3.SubId}, total: 3},
+		{filter: model.SubFilter{ServiceName: "Netflix"}, limit: 10, want: []int{a1.SubId, b1.SubId, a3.SubId}, total: 3},
+		{filter: model.SubFilter{UserId: userA, ServiceName: "Netflix"}, limit: 1, offset: 1, want: []int{a3.SubId}, total: 2},
+		{filter: model.SubFilter{UserId: userB, ServiceName: "Spotify"}, limit: 10, want: nil, total: 0},
+		{filter: model.SubFilter{UserIds: []string{userB}}, limit: 10, want: []int{b1.SubId}, total: 1},
+		{filter: model.SubFilter{UserIds: []string{userA, userB}, ServiceName: "Netflix"}, limit: 10, want: []int{a1.SubId, b1.SubId, a3.SubId}, total: 3},
+		{filter: model.SubFilter{UserIds: []string{}}, limit: 10, want: nil, total: 0},
+	}
+	for _, c := range cases {
+		list, err := repos.Subs.List(ctx, c.filter, c.limit, c.offset)
+		if err != nil {
+			return fmt.Errorf("list %+v: %w", c.filter, err)
+		}
+		if err := sameIds(list, c.want...); err != nil {
+			return fmt.Errorf("list %+v limit=%d offset=%d: %w", c.filter, c.limit, c.offset, err)
+		}
+		n, err := repos.Subs.Count(ctx, c.filter)
+		if err != nil {
+			return fmt.Errorf("count %+v: %w", c.filter, err)
+		}
+		if n != c.total {
+			return fmt.Errorf("count %+v: got %d, want %d", c.filter, n, c.total)
+		}
+	}
+	return nil
+}
+
+func checkSumCostFiltering(ctx context.Context, repos *repository.Repositories) error {
+	a1 := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	a2 := newSub("Spotify", 300, userA, month(2025, time.July), nil)
+	b1 := newSub("Netflix", 600, userB, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, a1, a2, b1); err != nil {
+		return err
+	}
+
+	cases := []struct {
+		user, service string
+		want          []int
+	}{
+		{user: "", service: "", want: []int{a1.SubId, a2.SubId, b1.SubId}},
+		{user: userA, service: "", want: []int{a1.SubId, a2.SubId}},
+		{user: "", service: "Netflix", want: []int{a1.SubId, b1.SubId}},
+		{user: userB, service: "Netflix", want: []int{b1.SubId}},
+		{user: userB, service: "Spotify", want: nil},
+		{user: "", service: " NETFLIX ", want: []int{a1.SubId, b1.SubId}},
+	}
+	for _, c := range cases {
+		subs, err := repos.Subs.SumCost(ctx, model.SubFilter{UserId: c.user, ServiceName: c.service})
+		if err != nil {
+			return fmt.Errorf("sum user=%q service=%q: %w", c.user, c.service, err)
+		}
+		// SumCost gives no ordering guarantee
+		got := make(map[int]bool, len(subs))
+		for _, s := range subs {
+			got[s.SubId] = true
+		}
+		if len(got) != len(c.want) || len(subs) != len(c.want) {
+			return fmt.Errorf("sum user=%q service=%q: got ids %v, want %v", c.user, c.service, ids(subs), c.want)
+		}
+		for _, id := range c.want {
+			if !got[id] {
+				return fmt.Errorf("sum user=%q service=%q: got ids %v, want %v", c.user, c.service, ids(subs), c.want)
+			}
+		}
+	}
+	return nil
+}
+
+func checkCountActive(ctx context.Context, repos *repository.Repositories) error {
+	june := month(2025, time.June)
+	if err := createAll(ctx, repos,
+		newSub("Netflix", 600, userA, month(2025, time.January), nil),
+		newSub("Spotify", 300, userA, month(2025, time.January), &june),
+		newSub("Netflix", 600, userB, month(2025, time.March), &june),
+		newSub("Spotify", 300, userB, month(2025, time.July), nil),
+	); err != nil {
+		return err
+	}
+
+	cases := []struct {
+		month time.Time
+		want  int
+	}{
+		{month: month(2024, time.December), want: 0},
+		{month: month(2025, time.January), want: 2},
+		{month: month(2025, time.June), want: 3},
+		{month: month(2025, time.July), want: 2},
+	}
+	for _, c := range cases {
+		n, err := repos.Subs.CountActive(ctx, c.month)
+		if err != nil {
+			return fmt.Errorf("count active %s: %w", c.month.Format("01-2006"), err)
+		}
+		if n != c.want {
+			return fmt.Errorf("count active %s: got %d, want %d", c.month.Format("01-2006"), n, c.want)
+		}
+	}
+	if n, err := repos.Subs.CountActive(reqctx.WithOrgId(ctx, "acme"), month(2025, time.July)); err != nil || n != 0 {
+		return fmt.Errorf("count active in other org: got %d, %v", n, err)
+	}
+	return nil
+}
+
+func checkHistory(ctx context.Context, repos *repository.Repositories) error {
+	ctx = reqctx.WithActor(ctx, "repotest")
+	ctx = reqctx.WithRequestId(ctx, "req-1")
+
+	s := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, s); err != nil {
+		return err
+	}
+	s.Price = 700
+	if err := repos.Subs.Update(ctx, s); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if err := repos.Subs.Delete(ctx, s.SubId); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	history, err := repos.Subs.History(ctx, s.SubId)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	want := []struct {
+		action              string
+		hasBefore, hasAfter bool
+	}{
+		{"create", false, true},
+		{"update", true, true},
+		{"delete", true, false},
+	}
+	if len(history) != len(want) {
+		return fmt.Errorf("got %d audit records, want %d", len(history), len(want))
+	}
+	for i, w := range want {
+		a := history[i]
+		if a.SubId != s.SubId || a.Action != w.action {
+			return fmt.Errorf("record %d: got %s of sub %d, want %s of sub %d", i, a.Action, a.SubId, w.action, s.SubId)
+		}
+		if (len(a.Before) > 0) != w.hasBefore || (len(a.After) > 0) != w.hasAfter {
+			return fmt.Errorf("record %d: unexpected before/after state", i)
+		}
+		if a.Actor != "repotest" || a.RequestId != "req-1" {
+			return fmt.Errorf("record %d: got actor %q request %q", i, a.Actor, a.RequestId)
+		}
+		if i > 0 && a.AuditId <= history[i-1].AuditId {
+			return fmt.Errorf("record %d: audit ids are not increasing", i)
+		}
+	}
+
+	other, err := repos.Subs.History(ctx, 424242)
+	if err != nil {
+		return fmt.Errorf("history missing: %w", err)
+	}
+	if len(other) != 0 {
+		return fmt.Errorf("history missing: got %d records", len(other))
+	}
+	return nil
+}
+
+func checkOrgIsolation(ctx context.Context, repos *repository.Repositories) error {
+	acme := reqctx.WithOrgId(ctx, "acme")
+	globex := reqctx.WithOrgId(ctx, "globex")
+
+	a := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	if err := createAll(acme, repos, a); err != nil {
+		return err
+	}
+	if a.OrgId != "acme" {
+		return fmt.Errorf("create: got org %q, want acme", a.OrgId)
+	}
+	g := newSub("Netflix", 400, userA, month(2025, time.July), nil)
+	if err := createAll(globex, repos, g); err != nil {
+		return err
+	}
+
+	if _, err := repos.Subs.GetById(globex, a.SubId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("get from other org: got %v, want ErrNotFound", err)
+	}
+	got, err := repos.Subs.GetById(acme, a.SubId)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if got.OrgId != "acme" {
+		return fmt.Errorf("get: got org %q, want acme", got.OrgId)
+	}
+
+	moved := *a
+	moved.Price = 1
+	if err := repos.Subs.Update(globex, &moved); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("update from other org: got %v, want ErrNotFound", err)
+	}
+	if err := repos.Subs.Delete(globex, a.SubId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("delete from other org: got %v, want ErrNotFound", err)
+	}
+
+	list, err := repos.Subs.List(acme, model.SubFilter{}, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if err := sameIds(list, a.SubId); err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	list, err = repos.Subs.List(ctx, model.SubFilter{}, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list default org: %w", err)
+	}
+	if err := sameIds(list); err != nil {
+		return fmt.Errorf("list default org: %w", err)
+	}
+	if n, err := repos.Subs.Count(ctx, model.SubFilter{}); err != nil || n != 0 {
+		return fmt.Errorf("count default org: got %d, %v", n, err)
+	}
+
+	sum, err := repos.Subs.SumCost(globex, model.SubFilter{UserId: userA, ServiceName: "Netflix"})
+	if err != nil {
+		return fmt.Errorf("sum cost: %w", err)
+	}
+	if err := sameIds(sum, g.SubId); err != nil {
+		return fmt.Errorf("sum cost: %w", err)
+	}
+
+	history, err := repos.Subs.History(globex, a.SubId)
+	if err != nil {
+		return fmt.Errorf("history from other org: %w", err)
+	}
+	if len(history) != 0 {
+		return fmt.Errorf("history from other org: got %d records", len(history))
+	}
+	history, err = repos.Subs.History(acme, a.SubId)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	if len(history) != 1 {
+		return fmt.Errorf("history: got %d records, want 1", len(history))
+	}
+	return nil
+}
+
+var errRollback = errors.New("rollback")
+
+func checkTxRollbackOnError(ctx context.Context, repos *repository.Repositories) error {
+	kept := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, kept); err != nil {
+		return err
+	}
+
+	var created *model.Sub
+	err := repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		created = newSub("Spotify", 300, userA, month(2025, time.July), nil)
+		if err := withRefs(ctx, repos, created); err != nil {
+			return err
+		}
+		if err := repos.Subs.Create(ctx, created); err != nil {
+			return err
+		}
+		kept.Price = 1
+		if err := repos.Subs.Update(ctx, kept); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return fmt.Errorf("WithinTx: got %v, want the error returned by fn", err)
+	}
+	return checkRolledBack(ctx, repos, kept.SubId, created)
+}
+
+func checkTxRollbackOnPanic(ctx context.Context, repos *repository.Repositories) (err error) {
+	kept := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, kept); err != nil {
+		return err
+	}
+
+	var created *model.Sub
+	func() {
+		defer func() {
+			if recover() == nil {
+				err = errors.New("WithinTx swallowed the panic")
+			}
+		}()
+		_ = repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+			created = newSub("Spotify", 300, userA, month(2025, time.July), nil)
+			if err := withRefs(ctx, repos, created); err != nil {
+				return err
+			}
+			if err := repos.Subs.Create(ctx, created); err != nil {
+				return err
+			}
+			kept.Price = 1
+			if err := repos.Subs.Update(ctx, kept); err != nil {
+				return err
+			}
+			panic(errRollback)
+		})
+	}()
+	if err != nil {
+		return err
+	}
+	return checkRolledBack(ctx, repos, kept.SubId, created)
+}
+
+func checkRolledBack(ctx context.Context, repos *repository.Repositories, keptId int, created *model.Sub) error {
+	if created != nil && created.SubId != 0 {
+		if _, err := repos.Subs.GetById(ctx, created.SubId); !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("create inside rolled back tx: got %v, want ErrNotFound", err)
+		}
+	}
+	got, err := repos.Subs.GetById(ctx, keptId)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if got.Price != 600 {
+		return fmt.Errorf("update inside rolled back tx persisted: price %d", got.Price)
+	}
+	return nil
+}
+
+func checkApiKeyLifecycle(ctx context.Context, repos *repository.Repositories) error {
+	first := &model.ApiKey{Name: "first", OrgId: "acme", Prefix: "sck_00000001", Scopes: []string{"read", "write"}}
+	second := &model.ApiKey{Name: "second", OrgId: reqctx.DefaultOrgId, Prefix: "sck_00000002", Scopes: []string{"admin"}}
+	if err := repos.ApiKeys.Create(ctx, first, "hash-1"); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if err := repos.ApiKeys.Create(ctx, second, "hash-2"); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if first.KeyId <= 0 || second.KeyId <= first.KeyId || first.CreatedAt.IsZero() {
+		return fmt.Errorf("create must set increasing ids and created_at, got %+v, %+v", first, second)
+	}
+
+	got, err := repos.ApiKeys.GetByHash(ctx, "hash-1")
+	if err != nil {
+		return fmt.Errorf("get by hash: %w", err)
+	}
+	if got.KeyId != first.KeyId || got.Name != "first" || got.OrgId != "acme" || got.Prefix != first.Prefix ||
+		len(got.Scopes) != 2 || got.Scopes[0] != "read" || got.Scopes[1] != "write" || got.RevokedAt != nil {
+		return fmt.Errorf("get by hash: got %+v, want %+v", got, first)
+	}
+	if _, err := repos.ApiKeys.GetByHash(ctx, "hash-unknown"); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("get unknown hash: got %v, want ErrNotFound", err)
+	}
+
+	if err := repos.ApiKeys.Revoke(ctx, first.KeyId); err != nil {
+		return fmt.Errorf("revoke: %w", err)
+	}
+	got, err = repos.ApiKeys.GetByHash(ctx, "hash-1")
+	if err != nil {
+		return fmt.Errorf("get revoked: %w", err)
+	}
+	if got.RevokedAt == nil {
+		return errors.New("revoked key has no revoked_at")
+	}
+	if err := repos.ApiKeys.Revoke(ctx, 424242); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("revoke missing: got %v, want ErrNotFound", err)
+	}
+
+	list, err := repos.ApiKeys.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(list) != 2 || list[0].KeyId != first.KeyId || list[1].KeyId != second.KeyId {
+		return fmt.Errorf("list: got %+v", list)
+	}
+	return nil
+}
+
+func checkUsers(ctx context.Context, repos *repository.Repositories) error {
+	u := &model.User{UserId: userA, Name: "Ivan"}
+	if err := repos.Users.Create(ctx, u); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if u.OrgId != reqctx.DefaultOrgId || u.CreatedAt.IsZero() {
+		return fmt.Errorf("create must set org and created_at, got %+v", u)
+	}
+	if err := repos.Users.Create(ctx, &model.User{UserId: userA}); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("create duplicate: got %v, want ErrConflict", err)
+	}
+	// Ensure keeps an existing user as is
+	if err := repos.Users.Ensure(ctx, userA); err != nil {
+		return fmt.Errorf("ensure existing: %w", err)
+	}
+	if err := repos.Users.Ensure(ctx, userB); err != nil {
+		return fmt.Errorf("ensure new: %w", err)
+	}
+
+	got, err := repos.Users.GetById(ctx, userA)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if got.Name != "Ivan" {
+		return fmt.Errorf("get: got name %q, want Ivan", got.Name)
+	}
+	if _, err := repos.Users.GetById(reqctx.WithOrgId(ctx, "acme"), userA); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("get from other org: got %v, want ErrNotFound", err)
+	}
+
+	renamed := &model.User{UserId: userB, Name: "Petr"}
+	if err := repos.Users.Update(ctx, renamed); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if renamed.CreatedAt.IsZero() || renamed.OrgId != reqctx.DefaultOrgId {
+		return fmt.Errorf("update must return the stored user, got %+v", renamed)
+	}
+	missing := "00000000-0000-4000-8000-000000000000"
+	if err := repos.Users.Update(ctx, &model.User{UserId: missing}); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("update missing: got %v, want ErrNotFound", err)
+	}
+
+	list, err := repos.Users.List(ctx, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(list) != 2 || list[0].UserId != userA || list[1].UserId != userB || list[1].Name != "Petr" {
+		return fmt.Errorf("list: got %+v", list)
+	}
+	if list, err = repos.Users.List(ctx, 1, 1); err != nil || len(list) != 1 || list[0].UserId != userB {
+		return fmt.Errorf("list limit=1 offset=1: got %+v, %v", list, err)
+	}
+	if n, err := repos.Users.Count(ctx); err != nil || n != 2 {
+		return fmt.Errorf("count: got %d, %v", n, err)
+	}
+
+	// Missing and repeated ids are skipped
+	list, err = repos.Users.GetByIds(ctx, []string{userB, missing, userA, userB})
+	if err != nil || len(list) != 2 || list[0].UserId != userA || list[1].Name != "Petr" {
+		return fmt.Errorf("get by ids: got %+v, %v", list, err)
+	}
+	if list, err = repos.Users.GetByIds(reqctx.WithOrgId(ctx, "acme"), []string{userA}); err != nil || len(list) != 0 {
+		return fmt.Errorf("get by ids from other org: got %+v, %v", list, err)
+	}
+	if list, err = repos.Users.GetByIds(ctx, nil); err != nil || len(list) != 0 {
+		return fmt.Errorf("get by no ids: got %+v, %v", list, err)
+	}
+
+	if err := createAll(ctx, repos, newSub("Netflix", 600, userA, month(2025, time.July), nil)); err != nil {
+		return err
+	}
+	if err := repos.Users.Delete(ctx, userA); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("delete with subs: got %v, want ErrConflict", err)
+	}
+	if err := repos.Users.Delete(ctx, userB); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if err := repos.Users.Delete(ctx, userB); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("delete missing: got %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func checkServices(ctx context.Context, repos *repository.Repositories) error {
+	netflix := &model.Service{Name: "  Netflix  "}
+	if err := repos.Services.Create(ctx, netflix); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	if netflix.ServiceId <= 0 || netflix.Name != "Netflix" || netflix.OrgId != reqctx.DefaultOrgId || netflix.CreatedAt.IsZero() {
+		return fmt.Errorf("create must set id, org, created_at and tidy the name, got %+v", netflix)
+	}
+	if err := repos.Services.Create(ctx, &model.Service{Name: "NETFLIX"}); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("create same name in other case: got %v, want ErrConflict", err)
+	}
+
+	same, err := repos.Services.Ensure(ctx, " netflix ")
+	if err != nil {
+		return fmt.Errorf("ensure existing: %w", err)
+	}
+	if same.ServiceId != netflix.ServiceId || same.Name != "Netflix" {
+		return fmt.Errorf("ensure existing: got %+v, want %+v", same, netflix)
+	}
+	plus, err := repos.Services.Ensure(ctx, "Yandex   Plus")
+	if err != nil {
+		return fmt.Errorf("ensure new: %w", err)
+	}
+	if plus.ServiceId == netflix.ServiceId || plus.Name != "Yandex Plus" {
+		return fmt.Errorf("ensure new: got %+v", plus)
+	}
+
+	acme := reqctx.WithOrgId(ctx, "acme")
+	other, err := repos.Services.Ensure(acme, "Netflix")
+	if err != nil {
+		return fmt.Errorf("ensure in other org: %w", err)
+	}
+	if other.ServiceId == netflix.ServiceId || other.OrgId != "acme" {
+		return fmt.Errorf("ensure in other org: got %+v", other)
+	}
+	if _, err := repos.Services.GetById(acme, netflix.ServiceId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("get from other org: got %v, want ErrNotFound", err)
+	}
+
+	s := newSub("netflix", 600, userA, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, s); err != nil {
+		return err
+	}
+	if s.ServiceId != netflix.ServiceId {
+		return fmt.Errorf("sub got service %d, want %d", s.ServiceId, netflix.ServiceId)
+	}
+
+	if err := repos.Services.Update(ctx, &model.Service{ServiceId: plus.ServiceId, Name: "netflix"}); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("rename to taken name: got %v, want ErrConflict", err)
+	}
+	if err := repos.Services.Update(ctx, &model.Service{ServiceId: 424242, Name: "Kion"}); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("rename missing: got %v, want ErrNotFound", err)
+	}
+	renamed := &model.Service{ServiceId: netflix.ServiceId, Name: "Netflix HD"}
+	if err := repos.Services.Update(ctx, renamed); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+	got, err := repos.Subs.GetById(ctx, s.SubId)
+	if err != nil {
+		return fmt.Errorf("get sub: %w", err)
+	}
+	if got.ServiceName != "Netflix HD" {
+		return fmt.Errorf("sub after rename: got service name %q, want Netflix HD", got.ServiceName)
+	}
+
+	list, err := repos.Services.List(ctx, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(list) != 2 || list[0].ServiceId != netflix.ServiceId || list[1].ServiceId != plus.ServiceId {
+		return fmt.Errorf("list: got %+v", list)
+	}
+
+	if err := repos.Services.Delete(ctx, netflix.ServiceId); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("delete with subs: got %v, want ErrConflict", err)
+	}
+	if err := repos.Services.Delete(ctx, plus.ServiceId); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if err := repos.Services.Delete(ctx, plus.ServiceId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("delete missing: got %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func checkListByService(ctx context.Context, repos *repository.Repositories) error {
+	a1 := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	b1 := newSub("netflix ", 600, userB, month(2025, time.July), nil)
+	a2 := newSub("Spotify", 300, userA, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, a1, b1, a2); err != nil {
+		return err
+	}
+
+	cases := []model.SubFilter{
+		{ServiceId: a1.ServiceId},
+		{ServiceName: "NETFLIX"},
+		{ServiceId: a1.ServiceId, ServiceName: "Netflix"},
+	}
+	for _, filter := range cases {
+		list, err := repos.Subs.List(ctx, filter, 10, 0)
+		if err != nil {
+			return fmt.Errorf("list %+v: %w", filter, err)
+		}
+		if err := sameIds(list, a1.SubId, b1.SubId); err != nil {
+			return fmt.Errorf("list %+v: %w", filter, err)
+		}
+		for _, s := range list {
+			if s.ServiceName != "Netflix" {
+				return fmt.Errorf("list %+v: got service name %q, want Netflix", filter, s.ServiceName)
+			}
+		}
+	}
+
+	sum, err := repos.Subs.SumCost(ctx, model.SubFilter{UserId: userA, ServiceId: a2.ServiceId})
+	if err != nil {
+		return fmt.Errorf("sum: %w", err)
+	}
+	if err := sameIds(sum, a2.SubId); err != nil {
+		return fmt.Errorf("sum: %w", err)
+	}
+	list, err := repos.Subs.List(ctx, model.SubFilter{ServiceId: a2.ServiceId, ServiceName: "Netflix"}, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	return sameIds(list)
+}
+
+func checkSubReferences(ctx context.Context, repos *repository.Repositories) error {
+	svc, err := repos.Services.Ensure(ctx, "Netflix")
+	if err != nil {
+		return fmt.Errorf("ensure service: %w", err)
+	}
+
+	unknownUser := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	unknownUser.ServiceId = svc.ServiceId
+	if err := repos.Subs.Create(ctx, unknownUser); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("create with unknown user: got %v, want ErrConflict", err)
+	}
+
+	if err := repos.Users.Ensure(ctx, userA); err != nil {
+		return fmt.Errorf("ensure user: %w", err)
+	}
+	unknownService := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	unknownService.ServiceId = 424242
+	if err := repos.Subs.Create(ctx, unknownService); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("create with unknown service: got %v, want ErrConflict", err)
+	}
+
+	// A service of another organization is unknown too
+	acme := reqctx.WithOrgId(ctx, "acme")
+	if err := repos.Users.Ensure(acme, userA); err != nil {
+		return fmt.Errorf("ensure user: %w", err)
+	}
+	foreign := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	foreign.ServiceId = svc.ServiceId
+	if err := repos.Subs.Create(acme, foreign); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("create with service of other org: got %v, want ErrConflict", err)
+	}
+
+	s := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, s); err != nil {
+		return err
+	}
+	s.ServiceId = 424242
+	if err := repos.Subs.Update(ctx, s); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("update with unknown service: got %v, want ErrConflict", err)
+	}
+	return nil
+}
+
+func sameService(got, want model.Service) error {
+	if got.ServiceId != want.ServiceId || got.Name != want.Name || got.Category != want.Category ||
+		got.BillingPeriod != want.BillingPeriod || got.Website != want.Website || got.LogoUrl != want.LogoUrl ||
+		(got.DefaultPrice == nil) != (want.DefaultPrice == nil) ||
+		(got.DefaultPrice != nil && *got.DefaultPrice != *want.DefaultPrice) {
+		return fmt.Errorf("got %+v, want %+v", got, want)
+	}
+	return nil
+}
+
+func checkServiceDetails(ctx context.Context, repos *repository.Repositories) error {
+	price := 2990
+	s := &model.Service{
+		Name:          "Yandex Plus",
+		Category:      "video",
+		DefaultPrice:  &price,
+		BillingPeriod: model.BillingYear,
+		Website:       "https://plus.yandex.ru",
+		LogoUrl:       "https://plus.yandex.ru/logo.png",
+	}
+	if err := repos.Services.Create(ctx, s); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	want := *s
+	got, err := repos.Services.GetById(ctx, s.ServiceId)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if err := sameService(*got, want); err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+
+	// A service added by name has no details and a monthly period
+	bare, err := repos.Services.Ensure(ctx, "Kion")
+	if err != nil {
+		return fmt.Errorf("ensure: %w", err)
+	}
+	if err := sameService(*bare, model.Service{ServiceId: bare.ServiceId, Name: "Kion", BillingPeriod: model.BillingMonth}); err != nil {
+		return fmt.Errorf("ensure: %w", err)
+	}
+	if err := repos.Services.Create(ctx, &model.Service{Name: "Okko"}); err != nil {
+		return fmt.Errorf("create without details: %w", err)
+	}
+
+	want.Category = "music"
+	want.DefaultPrice = nil
+	want.BillingPeriod = model.BillingMonth
+	want.LogoUrl = ""
+	updated := want
+	if err := repos.Services.Update(ctx, &updated); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if err := sameService(updated, want); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	list, err := repos.Services.List(ctx, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(list) != 3 {
+		return fmt.Errorf("list: got %+v", list)
+	}
+	if err := sameService(list[0], want); err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if list[2].BillingPeriod != model.BillingMonth || list[2].DefaultPrice != nil {
+		return fmt.Errorf("list: created without details: got %+v", list[2])
+	}
+	return nil
+}
+
+func checkBudgets(ctx context.Context, repos *repository.Repositories) error {
+	user := &model.Budget{UserId: userA, AmountRub: 1500}
+	team := &model.Budget{Team: "platform", AmountRub: 5000}
+	for _, b := range []*model.Budget{user, team} {
+		if err := repos.Budgets.Create(ctx, b); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+	}
+	if user.BudgetId <= 0 || team.BudgetId <= user.BudgetId || user.OrgId != reqctx.DefaultOrgId || user.CreatedAt.IsZero() {
+		return fmt.Errorf("create must set increasing ids, org and created_at, got %+v, %+v", user, team)
+	}
+	for _, dup := range []*model.Budget{{UserId: userA, AmountRub: 1}, {Team: "platform", AmountRub: 1}} {
+		if err := repos.Budgets.Create(ctx, dup); !errors.Is(err, repository.ErrConflict) {
+			return fmt.Errorf("create second budget for %+v: got %v, want ErrConflict", dup, err)
+		}
+	}
+	// Budgets are per organization
+	if err := repos.Budgets.Create(reqctx.WithOrgId(ctx, "acme"), &model.Budget{UserId: userA, AmountRub: 1}); err != nil {
+		return fmt.Errorf("create in other org: %w", err)
+	}
+	if _, err := repos.Budgets.GetById(reqctx.WithOrgId(ctx, "acme"), user.BudgetId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("get from other org: got %v, want ErrNotFound", err)
+	}
+
+	got, err := repos.Budgets.GetById(ctx, team.BudgetId)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	if got.Team != "platform" || got.UserId != "" || got.AmountRub != 5000 {
+		return fmt.Errorf("get: got %+v, want %+v", got, team)
+	}
+
+	changed := &model.Budget{BudgetId: user.BudgetId, AmountRub: 2000}
+	if err := repos.Budgets.Update(ctx, changed); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if changed.UserId != userA || changed.AmountRub != 2000 {
+		return fmt.Errorf("update must return the stored budget, got %+v", changed)
+	}
+	if err := repos.Budgets.Update(ctx, &model.Budget{BudgetId: 424242}); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("update missing: got %v, want ErrNotFound", err)
+	}
+
+	list, err := repos.Budgets.List(ctx, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(list) != 2 || list[0].BudgetId != user.BudgetId || list[0].AmountRub != 2000 || list[1].BudgetId != team.BudgetId {
+		return fmt.Errorf("list: got %+v", list)
+	}
+
+	if err := repos.Budgets.Delete(ctx, team.BudgetId); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if err := repos.Budgets.Delete(ctx, team.BudgetId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("delete missing: got %v, want ErrNotFound", err)
+	}
+	return nil
+}
+
+func checkPriceChanges(ctx context.Context, repos *repository.Repositories) error {
+	first := newSub("Netflix", 600, userA, month(2025, time.July), nil)
+	second := newSub("Spotify", 300, userA, month(2025, time.July), nil)
+	if err := createAll(ctx, repos, first, second); err != nil {
+		return err
+	}
+
+	later := &model.PriceChange{SubId: first.SubId, Month: month(2026, time.March), Price: 800}
+	earlier := &model.PriceChange{SubId: first.SubId, Month: month(2026, time.January), Price: 700}
+	other := &model.PriceChange{SubId: second.SubId, Month: month(2026, time.January), Price: 350}
+	for _, c := range []*model.PriceChange{later, earlier, other} {
+		if err := repos.PriceChanges.Create(ctx, c); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+	}
+	if later.ChangeId <= 0 || earlier.ChangeId <= later.ChangeId || later.OrgId != reqctx.DefaultOrgId ||
+		later.CreatedAt.IsZero() || !later.Month.Equal(month(2026, time.March)) {
+		return fmt.Errorf("create must set increasing ids, org and created_at, got %+v, %+v", later, earlier)
+	}
+	dup := &model.PriceChange{SubId: first.SubId, Month: month(2026, time.March), Price: 1}
+	if err := repos.PriceChanges.Create(ctx, dup); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("create second change in a month: got %v, want ErrConflict", err)
+	}
+	missing := &model.PriceChange{SubId: 424242, Month: month(2026, time.March), Price: 1}
+	if err := repos.PriceChanges.Create(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("create for missing sub: got %v, want ErrNotFound", err)
+	}
+	foreign := &model.PriceChange{SubId: first.SubId, Month: month(2026, time.June), Price: 1}
+	if err := repos.PriceChanges.Create(reqctx.WithOrgId(ctx, "acme"), foreign); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("create for sub of other org: got %v, want ErrNotFound", err)
+	}
+
+	list, err := repos.PriceChanges.List(ctx, []int{first.SubId, second.SubId})
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(list) != 3 || list[0].ChangeId != earlier.ChangeId || list[1].ChangeId != later.ChangeId ||
+		list[2].ChangeId != other.ChangeId || list[0].Price != 700 || !list[0].Month.Equal(month(2026, time.January)) {
+		return fmt.Errorf("list must be ordered by sub and month, got %+v", list)
+	}
+	if list, err := repos.PriceChanges.List(ctx, []int{}); err != nil || len(list) != 0 {
+		return fmt.Errorf("list of no subs: got %+v, %v", list, err)
+	}
+	if list, err := repos.PriceChanges.List(reqctx.WithOrgId(ctx, "acme"), []int{first.SubId}); err != nil || len(list) != 0 {
+		return fmt.Errorf("list from other org: got %+v, %v", list, err)
+	}
+
+	if err := repos.PriceChanges.Delete(ctx, second.SubId, later.ChangeId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("delete change of another sub: got %v, want ErrNotFound", err)
+	}
+	if err := repos.PriceChanges.Delete(ctx, first.SubId, later.ChangeId); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	// Changes go away with their subscription
+	if err := repos.Subs.Delete(ctx, first.SubId); err != nil {
+		return fmt.Errorf("delete sub: %w", err)
+	}
+	list, err = repos.PriceChanges.List(ctx, []int{first.SubId, second.SubId})
+	if err != nil {
+		return fmt.Errorf("list after delete: %w", err)
+	}
+	if len(list) != 1 || list[0].ChangeId != other.ChangeId {
+		return fmt.Errorf("list after delete: got %+v", list)
+	}
+	return nil
+}
+
+func checkReminders(ctx context.Context, repos *repository.Repositories) error {
+	r := model.Reminder{SubId: 1, Kind: model.ReminderRenewal, DueDate: month(2025, time.August)}
+	claim := func(ctx context.Context, r model.Reminder, channel string, want bool) error {
+		ok, err := repos.Reminders.Claim(ctx, r, channel)
+		if err != nil {
+			return fmt.Errorf("claim %+v via %s: %w", r, channel, err)
+		}
+		if ok != want {
+			return fmt.Errorf("claim %+v via %s: got %v, want %v", r, channel, ok, want)
+		}
+		return nil
+	}
+
+	if err := claim(ctx, r, "log", true); err != nil {
+		return err
+	}
+	if err := claim(ctx, r, "log", false); err != nil {
+		return err
+	}
+	// Every channel, kind, date and organization is claimed separately
+	if err := claim(ctx, r, "webhook", true); err != nil {
+		return err
+	}
+	end := r
+	end.Kind = model.ReminderEnd
+	if err := claim(ctx, end, "log", true); err != nil {
+		return err
+	}
+	next := r
+	next.DueDate = month(2025, time.September)
+	if err := claim(ctx, next, "log", true); err != nil {
+		return err
+	}
+	if err := claim(reqctx.WithOrgId(ctx, "acme"), r, "log", true); err != nil {
+		return err
+	}
+
+	if err := repos.Reminders.Release(ctx, r, "log"); err != nil {
+		return fmt.Errorf("release: %w", err)
+	}
+	if err := claim(ctx, r, "log", true); err != nil {
+		return fmt.Errorf("after release: %w", err)
+	}
+	return claim(ctx, r, "webhook", false)
+}
+
+func checkWebhooks(ctx context.Context, repos *repository.Repositories) error {
+	all := &model.WebhookEndpoint{Url: "https://example.com/all", Secret: "s1"}
+	created := &model.WebhookEndpoint{Url: "https://example.com/created", Secret: "s2", Events: []string{model.EventSubCreated}}
+	for _, e := range []*model.WebhookEndpoint{all, created} {
+		if err := repos.Webhooks.CreateEndpoint(ctx, e); err != nil {
+			return fmt.Errorf("create endpoint: %w", err)
+		}
+	}
+	if all.EndpointId <= 0 || created.EndpointId <= all.EndpointId || all.OrgId != reqctx.DefaultOrgId || all.CreatedAt.IsZero() {
+		return fmt.Errorf("create endpoint must set increasing ids, org and created_at, got %+v, %+v", all, created)
+	}
+	got, err := repos.Webhooks.GetEndpoint(ctx, created.EndpointId)
+	if err != nil {
+		return fmt.Errorf("get endpoint: %w", err)
+	}
+	if got.Secret != "s2" || len(got.Events) != 1 || got.Events[0] != model.EventSubCreated {
+		return fmt.Errorf("get endpoint: got %+v, want %+v", got, created)
+	}
+	if _, err := repos.Webhooks.GetEndpoint(reqctx.WithOrgId(ctx, "acme"), all.EndpointId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("get endpoint from other org: got %v, want ErrNotFound", err)
+	}
+
+	// An event of a rolled back transaction is never seen
+	errBoom := errors.New("boom")
+	err = repos.Tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := repos.Webhooks.Enqueue(ctx, &model.WebhookEvent{Type: model.EventSubDeleted, SubId: 9, Data: []byte(`{}`)}); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		return fmt.Errorf("rolled back enqueue: got %v, want %v", err, errBoom)
+	}
+
+	event := &model.WebhookEvent{Type: model.EventSubCreated, SubId: 1, Data: []byte(`{"sub":{"id":1}}`)}
+	if err := repos.Webhooks.Enqueue(ctx, event); err != nil {
+		return fmt.Errorf("enqueue: %w", err)
+	}
+	if event.EventId <= 0 || event.OrgId != reqctx.DefaultOrgId || event.CreatedAt.IsZero() {
+		return fmt.Errorf("enqueue must set id, org and created_at, got %+v", event)
+	}
+	pending, err := repos.Webhooks.Undispatched(ctx, 10)
+	if err != nil {
+		return fmt.Errorf("undispatched: %w", err)
+	}
+	if len(pending) != 1 || pending[0].EventId != event.EventId || pending[0].Type != model.EventSubCreated {
+		return fmt.Errorf("undispatched: got %+v, want only %+v", pending, event)
+	}
+	if other, err := repos.Webhooks.Undispatched(reqctx.WithOrgId(ctx, "acme"), 10); err != nil || len(other) != 0 {
+		return fmt.Errorf("undispatched in other org: got %+v, %v", other, err)
+	}
+
+	if err := repos.Webhooks.Dispatch(ctx, event.EventId, []int{all.EndpointId, created.EndpointId}); err != nil {
+		return fmt.Errorf("dispatch: %w", err)
+	}
+	if err := repos.Webhooks.Dispatch(ctx, event.EventId, []int{all.EndpointId}); !errors.Is(err, repository.ErrConflict) {
+		return fmt.Errorf("dispatch twice: got %v, want ErrConflict", err)
+	}
+	if pending, err := repos.Webhooks.Undispatched(ctx, 10); err != nil || len(pending) != 0 {
+		return fmt.Errorf("undispatched after dispatch: got %+v, %v", pending, err)
+	}
+
+	now := time.Now().Add(time.Second)
+	due, err := repos.Webhooks.ClaimDue(ctx, now, time.Minute, 1)
+	if err != nil {
+		return fmt.Errorf("claim due: %w", err)
+	}
+	if len(due) != 1 || due[0].EventType != model.EventSubCreated || due[0].Status != model.DeliveryPending {
+		return fmt.Errorf("claim due with limit 1: got %+v", due)
+	}
+	rest, err := repos.Webhooks.ClaimDue(ctx, now, time.Minute, 10)
+	if err != nil {
+		return fmt.Errorf("claim due: %w", err)
+	}
+	if len(rest) != 1 || rest[0].DeliveryId == due[0].DeliveryId {
+		return fmt.Errorf("claimed deliveries must not be claimed again, got %+v after %+v", rest, due)
+	}
+	if again, err := repos.Webhooks.ClaimDue(ctx, now, time.Minute, 10); err != nil || len(again) != 0 {
+		return fmt.Errorf("claim due within lease: got %+v, %v", again, err)
+	}
+
+	failed := due[0]
+	failed.Attempts = 1
+	failed.ResponseStatus = 503
+	failed.LastError = "webhook responded 503 Service Unavailable"
+	failed.NextAttemptAt = now.Add(time.Hour)
+	if err := repos.Webhooks.SaveAttempt(ctx, &failed); err != nil {
+		return fmt.Errorf("save attempt: %w", err)
+	}
+	delivered := rest[0]
+	deliveredAt := now.UTC()
+	delivered.Attempts = 1
+	delivered.Status = model.DeliveryDelivered
+	delivered.ResponseStatus = 200
+	delivered.DeliveredAt = &deliveredAt
+	if err := repos.Webhooks.SaveAttempt(ctx, &delivered); err != nil {
+		return fmt.Errorf("save attempt: %w", err)
+	}
+	if late, err := repos.Webhooks.ClaimDue(ctx, now.Add(2*time.Hour), time.Minute, 10); err != nil ||
+		len(late) != 1 || late[0].DeliveryId != failed.DeliveryId || late[0].Attempts != 1 {
+		return fmt.Errorf("claim due after backoff: got %+v, %v, want only delivery %d", late, err, failed.DeliveryId)
+	}
+
+	log, err := repos.Webhooks.ListDeliveries(ctx, failed.EndpointId, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list deliveries: %w", err)
+	}
+	if len(log) != 1 || log[0].ResponseStatus != 503 || log[0].LastError != failed.LastError {
+		return fmt.Errorf("list deliveries: got %+v, want %+v", log, failed)
+	}
+	log, err = repos.Webhooks.ListDeliveries(ctx, delivered.EndpointId, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list deliveries: %w", err)
+	}
+	if len(log) != 1 || log[0].Status != model.DeliveryDelivered || log[0].DeliveredAt == nil {
+		return fmt.Errorf("list deliveries: got %+v, want %+v", log, delivered)
+	}
+
+	if err := repos.Webhooks.DeleteEndpoint(ctx, all.EndpointId); err != nil {
+		return fmt.Errorf("delete endpoint: %w", err)
+	}
+	if err := repos.Webhooks.DeleteEndpoint(ctx, all.EndpointId); !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("delete missing endpoint: got %v, want ErrNotFound", err)
+	}
+	if log, err := repos.Webhooks.ListDeliveries(ctx, all.EndpointId, 10, 0); err != nil || len(log) != 0 {
+		return fmt.Errorf("deliveries of deleted endpoint: got %+v, %v", log, err)
+	}
+	endpoints, err := repos.Webhooks.ListEndpoints(ctx, 10, 0)
+	if err != nil {
+		return fmt.Errorf("list endpoints: %w", err)
+	}
+	if len(endpoints) != 1 || endpoints[0].EndpointId != created.EndpointId {
+		return fmt.Errorf("list endpoints: got %+v", endpoints)
+	}
+	return nil
+}
+
+func checkOrgs(ctx context.Context, repos *repository.Repositories) error {
+	orgs, err := repos.Orgs.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list empty: %w", err)
+	}
+	if len(orgs) != 0 {
+		return fmt.Errorf("list empty: got %v", orgs)
+	}
+
+	// One organization has only a user, the other only an endpoint
+	if err := repos.Users.Ensure(reqctx.WithOrgId(ctx, "zeta"), userA); err != nil {
+		return fmt.Errorf("ensure user: %w", err)
+	}
+	e := &model.WebhookEndpoint{Url: "https://example.com/hook", Secret: "s"}
+	if err := repos.Webhooks.CreateEndpoint(reqctx.WithOrgId(ctx, "acme"), e); err != nil {
+		return fmt.Errorf("create endpoint: %w", err)
+	}
+	if err := repos.Users.Ensure(reqctx.WithOrgId(ctx, "acme"), userB); err != nil {
+		return fmt.Errorf("ensure user: %w", err)
+	}
+
+	orgs, err = repos.Orgs.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if len(orgs) != 2 || orgs[0] != "acme" || orgs[1] != "zeta" {
+		return fmt.Errorf("list: got %v, want [acme zeta]", orgs)
+	}
+	return nil
+}
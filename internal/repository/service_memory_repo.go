@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type memoryServiceRepository struct {
+	db  *MemoryDB
+	log *logrus.Logger
+}
+
+func NewMemoryServiceRepository(db *MemoryDB, log *logrus.Logger) ServiceRepository {
+	return &memoryServiceRepository{db: db, log: log}
+}
+
+func (r *memoryServiceRepository) Create(ctx context.Context, s *model.Service) error {
+	r.log.WithFields(logrus.Fields{
+		"name": s.Name,
+	}).Debug("Creating service")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	orgId := reqctx.OrgId(ctx)
+	if _, ok := r.byName(orgId, s.Name); ok {
+		return ErrConflict
+	}
+	created := r.insert(orgId, s.Name)
+	created.Category = s.Category
+	created.DefaultPrice = copyPrice(s.DefaultPrice)
+	created.BillingPeriod = billingPeriod(s.BillingPeriod)
+	created.Website = s.Website
+	created.LogoUrl = s.LogoUrl
+	r.db.services[created.ServiceId] = created
+	*s = copyService(created)
+	return nil
+}
+
+func (r *memoryServiceRepository) Ensure(ctx context.Context, name string) (*model.Service, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	orgId := reqctx.OrgId(ctx)
+	s, ok := r.byName(orgId, name)
+	if !ok {
+		s = r.insert(orgId, name)
+	}
+	s = copyService(s)
+	return &s, nil
+}
+
+func (r *memoryServiceRepository) GetById(ctx context.Context, id int) (*model.Service, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	s, ok := r.db.services[id]
+	if !ok || s.OrgId != reqctx.OrgId(ctx) {
+		return nil, ErrNotFound
+	}
+	s = copyService(s)
+	return &s, nil
+}
+
+func (r *memoryServiceRepository) Update(ctx context.Context, s *model.Service) error {
+	r.log.WithFields(logrus.Fields{
+		"service_id": s.ServiceId,
+		"name":       s.Name,
+	}).Debug("Updating service")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	orgId := reqctx.OrgId(ctx)
+	stored, ok := r.db.services[s.ServiceId]
+	if !ok || stored.OrgId != orgId {
+		return ErrNotFound
+	}
+	if other, ok := r.byName(orgId, s.Name); ok && other.ServiceId != s.ServiceId {
+		return ErrConflict
+	}
+	stored.Name = serviceName(s.Name)
+	stored.Category = s.Category
+	stored.DefaultPrice = copyPrice(s.DefaultPrice)
+	stored.BillingPeriod = billingPeriod(s.BillingPeriod)
+	stored.Website = s.Website
+	stored.LogoUrl = s.LogoUrl
+	r.db.services[s.ServiceId] = stored
+	*s = copyService(stored)
+	return nil
+}
+
+func (r *memoryServiceRepository) Delete(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"service_id": id,
+	}).Debug("Deleting service")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	s, ok := r.db.services[id]
+	if !ok || s.OrgId != reqctx.OrgId(ctx) {
+		return ErrNotFound
+	}
+	for _, sub := range r.db.subs {
+		if sub.ServiceId == id {
+			return ErrConflict
+		}
+	}
+	delete(r.db.services, id)
+	return nil
+}
+
+func (r *memoryServiceRepository) List(ctx context.Context, limit, offset int) ([]model.Service, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var result []model.Service
+	for _, s := range r.db.services {
+		if s.OrgId == orgId {
+			result = append(result, copyService(s))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ServiceId < result[j].ServiceId })
+	return page(result, limit, offset), nil
+}
+
+func (r *memoryServiceRepository) Count(ctx context.Context) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	n := 0
+	for _, v := range r.db.services {
+		if v.OrgId == orgId {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// byName finds a service of the organization by name key. Caller holds db.mu.
+func (r *memoryServiceRepository) byName(orgId, name string) (model.Service, bool) {
+	key := serviceNameKey(name)
+	for _, s := range r.db.services {
+		if s.OrgId == orgId && serviceNameKey(s.Name) == key {
+			return s, true
+		}
+	}
+	return model.Service{}, false
+}
+
+// insert adds a service. Caller holds db.mu for writing.
+func (r *memoryServiceRepository) insert(orgId, name string) model.Service {
+	s := model.Service{
+		ServiceId:     r.db.nextServiceId,
+		OrgId:         orgId,
+		Name:          serviceName(name),
+		BillingPeriod: model.BillingMonth,
+		CreatedAt:     time.Now().UTC(),
+	}
+	r.db.nextServiceId++
+	r.db.services[s.ServiceId] = s
+	return s
+}
+
+func copyService(s model.Service) model.Service {
+	s.DefaultPrice = copyPrice(s.DefaultPrice)
+	return s
+}
+
+func copyPrice(p *int) *int {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
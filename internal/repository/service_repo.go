@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+// ServiceRepository only sees services of the organization from reqctx.OrgId.
+// Names are unique ignoring case and extra whitespace.
+type ServiceRepository interface {
+	// Create returns ErrConflict if a service with the same name exists
+	Create(ctx context.Context, s *model.Service) error
+	// Ensure returns the service with the name, creating it if needed
+	Ensure(ctx context.Context, name string) (*model.Service, error)
+	GetById(ctx context.Context, id int) (*model.Service, error)
+	// Update replaces name and details of the service, ErrConflict if the name is taken
+	Update(ctx context.Context, s *model.Service) error
+	// Delete returns ErrConflict while the service has subscriptions
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, limit, offset int) ([]model.Service, error)
+	Count(ctx context.Context) (int, error)
+}
+
+// serviceName tidies up whitespace of a service name
+func serviceName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// billingPeriod defaults an empty period to a month
+func billingPeriod(p string) string {
+	if p == "" {
+		return model.BillingMonth
+	}
+	return p
+}
+
+// serviceNameKey is what service names are compared by,
+// so "Netflix", "netflix " and "NETFLIX" are one service
+func serviceNameKey(name string) string {
+	return strings.ToLower(serviceName(name))
+}
+
+type serviceRepository struct {
+	pool *pgxpool.Pool
+	txm  TxManager
+	log  *logrus.Logger
+}
+
+func NewServiceRepository(pool *pgxpool.Pool, log *logrus.Logger) ServiceRepository {
+	return &serviceRepository{pool: pool, txm: NewTxManager(pool, log), log: log}
+}
+
+const serviceColumns = `service_id, org_id, name, category, default_price, billing_period, website, logo_url, created_at`
+
+func scanService(row pgx.Row, s *model.Service) error {
+	return row.Scan(&s.ServiceId, &s.OrgId, &s.Name, &s.Category, &s.DefaultPrice, &s.BillingPeriod, &s.Website, &s.LogoUrl, &s.CreatedAt)
+}
+
+func (r *serviceRepository) Create(ctx context.Context, s *model.Service) error {
+	r.log.WithFields(logrus.Fields{
+		"name": s.Name,
+	}).Debug("Creating service")
+
+	s.Name = serviceName(s.Name)
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO services (org_id, name, name_key, category, default_price, billing_period, website, logo_url)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING ` + serviceColumns
+		return scanService(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), s.Name, serviceNameKey(s.Name),
+			s.Category, s.DefaultPrice, billingPeriod(s.BillingPeriod), s.Website, s.LogoUrl), s)
+	})
+	if isConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "INSERT INTO services",
+			"name":  s.Name,
+		}).Error("Failed to create service")
+	}
+	return err
+}
+
+func (r *serviceRepository) Ensure(ctx context.Context, name string) (*model.Service, error) {
+	var s model.Service
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		orgId, key := reqctx.OrgId(ctx), serviceNameKey(name)
+		_, err := conn(ctx, r.pool).Exec(ctx, `
+			INSERT INTO services (org_id, name, name_key)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (org_id, name_key) DO NOTHING
+		`, orgId, serviceName(name), key)
+		if err != nil {
+			return err
+		}
+
+		query := `SELECT ` + serviceColumns + ` FROM services WHERE org_id = $1 AND name_key = $2`
+		return scanService(conn(ctx, r.pool).QueryRow(ctx, query, orgId, key), &s)
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "INSERT INTO services ON CONFLICT",
+			"name":  name,
+		}).Error("Failed to ensure service")
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *serviceRepository) GetById(ctx context.Context, id int) (*model.Service, error) {
+	var s model.Service
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `SELECT ` + serviceColumns + ` FROM services WHERE org_id = $1 AND service_id = $2`
+		return scanService(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), id), &s)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":      "SELECT FROM services",
+			"service_id": id,
+		}).Error("Failed to get service")
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *serviceRepository) Update(ctx context.Context, s *model.Service) error {
+	r.log.WithFields(logrus.Fields{
+		"service_id": s.ServiceId,
+		"name":       s.Name,
+	}).Debug("Updating service")
+
+	s.Name = serviceName(s.Name)
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			UPDATE services
+			SET name = $1, name_key = $2, category = $3, default_price = $4, billing_period = $5, website = $6, logo_url = $7
+			WHERE org_id = $8 AND service_id = $9
+			RETURNING ` + serviceColumns
+		return scanService(conn(ctx, r.pool).QueryRow(ctx, query, s.Name, serviceNameKey(s.Name),
+			s.Category, s.DefaultPrice, billingPeriod(s.BillingPeriod), s.Website, s.LogoUrl, reqctx.OrgId(ctx), s.ServiceId), s)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if isConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":      "UPDATE services",
+			"service_id": s.ServiceId,
+		}).Error("Failed to update service")
+	}
+	return err
+}
+
+func (r *serviceRepository) Delete(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"service_id": id,
+	}).Debug("Deleting service")
+
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM services WHERE org_id = $1 AND service_id = $2`, reqctx.OrgId(ctx), id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if isConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":      "DELETE FROM services",
+			"service_id": id,
+		}).Error("Failed to delete service")
+	}
+	return err
+}
+
+func (r *serviceRepository) List(ctx context.Context, limit, offset int) ([]model.Service, error) {
+	var result []model.Service
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			SELECT `+serviceColumns+`
+			FROM services WHERE org_id = $1 ORDER BY service_id LIMIT $2 OFFSET $3
+		`, reqctx.OrgId(ctx), limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var s model.Service
+			if err := scanService(rows, &s); err != nil {
+				return err
+			}
+			result = append(result, s)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM services",
+		}).Error("Failed to list services")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *serviceRepository) Count(ctx context.Context) (int, error) {
+	n, err := count(ctx, r.pool, r.txm, `SELECT count(*) FROM services WHERE org_id = $1`, reqctx.OrgId(ctx))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM services",
+		}).Error("Failed to count services")
+	}
+	return n, err
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type sqliteServiceRepository struct {
+	db  *sql.DB
+	log *logrus.Logger
+}
+
+func NewSQLiteServiceRepository(db *sql.DB, log *logrus.Logger) ServiceRepository {
+	return &sqliteServiceRepository{db: db, log: log}
+}
+
+const sqliteServiceColumns = `service_id, org_id, name, category, default_price, billing_period, website, logo_url, created_at`
+
+func scanSQLiteService(row sqliteRowScanner) (model.Service, error) {
+	var (
+		s            model.Service
+		defaultPrice sql.NullInt64
+		createdAt    string
+	)
+	err := row.Scan(&s.ServiceId, &s.OrgId, &s.Name, &s.Category, &defaultPrice, &s.BillingPeriod, &s.Website, &s.LogoUrl, &createdAt)
+	if err != nil {
+		return s, err
+	}
+	if defaultPrice.Valid {
+		p := int(defaultPrice.Int64)
+		s.DefaultPrice = &p
+	}
+	s.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	return s, err
+}
+
+func (r *sqliteServiceRepository) Create(ctx context.Context, s *model.Service) error {
+	r.log.WithFields(logrus.Fields{
+		"name": s.Name,
+	}).Debug("Creating service")
+
+	query := `
+		INSERT INTO services (org_id, name, name_key, category, default_price, billing_period, website, logo_url, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + sqliteServiceColumns
+	created, err := scanSQLiteService(sqlConn(ctx, r.db).QueryRowContext(ctx, query,
+		reqctx.OrgId(ctx), serviceName(s.Name), serviceNameKey(s.Name),
+		s.Category, s.DefaultPrice, billingPeriod(s.BillingPeriod), s.Website, s.LogoUrl, formatTimestamp(time.Now())))
+	if isSQLiteConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "INSERT INTO services",
+			"name":  s.Name,
+		}).Error("Failed to create service")
+		return err
+	}
+	*s = created
+	return nil
+}
+
+func (r *sqliteServiceRepository) Ensure(ctx context.Context, name string) (*model.Service, error) {
+	orgId, key := reqctx.OrgId(ctx), serviceNameKey(name)
+	_, err := sqlConn(ctx, r.db).ExecContext(ctx, `
+		INSERT OR IGNORE INTO services (org_id, name, name_key, created_at)
+		VALUES (?, ?, ?, ?)
+	`, orgId, serviceName(name), key, formatTimestamp(time.Now()))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "INSERT OR IGNORE INTO services",
+			"name":  name,
+		}).Error("Failed to ensure service")
+		return nil, err
+	}
+
+	query := `SELECT ` + sqliteServiceColumns + ` FROM services WHERE org_id = ? AND name_key = ?`
+	s, err := scanSQLiteService(sqlConn(ctx, r.db).QueryRowContext(ctx, query, orgId, key))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM services",
+			"name":  name,
+		}).Error("Failed to ensure service")
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sqliteServiceRepository) GetById(ctx context.Context, id int) (*model.Service, error) {
+	query := `SELECT ` + sqliteServiceColumns + ` FROM services WHERE org_id = ? AND service_id = ?`
+	s, err := scanSQLiteService(sqlConn(ctx, r.db).QueryRowContext(ctx, query, reqctx.OrgId(ctx), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":      "SELECT FROM services",
+			"service_id": id,
+		}).Error("Failed to get service")
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sqliteServiceRepository) Update(ctx context.Context, s *model.Service) error {
+	r.log.WithFields(logrus.Fields{
+		"service_id": s.ServiceId,
+		"name":       s.Name,
+	}).Debug("Updating service")
+
+	query := `
+		UPDATE services
+		SET name = ?, name_key = ?, category = ?, default_price = ?, billing_period = ?, website = ?, logo_url = ?
+		WHERE org_id = ? AND service_id = ?
+		RETURNING ` + sqliteServiceColumns
+	updated, err := scanSQLiteService(sqlConn(ctx, r.db).QueryRowContext(ctx, query,
+		serviceName(s.Name), serviceNameKey(s.Name),
+		s.Category, s.DefaultPrice, billingPeriod(s.BillingPeriod), s.Website, s.LogoUrl, reqctx.OrgId(ctx), s.ServiceId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if isSQLiteConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":      "UPDATE services",
+			"service_id": s.ServiceId,
+		}).Error("Failed to update service")
+		return err
+	}
+	*s = updated
+	return nil
+}
+
+func (r *sqliteServiceRepository) Delete(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"service_id": id,
+	}).Debug("Deleting service")
+
+	res, err := sqlConn(ctx, r.db).ExecContext(ctx, `DELETE FROM services WHERE org_id = ? AND service_id = ?`, reqctx.OrgId(ctx), id)
+	if isSQLiteConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":      "DELETE FROM services",
+			"service_id": id,
+		}).Error("Failed to delete service")
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteServiceRepository) List(ctx context.Context, limit, offset int) ([]model.Service, error) {
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+sqliteServiceColumns+`
+		FROM services WHERE org_id = ? ORDER BY service_id LIMIT ? OFFSET ?
+	`, reqctx.OrgId(ctx), limit, offset)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM services",
+		}).Error("Failed to list services")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.Service
+	for rows.Next() {
+		s, err := scanSQLiteService(rows)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for services")
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+func (r *sqliteServiceRepository) Count(ctx context.Context) (int, error) {
+	n, err := sqliteCount(ctx, r.db, `SELECT count(*) FROM services WHERE org_id = ?`, reqctx.OrgId(ctx))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM services",
+		}).Error("Failed to count services")
+	}
+	return n, err
+}
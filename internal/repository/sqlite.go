@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const sqliteDateLayout = "2006-01-02"
+
+// sqlQuerier is implemented by both *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type sqliteTxKey struct{}
+
+type sqliteTxManager struct {
+	db  *sql.DB
+	log *logrus.Logger
+}
+
+func NewSQLiteTxManager(db *sql.DB, log *logrus.Logger) TxManager {
+	return &sqliteTxManager{db: db, log: log}
+}
+
+func (m *sqliteTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	// Nested calls join the outer transaction
+	if _, ok := ctx.Value(sqliteTxKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		m.log.WithError(err).Error("Failed to begin transaction")
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			m.rollback(tx)
+			panic(p)
+		}
+		if err != nil {
+			m.rollback(tx)
+			return
+		}
+		if err = tx.Commit(); err != nil {
+			m.log.WithError(err).Error("Failed to commit transaction")
+		}
+	}()
+
+	return fn(context.WithValue(ctx, sqliteTxKey{}, tx))
+}
+
+func (m *sqliteTxManager) rollback(tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		m.log.WithError(err).Error("Failed to rollback transaction")
+	}
+}
+
+// sqlConn returns the transaction bound to ctx, or db if there is none
+func sqlConn(ctx context.Context, db *sql.DB) sqlQuerier {
+	if tx, ok := ctx.Value(sqliteTxKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// sqliteCount runs a SELECT count(*)
+func sqliteCount(ctx context.Context, db *sql.DB, query string, args ...any) (int, error) {
+	var n int
+	err := sqlConn(ctx, db).QueryRowContext(ctx, query, args...).Scan(&n)
+	return n, err
+}
+
+func formatDate(t time.Time) string {
+	return t.Format(sqliteDateLayout)
+}
+
+func formatNullDate(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: formatDate(*t), Valid: true}
+}
+
+func parseDate(s string) (time.Time, error) {
+	return time.ParseInLocation(sqliteDateLayout, s, time.UTC)
+}
+
+func parseNullDate(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := parseDate(s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseNullTimestamp(s sql.NullString) (*time.Time, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// isSQLiteConstraintViolation reports unique and foreign key violations
+func isSQLiteConstraintViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return true
+	}
+	return false
+}
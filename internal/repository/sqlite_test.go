@@ -0,0 +1,33 @@
+package repository_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmozzze/SubChecker/internal/config"
+	database "github.com/tmozzze/SubChecker/internal/db"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/repository/repotest"
+)
+
+func TestSQLiteRepositories(t *testing.T) {
+	log := discardLogger()
+	repotest.Run(t, func(t *testing.T) *repository.Repositories {
+		// Every check gets its own database file
+		cfg := &config.Config{
+			SQLitePath:          filepath.Join(t.TempDir(), "repotest.db"),
+			SQLiteMigrationsDir: "../../database/sqlite_migrations",
+		}
+		ctx := context.Background()
+		db, err := database.NewSQLite(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if err := database.RunSQLiteMigration(ctx, db, cfg, log); err != nil {
+			t.Fatal(err)
+		}
+		return repository.NewSQLiteRepositories(db, log)
+	})
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+const (
+	auditCreate = "create"
+	auditUpdate = "update"
+	auditDelete = "delete"
+)
+
+// lockById reads the current row state and locks it until the end of the
+// transaction bound to ctx. Returns ErrNotFound if there is no such row
+// in the organization.
+func (r *subRepository) lockById(ctx context.Context, id int) (*model.Sub, error) {
+	var s model.Sub
+	query := `
+		SELECT ` + subColumns + `
+		FROM ` + subFrom + ` WHERE s.org_id = $1 AND s.sub_id = $2 FOR UPDATE OF s
+	`
+	err := scanSub(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), id), &s)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "SELECT FROM subs FOR UPDATE",
+			"sub_id": id,
+		}).Error("Failed to lock subscription")
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *subRepository) writeAudit(ctx context.Context, action string, subId int, before, after *model.Sub) error {
+	beforeJSON, err := marshalState(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalState(after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO sub_audit (org_id, sub_id, action, before_state, after_state, actor, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = conn(ctx, r.pool).Exec(ctx, query, reqctx.OrgId(ctx), subId, action, beforeJSON, afterJSON,
+		reqctx.Actor(ctx), reqctx.RequestId(ctx))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "INSERT INTO sub_audit",
+			"sub_id": subId,
+			"action": action,
+		}).Error("Failed to write audit record")
+	}
+	return err
+}
+
+func marshalState(s *model.Sub) ([]byte, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+func (r *subRepository) History(ctx context.Context, id int) ([]model.SubAudit, error) {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": id,
+	}).Debug("Getting history")
+
+	var result []model.SubAudit
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			SELECT audit_id, sub_id, org_id, action, before_state, after_state, actor, request_id, changed_at
+			FROM sub_audit WHERE org_id = $1 AND sub_id = $2 ORDER BY audit_id
+		`, reqctx.OrgId(ctx), id)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var a model.SubAudit
+			err := rows.Scan(&a.AuditId, &a.SubId, &a.OrgId, &a.Action, &a.Before, &a.After, &a.Actor, &a.RequestId, &a.ChangedAt)
+			if err != nil {
+				return err
+			}
+			result = append(result, a)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "SELECT FROM sub_audit",
+			"sub_id": id,
+		}).Error("Failed to get history")
+
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type memorySubRepository struct {
+	db  *MemoryDB
+	txm TxManager
+	log *logrus.Logger
+}
+
+// NewMemorySubRepository returns a SubRepository that keeps data in db.
+// It mirrors the PostgreSQL implementation: ids are assigned sequentially,
+// dates are stored with day precision in UTC and lists are ordered by id.
+func NewMemorySubRepository(db *MemoryDB, log *logrus.Logger) SubRepository {
+	return &memorySubRepository{db: db, txm: NewMemoryTxManager(db, log), log: log}
+}
+
+func (r *memorySubRepository) Create(ctx context.Context, s *model.Sub) error {
+	r.log.WithFields(logrus.Fields{
+		"service_name": s.ServiceName,
+		"user_id":      s.UserId,
+	}).Debug("Creating new subscription")
+
+	return r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		r.db.mu.Lock()
+		defer r.db.mu.Unlock()
+
+		s.OrgId = reqctx.OrgId(ctx)
+		if !r.references(*s) {
+			return ErrConflict
+		}
+		stored := normalizeSub(*s)
+		stored.SubId = r.db.nextSubId
+		r.db.nextSubId++
+		r.db.subs[stored.SubId] = stored
+
+		s.SubId = stored.SubId
+		return r.writeAudit(ctx, auditCreate, stored.SubId, nil, &stored)
+	})
+}
+
+func (r *memorySubRepository) GetById(ctx context.Context, id int) (*model.Sub, error) {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": id,
+	}).Debug("Getting by id")
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	s, ok := r.get(ctx, id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	s = copySub(s)
+	return &s, nil
+}
+
+func (r *memorySubRepository) Update(ctx context.Context, s *model.Sub) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": s.SubId,
+	}).Debug("Updating")
+
+	return r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		r.db.mu.Lock()
+		defer r.db.mu.Unlock()
+
+		before, ok := r.get(ctx, s.SubId)
+		if !ok {
+			return ErrNotFound
+		}
+		s.OrgId = before.OrgId
+		if !r.references(*s) {
+			return ErrConflict
+		}
+		after := normalizeSub(*s)
+		r.db.subs[s.SubId] = after
+
+		return r.writeAudit(ctx, auditUpdate, s.SubId, &before, &after)
+	})
+}
+
+func (r *memorySubRepository) Delete(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": id,
+	}).Debug("Deleting")
+
+	return r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		r.db.mu.Lock()
+		defer r.db.mu.Unlock()
+
+		before, ok := r.get(ctx, id)
+		if !ok {
+			return ErrNotFound
+		}
+		delete(r.db.subs, id)
+		for changeId, c := range r.db.priceChanges {
+			if c.SubId == id {
+				delete(r.db.priceChanges, changeId)
+			}
+		}
+
+		return r.writeAudit(ctx, auditDelete, id, &before, nil)
+	})
+}
+
+func (r *memorySubRepository) List(ctx context.Context, filter model.SubFilter, limit, offset int) ([]model.Sub, error) {
+	r.log.WithFields(logrus.Fields{
+		"user_id":      filter.UserId,
+		"service_name": filter.ServiceName,
+		"limit":        limit,
+		"offset":       offset,
+	}).Debug("Getting list")
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	all := r.sorted(func(s model.Sub) bool { return s.OrgId == orgId && MatchSub(s, filter) })
+	return page(all, limit, offset), nil
+}
+
+func (r *memorySubRepository) Count(ctx context.Context, filter model.SubFilter) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	return len(r.sorted(func(s model.Sub) bool { return s.OrgId == orgId && MatchSub(s, filter) })), nil
+}
+
+func (r *memorySubRepository) CountActive(ctx context.Context, month time.Time) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	return len(r.sorted(func(s model.Sub) bool {
+		return s.OrgId == orgId && !s.StartDate.After(month) && (s.EndDate == nil || !s.EndDate.Before(month))
+	})), nil
+}
+
+func (r *memorySubRepository) SumCost(ctx context.Context, filter model.SubFilter) ([]model.Sub, error) {
+	r.log.WithFields(logrus.Fields{
+		"user_id":      filter.UserId,
+		"service_name": filter.ServiceName,
+	}).Debug("Getting sum")
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	return r.sorted(func(s model.Sub) bool { return s.OrgId == orgId && MatchSub(s, filter) }), nil
+}
+
+func (r *memorySubRepository) History(ctx context.Context, id int) ([]model.SubAudit, error) {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": id,
+	}).Debug("Getting history")
+
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var result []model.SubAudit
+	for _, a := range r.db.audit {
+		if a.OrgId == orgId && a.SubId == id {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+// get returns the sub with id if it belongs to the organization. Caller holds db.mu.
+func (r *memorySubRepository) get(ctx context.Context, id int) (model.Sub, bool) {
+	s, ok := r.db.subs[id]
+	if !ok || s.OrgId != reqctx.OrgId(ctx) {
+		return model.Sub{}, false
+	}
+	return r.withService(s), true
+}
+
+// withService sets the current name of the sub's service, the way the
+// PostgreSQL repository joins it. Caller holds db.mu.
+func (r *memorySubRepository) withService(s model.Sub) model.Sub {
+	s.ServiceName = r.db.services[s.ServiceId].Name
+	return s
+}
+
+// references reports whether the user and the service of s exist in its
+// organization, like the foreign keys on subs. Caller holds db.mu.
+func (r *memorySubRepository) references(s model.Sub) bool {
+	_, userOk := r.db.users[memoryUserKey{orgId: s.OrgId, userId: s.UserId}]
+	service, serviceOk := r.db.services[s.ServiceId]
+	return userOk && serviceOk && service.OrgId == s.OrgId
+}
+
+// sorted returns copies of matching subs ordered by id. Caller holds db.mu.
+func (r *memorySubRepository) sorted(match func(model.Sub) bool) []model.Sub {
+	var result []model.Sub
+	for _, s := range r.db.subs {
+		s = r.withService(s)
+		if match(s) {
+			result = append(result, copySub(s))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].SubId < result[j].SubId })
+	return result
+}
+
+// MatchSub reports whether filter matches s the same way repositories filter lists
+func MatchSub(s model.Sub, filter model.SubFilter) bool {
+	return (filter.UserId == "" || s.UserId == filter.UserId) &&
+		(filter.UserIds == nil || slices.Contains(filter.UserIds, s.UserId)) &&
+		(filter.ServiceId == 0 || s.ServiceId == filter.ServiceId) &&
+		(filter.ServiceName == "" || serviceNameKey(s.ServiceName) == serviceNameKey(filter.ServiceName))
+}
+
+// writeAudit appends an audit record. Caller holds db.mu for writing.
+func (r *memorySubRepository) writeAudit(ctx context.Context, action string, subId int, before, after *model.Sub) error {
+	beforeJSON, err := marshalState(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalState(after)
+	if err != nil {
+		return err
+	}
+
+	r.db.audit = append(r.db.audit, model.SubAudit{
+		AuditId:   r.db.nextAuditId,
+		SubId:     subId,
+		OrgId:     reqctx.OrgId(ctx),
+		Action:    action,
+		Before:    json.RawMessage(beforeJSON),
+		After:     json.RawMessage(afterJSON),
+		Actor:     reqctx.Actor(ctx),
+		RequestId: reqctx.RequestId(ctx),
+		ChangedAt: time.Now().UTC(),
+	})
+	r.db.nextAuditId++
+	return nil
+}
+
+// normalizeSub stores dates the way a DATE column does
+func normalizeSub(s model.Sub) model.Sub {
+	s.StartDate = truncateToDay(s.StartDate)
+	if s.EndDate != nil {
+		ed := truncateToDay(*s.EndDate)
+		s.EndDate = &ed
+	}
+	return s
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func copySub(s model.Sub) model.Sub {
+	if s.EndDate != nil {
+		ed := *s.EndDate
+		s.EndDate = &ed
+	}
+	return s
+}
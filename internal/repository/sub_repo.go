@@ -2,29 +2,41 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
 )
 
+// SubRepository only sees subscriptions of the organization from reqctx.OrgId
 type SubRepository interface {
 	Create(ctx context.Context, s *model.Sub) error
 	GetById(ctx context.Context, id int) (*model.Sub, error)
 	Update(ctx context.Context, s *model.Sub) error
 	Delete(ctx context.Context, id int) error
-	List(ctx context.Context, limit, offset int) ([]model.Sub, error)
-	SumCost(ctx context.Context, userID, serviceName string) ([]model.Sub, error)
+	List(ctx context.Context, filter model.SubFilter, limit, offset int) ([]model.Sub, error)
+	// Count is how many subscriptions List pages through
+	Count(ctx context.Context, filter model.SubFilter) (int, error)
+	// CountActive is how many subscriptions are paid for in month: started
+	// in it or before and not ended before it
+	CountActive(ctx context.Context, month time.Time) (int, error)
+	SumCost(ctx context.Context, filter model.SubFilter) ([]model.Sub, error)
+	History(ctx context.Context, id int) ([]model.SubAudit, error)
 }
 
 type subRepository struct {
 	pool *pgxpool.Pool
+	txm  TxManager
 	log  *logrus.Logger
 }
 
 func NewSubRepository(pool *pgxpool.Pool, log *logrus.Logger) SubRepository {
-	return &subRepository{pool: pool, log: log}
+	return &subRepository{pool: pool, txm: NewTxManager(pool, log), log: log}
 }
 
 func (r *subRepository) Create(ctx context.Context, s *model.Sub) error {
@@ -38,20 +50,29 @@ func (r *subRepository) Create(ctx context.Context, s *model.Sub) error {
 		endDate = *s.EndDate
 	}
 
-	query := `
-		INSERT INTO subs (service_name, price, user_id, start_date, end_date)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING sub_id
-	`
-	err := r.pool.QueryRow(ctx, query, s.ServiceName, s.Price, s.UserId, s.StartDate, endDate).Scan(&s.SubId)
-	if err != nil {
-		r.log.WithError(err).WithFields(logrus.Fields{
-			"query":        "INSERT INTO subs",
-			"service_name": s.ServiceName,
-			"user_id":      s.UserId,
-		}).Error("Failed to create subscription")
-	}
-	return err
+	s.OrgId = reqctx.OrgId(ctx)
+
+	return r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO subs (org_id, service_id, price, user_id, start_date, end_date)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING sub_id
+		`
+		err := conn(ctx, r.pool).QueryRow(ctx, query, s.OrgId, s.ServiceId, s.Price, s.UserId, s.StartDate, endDate).Scan(&s.SubId)
+		if isConstraintViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"query":        "INSERT INTO subs",
+				"service_name": s.ServiceName,
+				"user_id":      s.UserId,
+			}).Error("Failed to create subscription")
+			return err
+		}
+
+		return r.writeAudit(ctx, auditCreate, s.SubId, nil, s)
+	})
 }
 
 func (r *subRepository) GetById(ctx context.Context, id int) (*model.Sub, error) {
@@ -60,16 +81,20 @@ func (r *subRepository) GetById(ctx context.Context, id int) (*model.Sub, error)
 	}).Debug("Getting by id")
 
 	var s model.Sub
-	query := `
-		SELECT sub_id, service_name, price, user_id, start_date, end_date
-		FROM subs WHERE sub_id = $1
-	`
-	row := r.pool.QueryRow(ctx, query, id)
-	err := row.Scan(&s.SubId, &s.ServiceName, &s.Price, &s.UserId, &s.StartDate, &s.EndDate)
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			SELECT ` + subColumns + `
+			FROM ` + subFrom + ` WHERE s.org_id = $1 AND s.sub_id = $2
+		`
+		return scanSub(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), id), &s)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		r.log.WithError(err).WithFields(logrus.Fields{
 			"query":  "SELECT FROM subs",
-			"sub_id": s.SubId,
+			"sub_id": id,
 		}).Error("Failed to get subscription")
 		return nil, err
 	}
@@ -81,20 +106,33 @@ func (r *subRepository) Update(ctx context.Context, s *model.Sub) error {
 		"sub_id": s.SubId,
 	}).Debug("Updating")
 
-	query := `
-		UPDATE subs
-		SET service_name=$1, price=$2, user_id=$3, start_date=$4, end_date=$5
-		WHERE sub_id=$6
-	`
+	return r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := r.lockById(ctx, s.SubId)
+		if err != nil {
+			return err
+		}
 
-	_, err := r.pool.Exec(ctx, query, s.ServiceName, s.Price, s.UserId, s.StartDate, s.EndDate, s.SubId)
-	if err != nil {
-		r.log.WithError(err).WithFields(logrus.Fields{
-			"query":  "UPDATE subs",
-			"sub_id": s.SubId,
-		}).Error("Failed to update subscription")
-	}
-	return err
+		s.OrgId = before.OrgId
+		query := `
+			UPDATE subs
+			SET service_id=$1, price=$2, user_id=$3, start_date=$4, end_date=$5
+			WHERE org_id=$6 AND sub_id=$7
+		`
+
+		_, err = conn(ctx, r.pool).Exec(ctx, query, s.ServiceId, s.Price, s.UserId, s.StartDate, s.EndDate, s.OrgId, s.SubId)
+		if isConstraintViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"query":  "UPDATE subs",
+				"sub_id": s.SubId,
+			}).Error("Failed to update subscription")
+			return err
+		}
+
+		return r.writeAudit(ctx, auditUpdate, s.SubId, before, s)
+	})
 }
 
 func (r *subRepository) Delete(ctx context.Context, id int) error {
@@ -102,28 +140,42 @@ func (r *subRepository) Delete(ctx context.Context, id int) error {
 		"sub_id": id,
 	}).Debug("Deleting")
 
-	query := `DELETE FROM subs WHERE sub_id=$1`
-	_, err := r.pool.Exec(ctx, query, id)
-	if err != nil {
-		r.log.WithError(err).WithFields(logrus.Fields{
-			"query":  "DELETE FROM subs",
-			"sub_id": id,
-		}).Error("Failed to delete subscription")
-	}
+	return r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := r.lockById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		query := `DELETE FROM subs WHERE org_id=$1 AND sub_id=$2`
+		_, err = conn(ctx, r.pool).Exec(ctx, query, before.OrgId, id)
+		if err != nil {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"query":  "DELETE FROM subs",
+				"sub_id": id,
+			}).Error("Failed to delete subscription")
+			return err
+		}
 
-	return err
+		return r.writeAudit(ctx, auditDelete, id, before, nil)
+	})
 }
 
-func (r *subRepository) List(ctx context.Context, limit, offset int) ([]model.Sub, error) {
+func (r *subRepository) List(ctx context.Context, filter model.SubFilter, limit, offset int) ([]model.Sub, error) {
 	r.log.WithFields(logrus.Fields{
-		"limit":  limit,
-		"offset": offset,
+		"user_id":      filter.UserId,
+		"service_name": filter.ServiceName,
+		"limit":        limit,
+		"offset":       offset,
 	}).Debug("Getting list")
 
-	rows, err := r.pool.Query(ctx, `
-        SELECT sub_id, service_name, price, user_id, start_date, end_date
-        FROM subs ORDER BY sub_id LIMIT $1 OFFSET $2
-    `, limit, offset)
+	where, args := subFilterWhere(reqctx.OrgId(ctx), filter)
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM %s %s ORDER BY s.sub_id LIMIT $%d OFFSET $%d
+    `, subColumns, subFrom, where, len(args)-1, len(args))
+
+	result, err := r.query(ctx, query, args...)
 	if err != nil {
 		r.log.WithError(err).WithFields(logrus.Fields{
 			"query":  "SELECT FROM subs",
@@ -133,60 +185,103 @@ func (r *subRepository) List(ctx context.Context, limit, offset int) ([]model.Su
 
 		return nil, err
 	}
-	defer rows.Close()
+	return result, nil
+}
 
-	var result []model.Sub
-	for rows.Next() {
-		var s model.Sub
-		err := rows.Scan(&s.SubId, &s.ServiceName, &s.Price, &s.UserId, &s.StartDate, &s.EndDate)
-		if err != nil {
-			r.log.WithError(err).Error("Failed to scan rows")
+func (r *subRepository) Count(ctx context.Context, filter model.SubFilter) (int, error) {
+	where, args := subFilterWhere(reqctx.OrgId(ctx), filter)
+	n, err := count(ctx, r.pool, r.txm, `SELECT count(*) FROM `+subFrom+` `+where, args...)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM subs",
+		}).Error("Failed to count subscriptions")
+	}
+	return n, err
+}
 
-			return nil, err
-		}
-		result = append(result, s)
+func (r *subRepository) CountActive(ctx context.Context, month time.Time) (int, error) {
+	n, err := count(ctx, r.pool, r.txm, `
+		SELECT count(*) FROM subs
+		WHERE org_id = $1 AND start_date <= $2 AND (end_date IS NULL OR end_date >= $2)`,
+		reqctx.OrgId(ctx), month)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM subs",
+		}).Error("Failed to count active subscriptions")
 	}
-	return result, nil
+	return n, err
 }
 
-func (r *subRepository) SumCost(ctx context.Context, userId, serviceName string) ([]model.Sub, error) {
+func (r *subRepository) SumCost(ctx context.Context, filter model.SubFilter) ([]model.Sub, error) {
 	r.log.WithFields(logrus.Fields{
-		"user_id":      userId,
-		"service_name": serviceName,
+		"user_id":      filter.UserId,
+		"service_name": filter.ServiceName,
 	}).Debug("Getting sum")
 
-	base := `SELECT sub_id, service_name, price, user_id, start_date, end_date FROM subs WHERE 1=1`
-	args := []interface{}{}
-	i := 1
-	if userId != "" {
-		base += fmt.Sprintf(" AND user_id = $%d", i)
-		args = append(args, userId)
-		i++
-	}
-	if serviceName != "" {
-		base += fmt.Sprintf(" AND service_name = $%d", i)
-		args = append(args, serviceName)
-		i++
-	}
+	where, args := subFilterWhere(reqctx.OrgId(ctx), filter)
+	base := `SELECT ` + subColumns + ` FROM ` + subFrom + ` ` + where
 
-	rows, err := r.pool.Query(ctx, base, args...)
+	result, err := r.query(ctx, base, args...)
 	if err != nil {
 		r.log.WithError(err).Error("Failed to get list for SumCost")
 
 		return nil, err
 	}
-	defer rows.Close()
+	return result, nil
+}
+
+// subs are always read together with the name of their service
+const (
+	subColumns = `s.sub_id, s.org_id, s.service_id, sv.name, s.price, s.user_id, s.start_date, s.end_date`
+	subFrom    = `subs s JOIN services sv ON sv.service_id = s.service_id`
+)
+
+func scanSub(row pgx.Row, s *model.Sub) error {
+	return row.Scan(&s.SubId, &s.OrgId, &s.ServiceId, &s.ServiceName, &s.Price, &s.UserId, &s.StartDate, &s.EndDate)
+}
 
+// query runs a SELECT of subColumns in a transaction, so that row-level security applies
+func (r *subRepository) query(ctx context.Context, query string, args ...any) ([]model.Sub, error) {
 	var result []model.Sub
-	for rows.Next() {
-		var s model.Sub
-		err := rows.Scan(&s.SubId, &s.ServiceName, &s.Price, &s.UserId, &s.StartDate, &s.EndDate)
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, query, args...)
 		if err != nil {
-			r.log.WithError(err).Error("Failed to scan rows for SumCost")
+			return err
+		}
+		defer rows.Close()
 
-			return nil, err
+		for rows.Next() {
+			var s model.Sub
+			if err := scanSub(rows, &s); err != nil {
+				return err
+			}
+			result = append(result, s)
 		}
-		result = append(result, s)
+		return rows.Err()
+	})
+	return result, err
+}
+
+// subFilterWhere builds a WHERE clause for filter within the organization,
+// with positional args from $1
+func subFilterWhere(orgId string, filter model.SubFilter) (string, []any) {
+	where := `WHERE s.org_id = $1`
+	args := []any{orgId}
+	if filter.UserId != "" {
+		args = append(args, filter.UserId)
+		where += fmt.Sprintf(" AND s.user_id = $%d", len(args))
 	}
-	return result, nil
+	if filter.UserIds != nil {
+		args = append(args, filter.UserIds)
+		where += fmt.Sprintf(" AND s.user_id::text = ANY($%d)", len(args))
+	}
+	if filter.ServiceId != 0 {
+		args = append(args, filter.ServiceId)
+		where += fmt.Sprintf(" AND s.service_id = $%d", len(args))
+	}
+	if filter.ServiceName != "" {
+		args = append(args, serviceNameKey(filter.ServiceName))
+		where += fmt.Sprintf(" AND sv.name_key = $%d", len(args))
+	}
+	return where, args
 }
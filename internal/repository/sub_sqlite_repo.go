@@ -0,0 +1,354 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type sqliteSubRepository struct {
+	db  *sql.DB
+	txm TxManager
+	log *logrus.Logger
+}
+
+func NewSQLiteSubRepository(db *sql.DB, log *logrus.Logger) SubRepository {
+	return &sqliteSubRepository{db: db, txm: NewSQLiteTxManager(db, log), log: log}
+}
+
+// subs are always read together with the name of their service
+const (
+	sqliteSubColumns = `s.sub_id, s.org_id, s.service_id, sv.name, s.price, s.user_id, s.start_date, s.end_date`
+	sqliteSubFrom    = `subs s JOIN services sv ON sv.service_id = s.service_id`
+)
+
+type sqliteRowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSQLiteSub(row sqliteRowScanner) (model.Sub, error) {
+	var (
+		s         model.Sub
+		startDate string
+		endDate   sql.NullString
+	)
+	if err := row.Scan(&s.SubId, &s.OrgId, &s.ServiceId, &s.ServiceName, &s.Price, &s.UserId, &startDate, &endDate); err != nil {
+		return s, err
+	}
+
+	var err error
+	if s.StartDate, err = parseDate(startDate); err != nil {
+		return s, err
+	}
+	if s.EndDate, err = parseNullDate(endDate); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+func (r *sqliteSubRepository) Create(ctx context.Context, s *model.Sub) error {
+	r.log.WithFields(logrus.Fields{
+		"service_name": s.ServiceName,
+		"user_id":      s.UserId,
+	}).Debug("Creating new subscription")
+
+	s.OrgId = reqctx.OrgId(ctx)
+
+	return r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO subs (org_id, service_id, price, user_id, start_date, end_date)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`
+		res, err := sqlConn(ctx, r.db).ExecContext(ctx, query,
+			s.OrgId, s.ServiceId, s.Price, s.UserId, formatDate(s.StartDate), formatNullDate(s.EndDate))
+		if isSQLiteConstraintViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"query":        "INSERT INTO subs",
+				"service_name": s.ServiceName,
+				"user_id":      s.UserId,
+			}).Error("Failed to create subscription")
+			return err
+		}
+
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		s.SubId = int(id)
+
+		return r.writeAudit(ctx, auditCreate, s.SubId, nil, s)
+	})
+}
+
+func (r *sqliteSubRepository) GetById(ctx context.Context, id int) (*model.Sub, error) {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": id,
+	}).Debug("Getting by id")
+
+	query := `SELECT ` + sqliteSubColumns + ` FROM ` + sqliteSubFrom + ` WHERE s.org_id = ? AND s.sub_id = ?`
+	s, err := scanSQLiteSub(sqlConn(ctx, r.db).QueryRowContext(ctx, query, reqctx.OrgId(ctx), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "SELECT FROM subs",
+			"sub_id": id,
+		}).Error("Failed to get subscription")
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *sqliteSubRepository) Update(ctx context.Context, s *model.Sub) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": s.SubId,
+	}).Debug("Updating")
+
+	return r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := r.GetById(ctx, s.SubId)
+		if err != nil {
+			return err
+		}
+
+		s.OrgId = before.OrgId
+		query := `
+			UPDATE subs
+			SET service_id=?, price=?, user_id=?, start_date=?, end_date=?
+			WHERE org_id=? AND sub_id=?
+		`
+		_, err = sqlConn(ctx, r.db).ExecContext(ctx, query,
+			s.ServiceId, s.Price, s.UserId, formatDate(s.StartDate), formatNullDate(s.EndDate), s.OrgId, s.SubId)
+		if isSQLiteConstraintViolation(err) {
+			return ErrConflict
+		}
+		if err != nil {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"query":  "UPDATE subs",
+				"sub_id": s.SubId,
+			}).Error("Failed to update subscription")
+			return err
+		}
+
+		return r.writeAudit(ctx, auditUpdate, s.SubId, before, s)
+	})
+}
+
+func (r *sqliteSubRepository) Delete(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": id,
+	}).Debug("Deleting")
+
+	return r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := r.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = sqlConn(ctx, r.db).ExecContext(ctx, `DELETE FROM subs WHERE org_id=? AND sub_id=?`, before.OrgId, id)
+		if err != nil {
+			r.log.WithError(err).WithFields(logrus.Fields{
+				"query":  "DELETE FROM subs",
+				"sub_id": id,
+			}).Error("Failed to delete subscription")
+			return err
+		}
+
+		return r.writeAudit(ctx, auditDelete, id, before, nil)
+	})
+}
+
+func (r *sqliteSubRepository) List(ctx context.Context, filter model.SubFilter, limit, offset int) ([]model.Sub, error) {
+	r.log.WithFields(logrus.Fields{
+		"user_id":      filter.UserId,
+		"service_name": filter.ServiceName,
+		"limit":        limit,
+		"offset":       offset,
+	}).Debug("Getting list")
+
+	where, args := sqliteSubFilterWhere(reqctx.OrgId(ctx), filter)
+	query := `SELECT ` + sqliteSubColumns + ` FROM ` + sqliteSubFrom + where + ` ORDER BY s.sub_id LIMIT ? OFFSET ?`
+	subs, err := r.query(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "SELECT FROM subs",
+			"limit":  limit,
+			"offset": offset,
+		}).Error("Failed to get list")
+	}
+	return subs, err
+}
+
+func (r *sqliteSubRepository) Count(ctx context.Context, filter model.SubFilter) (int, error) {
+	where, args := sqliteSubFilterWhere(reqctx.OrgId(ctx), filter)
+	n, err := sqliteCount(ctx, r.db, `SELECT count(*) FROM `+sqliteSubFrom+where, args...)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM subs",
+		}).Error("Failed to count subscriptions")
+	}
+	return n, err
+}
+
+func (r *sqliteSubRepository) CountActive(ctx context.Context, month time.Time) (int, error) {
+	m := formatDate(month)
+	n, err := sqliteCount(ctx, r.db, `
+		SELECT count(*) FROM subs
+		WHERE org_id = ? AND start_date <= ? AND (end_date IS NULL OR end_date >= ?)`,
+		reqctx.OrgId(ctx), m, m)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM subs",
+		}).Error("Failed to count active subscriptions")
+	}
+	return n, err
+}
+
+func (r *sqliteSubRepository) SumCost(ctx context.Context, filter model.SubFilter) ([]model.Sub, error) {
+	r.log.WithFields(logrus.Fields{
+		"user_id":      filter.UserId,
+		"service_name": filter.ServiceName,
+	}).Debug("Getting sum")
+
+	where, args := sqliteSubFilterWhere(reqctx.OrgId(ctx), filter)
+	query := `SELECT ` + sqliteSubColumns + ` FROM ` + sqliteSubFrom + where
+
+	subs, err := r.query(ctx, query, args...)
+	if err != nil {
+		r.log.WithError(err).Error("Failed to get list for SumCost")
+	}
+	return subs, err
+}
+
+// sqliteSubFilterWhere builds a WHERE clause for filter within the organization
+func sqliteSubFilterWhere(orgId string, filter model.SubFilter) (string, []any) {
+	where := []string{"s.org_id = ?"}
+	args := []any{orgId}
+	if filter.UserId != "" {
+		where = append(where, "s.user_id = ?")
+		args = append(args, filter.UserId)
+	}
+	if filter.UserIds != nil {
+		// IN () is valid in SQLite and matches nothing
+		where = append(where, "s.user_id IN ("+strings.TrimSuffix(strings.Repeat("?,", len(filter.UserIds)), ",")+")")
+		for _, id := range filter.UserIds {
+			args = append(args, id)
+		}
+	}
+	if filter.ServiceId != 0 {
+		where = append(where, "s.service_id = ?")
+		args = append(args, filter.ServiceId)
+	}
+	if filter.ServiceName != "" {
+		where = append(where, "sv.name_key = ?")
+		args = append(args, serviceNameKey(filter.ServiceName))
+	}
+	return ` WHERE ` + strings.Join(where, " AND "), args
+}
+
+func (r *sqliteSubRepository) query(ctx context.Context, query string, args ...any) ([]model.Sub, error) {
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.Sub
+	for rows.Next() {
+		s, err := scanSQLiteSub(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+func (r *sqliteSubRepository) History(ctx context.Context, id int) ([]model.SubAudit, error) {
+	r.log.WithFields(logrus.Fields{
+		"sub_id": id,
+	}).Debug("Getting history")
+
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT audit_id, sub_id, org_id, action, before_state, after_state, actor, request_id, changed_at
+		FROM sub_audit WHERE org_id = ? AND sub_id = ? ORDER BY audit_id
+	`, reqctx.OrgId(ctx), id)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "SELECT FROM sub_audit",
+			"sub_id": id,
+		}).Error("Failed to get history")
+
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.SubAudit
+	for rows.Next() {
+		var (
+			a             model.SubAudit
+			before, after sql.NullString
+			changedAt     string
+		)
+		err := rows.Scan(&a.AuditId, &a.SubId, &a.OrgId, &a.Action, &before, &after, &a.Actor, &a.RequestId, &changedAt)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for history")
+
+			return nil, err
+		}
+		if before.Valid {
+			a.Before = []byte(before.String)
+		}
+		if after.Valid {
+			a.After = []byte(after.String)
+		}
+		if a.ChangedAt, err = time.Parse(time.RFC3339Nano, changedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+func (r *sqliteSubRepository) writeAudit(ctx context.Context, action string, subId int, before, after *model.Sub) error {
+	beforeJSON, err := marshalState(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalState(after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO sub_audit (org_id, sub_id, action, before_state, after_state, actor, request_id, changed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err = sqlConn(ctx, r.db).ExecContext(ctx, query, reqctx.OrgId(ctx), subId, action,
+		nullJSON(beforeJSON), nullJSON(afterJSON), reqctx.Actor(ctx), reqctx.RequestId(ctx),
+		formatTimestamp(time.Now()))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "INSERT INTO sub_audit",
+			"sub_id": subId,
+			"action": action,
+		}).Error("Failed to write audit record")
+	}
+	return err
+}
+
+func nullJSON(b []byte) sql.NullString {
+	if b == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(b), Valid: true}
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means a write violates a uniqueness or reference constraint
+	ErrConflict = errors.New("conflict")
+)
+
+// TxManager runs several repository calls as one unit of work.
+// Repositories pick the transaction up from the context passed to fn.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// querier is implemented by both *pgxpool.Pool and pgx.Tx
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type pgTxManager struct {
+	pool *pgxpool.Pool
+	log  *logrus.Logger
+}
+
+func NewTxManager(pool *pgxpool.Pool, log *logrus.Logger) TxManager {
+	return &pgTxManager{pool: pool, log: log}
+}
+
+func (m *pgTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	// Nested calls join the outer transaction
+	if _, ok := txFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		m.log.WithError(err).Error("Failed to begin transaction")
+		return err
+	}
+
+	// Row-level security policies only let the transaction see rows of this organization
+	if _, err = tx.Exec(ctx, `SELECT set_config('app.org_id', $1, true)`, reqctx.OrgId(ctx)); err != nil {
+		m.log.WithError(err).Error("Failed to set organization for transaction")
+		m.rollback(ctx, tx)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			m.rollback(ctx, tx)
+			panic(p)
+		}
+		if err != nil {
+			m.rollback(ctx, tx)
+			return
+		}
+		if err = tx.Commit(ctx); err != nil {
+			m.log.WithError(err).Error("Failed to commit transaction")
+		}
+	}()
+
+	return fn(context.WithValue(ctx, txKey{}, tx))
+}
+
+func (m *pgTxManager) rollback(ctx context.Context, tx pgx.Tx) {
+	// Rollback must happen even if ctx is already cancelled
+	if err := tx.Rollback(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		m.log.WithError(err).Error("Failed to rollback transaction")
+	}
+}
+
+func txFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// conn returns the transaction bound to ctx, or the pool if there is none
+func conn(ctx context.Context, pool *pgxpool.Pool) querier {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx
+	}
+	return pool
+}
+
+// count runs a SELECT count(*) in a transaction, so that row-level security applies
+func count(ctx context.Context, pool *pgxpool.Pool, txm TxManager, query string, args ...any) (int, error) {
+	var n int
+	err := txm.WithinTx(ctx, func(ctx context.Context) error {
+		return conn(ctx, pool).QueryRow(ctx, query, args...).Scan(&n)
+	})
+	return n, err
+}
+
+// isConstraintViolation reports unique and foreign key violations
+func isConstraintViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "23505" || pgErr.Code == "23503")
+}
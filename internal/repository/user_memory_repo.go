@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type memoryUserRepository struct {
+	db  *MemoryDB
+	log *logrus.Logger
+}
+
+func NewMemoryUserRepository(db *MemoryDB, log *logrus.Logger) UserRepository {
+	return &memoryUserRepository{db: db, log: log}
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, u *model.User) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": u.UserId,
+	}).Debug("Creating user")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := memoryUserKey{orgId: reqctx.OrgId(ctx), userId: u.UserId}
+	if _, ok := r.db.users[key]; ok {
+		return ErrConflict
+	}
+	u.OrgId = key.orgId
+	u.CreatedAt = time.Now().UTC()
+	r.db.users[key] = *u
+	return nil
+}
+
+func (r *memoryUserRepository) Ensure(ctx context.Context, id string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := memoryUserKey{orgId: reqctx.OrgId(ctx), userId: id}
+	if _, ok := r.db.users[key]; !ok {
+		r.db.users[key] = model.User{UserId: id, OrgId: key.orgId, CreatedAt: time.Now().UTC()}
+	}
+	return nil
+}
+
+// Lock does nothing, memory transactions are serialized
+func (r *memoryUserRepository) Lock(context.Context, string) error {
+	return nil
+}
+
+func (r *memoryUserRepository) GetById(ctx context.Context, id string) (*model.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	u, ok := r.db.users[memoryUserKey{orgId: reqctx.OrgId(ctx), userId: id}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (r *memoryUserRepository) GetByIds(ctx context.Context, ids []string) ([]model.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var result []model.User
+	for _, id := range ids {
+		if u, ok := r.db.users[memoryUserKey{orgId: orgId, userId: id}]; ok {
+			result = append(result, u)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserId < result[j].UserId })
+	return slices.CompactFunc(result, func(a, b model.User) bool { return a.UserId == b.UserId }), nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, u *model.User) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": u.UserId,
+	}).Debug("Updating user")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := memoryUserKey{orgId: reqctx.OrgId(ctx), userId: u.UserId}
+	stored, ok := r.db.users[key]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Name = u.Name
+	r.db.users[key] = stored
+	*u = stored
+	return nil
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, id string) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": id,
+	}).Debug("Deleting user")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := memoryUserKey{orgId: reqctx.OrgId(ctx), userId: id}
+	if _, ok := r.db.users[key]; !ok {
+		return ErrNotFound
+	}
+	for _, s := range r.db.subs {
+		if s.OrgId == key.orgId && s.UserId == id {
+			return ErrConflict
+		}
+	}
+	delete(r.db.users, key)
+	return nil
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, limit, offset int) ([]model.User, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var result []model.User
+	for key, u := range r.db.users {
+		if key.orgId == orgId {
+			result = append(result, u)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].UserId < result[j].UserId })
+	return page(result, limit, offset), nil
+}
+
+func (r *memoryUserRepository) Count(ctx context.Context) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	n := 0
+	for key := range r.db.users {
+		if key.orgId == orgId {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// page returns the part of items a LIMIT/OFFSET query would
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+// UserRepository only sees users of the organization from reqctx.OrgId
+type UserRepository interface {
+	// Create returns ErrConflict if the user already exists
+	Create(ctx context.Context, u *model.User) error
+	// Ensure creates the user with an empty name unless it exists
+	Ensure(ctx context.Context, id string) error
+	// Lock holds the user until the transaction ends, so checks of the
+	// user's subscriptions don't race with concurrent writes of them
+	Lock(ctx context.Context, id string) error
+	GetById(ctx context.Context, id string) (*model.User, error)
+	// GetByIds returns the users that exist out of ids, ordered by id
+	GetByIds(ctx context.Context, ids []string) ([]model.User, error)
+	Update(ctx context.Context, u *model.User) error
+	// Delete returns ErrConflict while the user has subscriptions
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, limit, offset int) ([]model.User, error)
+	Count(ctx context.Context) (int, error)
+}
+
+type userRepository struct {
+	pool *pgxpool.Pool
+	txm  TxManager
+	log  *logrus.Logger
+}
+
+func NewUserRepository(pool *pgxpool.Pool, log *logrus.Logger) UserRepository {
+	return &userRepository{pool: pool, txm: NewTxManager(pool, log), log: log}
+}
+
+func (r *userRepository) Create(ctx context.Context, u *model.User) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": u.UserId,
+	}).Debug("Creating user")
+
+	u.OrgId = reqctx.OrgId(ctx)
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO users (org_id, user_id, name)
+			VALUES ($1, $2, $3)
+			RETURNING created_at
+		`
+		return conn(ctx, r.pool).QueryRow(ctx, query, u.OrgId, u.UserId, u.Name).Scan(&u.CreatedAt)
+	})
+	if isConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "INSERT INTO users",
+			"user_id": u.UserId,
+		}).Error("Failed to create user")
+	}
+	return err
+}
+
+func (r *userRepository) Ensure(ctx context.Context, id string) error {
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `INSERT INTO users (org_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		_, err := conn(ctx, r.pool).Exec(ctx, query, reqctx.OrgId(ctx), id)
+		return err
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "INSERT INTO users ON CONFLICT",
+			"user_id": id,
+		}).Error("Failed to ensure user")
+	}
+	return err
+}
+
+func (r *userRepository) Lock(ctx context.Context, id string) error {
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `SELECT 1 FROM users WHERE org_id = $1 AND user_id = $2 FOR UPDATE`
+		_, err := conn(ctx, r.pool).Exec(ctx, query, reqctx.OrgId(ctx), id)
+		return err
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "SELECT FROM users FOR UPDATE",
+			"user_id": id,
+		}).Error("Failed to lock user")
+	}
+	return err
+}
+
+func (r *userRepository) GetById(ctx context.Context, id string) (*model.User, error) {
+	var u model.User
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `SELECT user_id, org_id, name, created_at FROM users WHERE org_id = $1 AND user_id = $2`
+		return conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), id).Scan(&u.UserId, &u.OrgId, &u.Name, &u.CreatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "SELECT FROM users",
+			"user_id": id,
+		}).Error("Failed to get user")
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepository) GetByIds(ctx context.Context, ids []string) ([]model.User, error) {
+	var result []model.User
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			SELECT user_id, org_id, name, created_at
+			FROM users WHERE org_id = $1 AND user_id::text = ANY($2) ORDER BY user_id
+		`, reqctx.OrgId(ctx), ids)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var u model.User
+			if err := rows.Scan(&u.UserId, &u.OrgId, &u.Name, &u.CreatedAt); err != nil {
+				return err
+			}
+			result = append(result, u)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM users",
+			"users": len(ids),
+		}).Error("Failed to get users")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, u *model.User) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": u.UserId,
+	}).Debug("Updating user")
+
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `UPDATE users SET name = $1 WHERE org_id = $2 AND user_id = $3 RETURNING org_id, created_at`
+		return conn(ctx, r.pool).QueryRow(ctx, query, u.Name, reqctx.OrgId(ctx), u.UserId).Scan(&u.OrgId, &u.CreatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "UPDATE users",
+			"user_id": u.UserId,
+		}).Error("Failed to update user")
+	}
+	return err
+}
+
+func (r *userRepository) Delete(ctx context.Context, id string) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": id,
+	}).Debug("Deleting user")
+
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM users WHERE org_id = $1 AND user_id = $2`, reqctx.OrgId(ctx), id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if isConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "DELETE FROM users",
+			"user_id": id,
+		}).Error("Failed to delete user")
+	}
+	return err
+}
+
+func (r *userRepository) List(ctx context.Context, limit, offset int) ([]model.User, error) {
+	var result []model.User
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			SELECT user_id, org_id, name, created_at
+			FROM users WHERE org_id = $1 ORDER BY user_id LIMIT $2 OFFSET $3
+		`, reqctx.OrgId(ctx), limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var u model.User
+			if err := rows.Scan(&u.UserId, &u.OrgId, &u.Name, &u.CreatedAt); err != nil {
+				return err
+			}
+			result = append(result, u)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM users",
+		}).Error("Failed to list users")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *userRepository) Count(ctx context.Context) (int, error) {
+	n, err := count(ctx, r.pool, r.txm, `SELECT count(*) FROM users WHERE org_id = $1`, reqctx.OrgId(ctx))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM users",
+		}).Error("Failed to count users")
+	}
+	return n, err
+}
@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type sqliteUserRepository struct {
+	db  *sql.DB
+	log *logrus.Logger
+}
+
+func NewSQLiteUserRepository(db *sql.DB, log *logrus.Logger) UserRepository {
+	return &sqliteUserRepository{db: db, log: log}
+}
+
+const sqliteUserColumns = `user_id, org_id, name, created_at`
+
+func scanSQLiteUser(row sqliteRowScanner) (model.User, error) {
+	var (
+		u         model.User
+		createdAt string
+	)
+	if err := row.Scan(&u.UserId, &u.OrgId, &u.Name, &createdAt); err != nil {
+		return u, err
+	}
+	var err error
+	u.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	return u, err
+}
+
+func (r *sqliteUserRepository) Create(ctx context.Context, u *model.User) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": u.UserId,
+	}).Debug("Creating user")
+
+	u.OrgId = reqctx.OrgId(ctx)
+	u.CreatedAt = time.Now().UTC()
+	query := `INSERT INTO users (org_id, user_id, name, created_at) VALUES (?, ?, ?, ?)`
+	_, err := sqlConn(ctx, r.db).ExecContext(ctx, query, u.OrgId, u.UserId, u.Name, formatTimestamp(u.CreatedAt))
+	if isSQLiteConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "INSERT INTO users",
+			"user_id": u.UserId,
+		}).Error("Failed to create user")
+	}
+	return err
+}
+
+func (r *sqliteUserRepository) Ensure(ctx context.Context, id string) error {
+	query := `INSERT OR IGNORE INTO users (org_id, user_id, created_at) VALUES (?, ?, ?)`
+	_, err := sqlConn(ctx, r.db).ExecContext(ctx, query, reqctx.OrgId(ctx), id, formatTimestamp(time.Now()))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "INSERT OR IGNORE INTO users",
+			"user_id": id,
+		}).Error("Failed to ensure user")
+	}
+	return err
+}
+
+// Lock does nothing, the only connection serializes transactions
+func (r *sqliteUserRepository) Lock(context.Context, string) error {
+	return nil
+}
+
+func (r *sqliteUserRepository) GetById(ctx context.Context, id string) (*model.User, error) {
+	query := `SELECT ` + sqliteUserColumns + ` FROM users WHERE org_id = ? AND user_id = ?`
+	u, err := scanSQLiteUser(sqlConn(ctx, r.db).QueryRowContext(ctx, query, reqctx.OrgId(ctx), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "SELECT FROM users",
+			"user_id": id,
+		}).Error("Failed to get user")
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *sqliteUserRepository) GetByIds(ctx context.Context, ids []string) ([]model.User, error) {
+	args := []any{reqctx.OrgId(ctx)}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	// IN () is valid in SQLite and matches nothing
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+sqliteUserColumns+`
+		FROM users WHERE org_id = ? AND user_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")+`)
+		ORDER BY user_id
+	`, args...)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM users",
+			"users": len(ids),
+		}).Error("Failed to get users")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.User
+	for rows.Next() {
+		u, err := scanSQLiteUser(rows)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for users")
+			return nil, err
+		}
+		result = append(result, u)
+	}
+	return result, rows.Err()
+}
+
+func (r *sqliteUserRepository) Update(ctx context.Context, u *model.User) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": u.UserId,
+	}).Debug("Updating user")
+
+	query := `UPDATE users SET name = ? WHERE org_id = ? AND user_id = ? RETURNING ` + sqliteUserColumns
+	updated, err := scanSQLiteUser(sqlConn(ctx, r.db).QueryRowContext(ctx, query, u.Name, reqctx.OrgId(ctx), u.UserId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "UPDATE users",
+			"user_id": u.UserId,
+		}).Error("Failed to update user")
+		return err
+	}
+	*u = updated
+	return nil
+}
+
+func (r *sqliteUserRepository) Delete(ctx context.Context, id string) error {
+	r.log.WithFields(logrus.Fields{
+		"user_id": id,
+	}).Debug("Deleting user")
+
+	res, err := sqlConn(ctx, r.db).ExecContext(ctx, `DELETE FROM users WHERE org_id = ? AND user_id = ?`, reqctx.OrgId(ctx), id)
+	if isSQLiteConstraintViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":   "DELETE FROM users",
+			"user_id": id,
+		}).Error("Failed to delete user")
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteUserRepository) List(ctx context.Context, limit, offset int) ([]model.User, error) {
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+sqliteUserColumns+`
+		FROM users WHERE org_id = ? ORDER BY user_id LIMIT ? OFFSET ?
+	`, reqctx.OrgId(ctx), limit, offset)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM users",
+		}).Error("Failed to list users")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.User
+	for rows.Next() {
+		u, err := scanSQLiteUser(rows)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for users")
+			return nil, err
+		}
+		result = append(result, u)
+	}
+	return result, rows.Err()
+}
+
+func (r *sqliteUserRepository) Count(ctx context.Context) (int, error) {
+	n, err := sqliteCount(ctx, r.db, `SELECT count(*) FROM users WHERE org_id = ?`, reqctx.OrgId(ctx))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM users",
+		}).Error("Failed to count users")
+	}
+	return n, err
+}
@@ -0,0 +1,256 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type memoryWebhookEvent struct {
+	model.WebhookEvent
+	dispatched bool
+}
+
+type memoryWebhookDelivery struct {
+	model.WebhookDelivery
+	orgId string
+}
+
+type memoryWebhookRepository struct {
+	db  *MemoryDB
+	log *logrus.Logger
+}
+
+func NewMemoryWebhookRepository(db *MemoryDB, log *logrus.Logger) WebhookRepository {
+	return &memoryWebhookRepository{db: db, log: log}
+}
+
+func (r *memoryWebhookRepository) CreateEndpoint(ctx context.Context, e *model.WebhookEndpoint) error {
+	r.log.WithFields(logrus.Fields{
+		"url": e.Url,
+	}).Debug("Creating webhook endpoint")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if e.Events == nil {
+		e.Events = []string{}
+	}
+	e.EndpointId = r.db.nextEndpointId
+	e.OrgId = reqctx.OrgId(ctx)
+	e.CreatedAt = time.Now().UTC()
+	r.db.nextEndpointId++
+	r.db.webhookEndpoints[e.EndpointId] = *e
+	return nil
+}
+
+func (r *memoryWebhookRepository) GetEndpoint(ctx context.Context, id int) (*model.WebhookEndpoint, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	e, ok := r.db.webhookEndpoints[id]
+	if !ok || e.OrgId != reqctx.OrgId(ctx) {
+		return nil, ErrNotFound
+	}
+	return &e, nil
+}
+
+func (r *memoryWebhookRepository) ListEndpoints(ctx context.Context, limit, offset int) ([]model.WebhookEndpoint, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var result []model.WebhookEndpoint
+	for _, e := range r.db.webhookEndpoints {
+		if e.OrgId == orgId {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].EndpointId < result[j].EndpointId })
+	return page(result, limit, offset), nil
+}
+
+func (r *memoryWebhookRepository) CountEndpoints(ctx context.Context) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	n := 0
+	for _, e := range r.db.webhookEndpoints {
+		if e.OrgId == orgId {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *memoryWebhookRepository) DeleteEndpoint(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"endpoint_id": id,
+	}).Debug("Deleting webhook endpoint")
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	e, ok := r.db.webhookEndpoints[id]
+	if !ok || e.OrgId != reqctx.OrgId(ctx) {
+		return ErrNotFound
+	}
+	delete(r.db.webhookEndpoints, id)
+	for deliveryId, d := range r.db.webhookDeliveries {
+		if d.EndpointId == id {
+			delete(r.db.webhookDeliveries, deliveryId)
+		}
+	}
+	return nil
+}
+
+func (r *memoryWebhookRepository) Enqueue(ctx context.Context, e *model.WebhookEvent) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	e.EventId = r.db.nextEventId
+	e.OrgId = reqctx.OrgId(ctx)
+	e.CreatedAt = time.Now().UTC()
+	r.db.nextEventId++
+	r.db.webhookOutbox[e.EventId] = memoryWebhookEvent{WebhookEvent: *e}
+	return nil
+}
+
+func (r *memoryWebhookRepository) Undispatched(ctx context.Context, limit int) ([]model.WebhookEvent, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var result []model.WebhookEvent
+	for _, e := range r.db.webhookOutbox {
+		if e.OrgId == orgId && !e.dispatched {
+			result = append(result, e.WebhookEvent)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].EventId < result[j].EventId })
+	return page(result, limit, 0), nil
+}
+
+func (r *memoryWebhookRepository) GetEvent(ctx context.Context, id int64) (*model.WebhookEvent, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	e, ok := r.db.webhookOutbox[id]
+	if !ok || e.OrgId != reqctx.OrgId(ctx) {
+		return nil, ErrNotFound
+	}
+	return &e.WebhookEvent, nil
+}
+
+func (r *memoryWebhookRepository) Dispatch(ctx context.Context, eventId int64, endpointIds []int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	orgId := reqctx.OrgId(ctx)
+	e, ok := r.db.webhookOutbox[eventId]
+	if !ok || e.OrgId != orgId || e.dispatched {
+		return ErrConflict
+	}
+	for _, id := range endpointIds {
+		if endpoint, ok := r.db.webhookEndpoints[id]; !ok || endpoint.OrgId != orgId {
+			return ErrConflict
+		}
+	}
+
+	now := time.Now().UTC()
+	e.dispatched = true
+	r.db.webhookOutbox[eventId] = e
+	for _, id := range endpointIds {
+		d := model.WebhookDelivery{
+			DeliveryId:    r.db.nextDeliveryId,
+			EventId:       eventId,
+			EndpointId:    id,
+			EventType:     e.Type,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		r.db.nextDeliveryId++
+		r.db.webhookDeliveries[d.DeliveryId] = memoryWebhookDelivery{WebhookDelivery: d, orgId: orgId}
+	}
+	return nil
+}
+
+func (r *memoryWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var due []model.WebhookDelivery
+	for _, d := range r.db.webhookDeliveries {
+		if d.orgId == orgId && d.Status == model.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d.WebhookDelivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+		}
+		return due[i].DeliveryId < due[j].DeliveryId
+	})
+	due = page(due, limit, 0)
+
+	until := now.Add(lease).UTC()
+	for i := range due {
+		due[i].NextAttemptAt = until
+		r.db.webhookDeliveries[due[i].DeliveryId] = memoryWebhookDelivery{WebhookDelivery: due[i], orgId: orgId}
+	}
+	return due, nil
+}
+
+func (r *memoryWebhookRepository) SaveAttempt(ctx context.Context, d *model.WebhookDelivery) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.webhookDeliveries[d.DeliveryId]
+	if !ok || stored.orgId != reqctx.OrgId(ctx) {
+		return ErrNotFound
+	}
+	stored.Status = d.Status
+	stored.Attempts = d.Attempts
+	stored.ResponseStatus = d.ResponseStatus
+	stored.LastError = d.LastError
+	stored.NextAttemptAt = d.NextAttemptAt.UTC()
+	stored.DeliveredAt = d.DeliveredAt
+	r.db.webhookDeliveries[d.DeliveryId] = stored
+	return nil
+}
+
+func (r *memoryWebhookRepository) ListDeliveries(ctx context.Context, endpointId int, limit, offset int) ([]model.WebhookDelivery, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	var result []model.WebhookDelivery
+	for _, d := range r.db.webhookDeliveries {
+		if d.orgId == orgId && d.EndpointId == endpointId {
+			result = append(result, d.WebhookDelivery)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DeliveryId > result[j].DeliveryId })
+	return page(result, limit, offset), nil
+}
+
+func (r *memoryWebhookRepository) CountDeliveries(ctx context.Context, endpointId int) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	orgId := reqctx.OrgId(ctx)
+	n := 0
+	for _, d := range r.db.webhookDeliveries {
+		if d.orgId == orgId && d.EndpointId == endpointId {
+			n++
+		}
+	}
+	return n, nil
+}
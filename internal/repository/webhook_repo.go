@@ -0,0 +1,398 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+// WebhookRepository keeps webhook endpoints, the outbox of subscription
+// events and deliveries of events to endpoints. It only sees the
+// organization from reqctx.OrgId.
+type WebhookRepository interface {
+	CreateEndpoint(ctx context.Context, e *model.WebhookEndpoint) error
+	GetEndpoint(ctx context.Context, id int) (*model.WebhookEndpoint, error)
+	ListEndpoints(ctx context.Context, limit, offset int) ([]model.WebhookEndpoint, error)
+	CountEndpoints(ctx context.Context) (int, error)
+	// DeleteEndpoint also deletes deliveries to the endpoint
+	DeleteEndpoint(ctx context.Context, id int) error
+
+	// Enqueue writes e to the outbox. Called within the transaction of the
+	// change e describes, so that a rolled back change has no event.
+	Enqueue(ctx context.Context, e *model.WebhookEvent) error
+	// Undispatched returns up to limit events not fanned out yet, oldest first
+	Undispatched(ctx context.Context, limit int) ([]model.WebhookEvent, error)
+	GetEvent(ctx context.Context, id int64) (*model.WebhookEvent, error)
+	// Dispatch marks an event fanned out and adds a pending delivery of it
+	// to every endpoint. Returns ErrConflict if it has been dispatched before.
+	Dispatch(ctx context.Context, eventId int64, endpointIds []int) error
+
+	// ClaimDue returns up to limit pending deliveries due at now and
+	// postpones them until now+lease, so that two dispatchers don't send
+	// the same delivery at once
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error)
+	// SaveAttempt stores the outcome of an attempt: Status, Attempts,
+	// ResponseStatus, LastError, NextAttemptAt and DeliveredAt
+	SaveAttempt(ctx context.Context, d *model.WebhookDelivery) error
+	// ListDeliveries is the delivery log of an endpoint, newest first
+	ListDeliveries(ctx context.Context, endpointId int, limit, offset int) ([]model.WebhookDelivery, error)
+	CountDeliveries(ctx context.Context, endpointId int) (int, error)
+}
+
+type webhookRepository struct {
+	pool *pgxpool.Pool
+	txm  TxManager
+	log  *logrus.Logger
+}
+
+func NewWebhookRepository(pool *pgxpool.Pool, log *logrus.Logger) WebhookRepository {
+	return &webhookRepository{pool: pool, txm: NewTxManager(pool, log), log: log}
+}
+
+const (
+	endpointColumns = `endpoint_id, org_id, url, secret, events, created_at`
+	eventColumns    = `event_id, org_id, event_type, sub_id, data, created_at`
+	deliveryColumns = `d.delivery_id, d.event_id, d.endpoint_id, o.event_type, d.status, d.attempts,
+		COALESCE(d.response_status, 0), COALESCE(d.last_error, ''), d.next_attempt_at, d.delivered_at, d.created_at`
+)
+
+func scanEndpoint(row pgx.Row, e *model.WebhookEndpoint) error {
+	return row.Scan(&e.EndpointId, &e.OrgId, &e.Url, &e.Secret, &e.Events, &e.CreatedAt)
+}
+
+func scanEvent(row pgx.Row, e *model.WebhookEvent) error {
+	return row.Scan(&e.EventId, &e.OrgId, &e.Type, &e.SubId, &e.Data, &e.CreatedAt)
+}
+
+func scanDelivery(row pgx.Row, d *model.WebhookDelivery) error {
+	return row.Scan(&d.DeliveryId, &d.EventId, &d.EndpointId, &d.EventType, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.LastError, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt)
+}
+
+func (r *webhookRepository) CreateEndpoint(ctx context.Context, e *model.WebhookEndpoint) error {
+	r.log.WithFields(logrus.Fields{
+		"url": e.Url,
+	}).Debug("Creating webhook endpoint")
+
+	if e.Events == nil {
+		e.Events = []string{}
+	}
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO webhook_endpoints (org_id, url, secret, events)
+			VALUES ($1, $2, $3, $4)
+			RETURNING ` + endpointColumns
+		return scanEndpoint(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), e.Url, e.Secret, e.Events), e)
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "INSERT INTO webhook_endpoints",
+			"url":   e.Url,
+		}).Error("Failed to create webhook endpoint")
+	}
+	return err
+}
+
+func (r *webhookRepository) GetEndpoint(ctx context.Context, id int) (*model.WebhookEndpoint, error) {
+	var e model.WebhookEndpoint
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE org_id = $1 AND endpoint_id = $2`
+		return scanEndpoint(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), id), &e)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "SELECT FROM webhook_endpoints",
+			"endpoint_id": id,
+		}).Error("Failed to get webhook endpoint")
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *webhookRepository) ListEndpoints(ctx context.Context, limit, offset int) ([]model.WebhookEndpoint, error) {
+	var result []model.WebhookEndpoint
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			SELECT `+endpointColumns+`
+			FROM webhook_endpoints WHERE org_id = $1 ORDER BY endpoint_id LIMIT $2 OFFSET $3
+		`, reqctx.OrgId(ctx), limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e model.WebhookEndpoint
+			if err := scanEndpoint(rows, &e); err != nil {
+				return err
+			}
+			result = append(result, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM webhook_endpoints",
+		}).Error("Failed to list webhook endpoints")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *webhookRepository) CountEndpoints(ctx context.Context) (int, error) {
+	n, err := count(ctx, r.pool, r.txm, `SELECT count(*) FROM webhook_endpoints WHERE org_id = $1`, reqctx.OrgId(ctx))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM webhook_endpoints",
+		}).Error("Failed to count webhook endpoints")
+	}
+	return n, err
+}
+
+func (r *webhookRepository) DeleteEndpoint(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"endpoint_id": id,
+	}).Debug("Deleting webhook endpoint")
+
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		tag, err := conn(ctx, r.pool).Exec(ctx, `DELETE FROM webhook_endpoints WHERE org_id = $1 AND endpoint_id = $2`, reqctx.OrgId(ctx), id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "DELETE FROM webhook_endpoints",
+			"endpoint_id": id,
+		}).Error("Failed to delete webhook endpoint")
+	}
+	return err
+}
+
+func (r *webhookRepository) Enqueue(ctx context.Context, e *model.WebhookEvent) error {
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO webhook_outbox (org_id, event_type, sub_id, data)
+			VALUES ($1, $2, $3, $4)
+			RETURNING ` + eventColumns
+		return scanEvent(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), e.Type, e.SubId, e.Data), e)
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "INSERT INTO webhook_outbox",
+			"type":   e.Type,
+			"sub_id": e.SubId,
+		}).Error("Failed to enqueue webhook event")
+	}
+	return err
+}
+
+func (r *webhookRepository) Undispatched(ctx context.Context, limit int) ([]model.WebhookEvent, error) {
+	var result []model.WebhookEvent
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			SELECT `+eventColumns+`
+			FROM webhook_outbox WHERE org_id = $1 AND dispatched_at IS NULL ORDER BY event_id LIMIT $2
+		`, reqctx.OrgId(ctx), limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e model.WebhookEvent
+			if err := scanEvent(rows, &e); err != nil {
+				return err
+			}
+			result = append(result, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM webhook_outbox",
+		}).Error("Failed to list undispatched webhook events")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *webhookRepository) GetEvent(ctx context.Context, id int64) (*model.WebhookEvent, error) {
+	var e model.WebhookEvent
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		query := `SELECT ` + eventColumns + ` FROM webhook_outbox WHERE org_id = $1 AND event_id = $2`
+		return scanEvent(conn(ctx, r.pool).QueryRow(ctx, query, reqctx.OrgId(ctx), id), &e)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":    "SELECT FROM webhook_outbox",
+			"event_id": id,
+		}).Error("Failed to get webhook event")
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *webhookRepository) Dispatch(ctx context.Context, eventId int64, endpointIds []int) error {
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		orgId := reqctx.OrgId(ctx)
+		tag, err := conn(ctx, r.pool).Exec(ctx, `
+			UPDATE webhook_outbox SET dispatched_at = now()
+			WHERE org_id = $1 AND event_id = $2 AND dispatched_at IS NULL
+		`, orgId, eventId)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrConflict
+		}
+
+		for _, id := range endpointIds {
+			_, err := conn(ctx, r.pool).Exec(ctx, `
+				INSERT INTO webhook_deliveries (org_id, event_id, endpoint_id)
+				VALUES ($1, $2, $3)
+			`, orgId, eventId, id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrConflict) {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":    "INSERT INTO webhook_deliveries",
+			"event_id": eventId,
+		}).Error("Failed to dispatch webhook event")
+	}
+	return err
+}
+
+func (r *webhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	var result []model.WebhookDelivery
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			UPDATE webhook_deliveries d SET next_attempt_at = $2
+			FROM webhook_outbox o
+			WHERE o.event_id = d.event_id AND d.delivery_id IN (
+				SELECT delivery_id FROM webhook_deliveries
+				WHERE org_id = $1 AND status = 'pending' AND next_attempt_at <= $3
+				ORDER BY next_attempt_at, delivery_id LIMIT $4
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING `+deliveryColumns,
+			reqctx.OrgId(ctx), now.Add(lease), now, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var d model.WebhookDelivery
+			if err := scanDelivery(rows, &d); err != nil {
+				return err
+			}
+			result = append(result, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "UPDATE webhook_deliveries",
+		}).Error("Failed to claim webhook deliveries")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *webhookRepository) SaveAttempt(ctx context.Context, d *model.WebhookDelivery) error {
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		tag, err := conn(ctx, r.pool).Exec(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $1, attempts = $2, response_status = $3, last_error = $4, next_attempt_at = $5, delivered_at = $6
+			WHERE org_id = $7 AND delivery_id = $8
+		`, d.Status, d.Attempts, nullIfZero(d.ResponseStatus), nullIfEmpty(d.LastError), d.NextAttemptAt, d.DeliveredAt,
+			reqctx.OrgId(ctx), d.DeliveryId)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "UPDATE webhook_deliveries",
+			"delivery_id": d.DeliveryId,
+		}).Error("Failed to save webhook delivery attempt")
+	}
+	return err
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, endpointId int, limit, offset int) ([]model.WebhookDelivery, error) {
+	var result []model.WebhookDelivery
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := conn(ctx, r.pool).Query(ctx, `
+			SELECT `+deliveryColumns+`
+			FROM webhook_deliveries d JOIN webhook_outbox o ON o.event_id = d.event_id
+			WHERE d.org_id = $1 AND d.endpoint_id = $2
+			ORDER BY d.delivery_id DESC LIMIT $3 OFFSET $4
+		`, reqctx.OrgId(ctx), endpointId, limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var d model.WebhookDelivery
+			if err := scanDelivery(rows, &d); err != nil {
+				return err
+			}
+			result = append(result, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "SELECT FROM webhook_deliveries",
+			"endpoint_id": endpointId,
+		}).Error("Failed to list webhook deliveries")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *webhookRepository) CountDeliveries(ctx context.Context, endpointId int) (int, error) {
+	n, err := count(ctx, r.pool, r.txm, `SELECT count(*) FROM webhook_deliveries WHERE org_id = $1 AND endpoint_id = $2`, reqctx.OrgId(ctx), endpointId)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "SELECT count(*) FROM webhook_deliveries",
+			"endpoint_id": endpointId,
+		}).Error("Failed to count webhook deliveries")
+	}
+	return n, err
+}
+
+// nullIfZero stores 0 as NULL
+func nullIfZero(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
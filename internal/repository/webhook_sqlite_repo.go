@@ -0,0 +1,390 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+type sqliteWebhookRepository struct {
+	db  *sql.DB
+	txm TxManager
+	log *logrus.Logger
+}
+
+func NewSQLiteWebhookRepository(db *sql.DB, log *logrus.Logger) WebhookRepository {
+	return &sqliteWebhookRepository{db: db, txm: NewSQLiteTxManager(db, log), log: log}
+}
+
+// sqliteSortableLayout keeps all fractional digits, so that next_attempt_at
+// compares as text the same way as in time
+const sqliteSortableLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+func formatSortableTimestamp(t time.Time) string {
+	return t.UTC().Format(sqliteSortableLayout)
+}
+
+func scanSQLiteEndpoint(row sqliteRowScanner) (model.WebhookEndpoint, error) {
+	var (
+		e                 model.WebhookEndpoint
+		events, createdAt string
+	)
+	if err := row.Scan(&e.EndpointId, &e.OrgId, &e.Url, &e.Secret, &events, &createdAt); err != nil {
+		return e, err
+	}
+	e.Events = []string{}
+	if events != "" {
+		e.Events = strings.Split(events, ",")
+	}
+	var err error
+	e.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	return e, err
+}
+
+func scanSQLiteEvent(row sqliteRowScanner) (model.WebhookEvent, error) {
+	var (
+		e               model.WebhookEvent
+		data, createdAt string
+	)
+	if err := row.Scan(&e.EventId, &e.OrgId, &e.Type, &e.SubId, &data, &createdAt); err != nil {
+		return e, err
+	}
+	e.Data = []byte(data)
+	var err error
+	e.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	return e, err
+}
+
+func scanSQLiteDelivery(row sqliteRowScanner) (model.WebhookDelivery, error) {
+	var (
+		d                        model.WebhookDelivery
+		nextAttemptAt, createdAt string
+		deliveredAt              sql.NullString
+	)
+	err := row.Scan(&d.DeliveryId, &d.EventId, &d.EndpointId, &d.EventType, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.LastError, &nextAttemptAt, &deliveredAt, &createdAt)
+	if err != nil {
+		return d, err
+	}
+	if d.NextAttemptAt, err = time.Parse(time.RFC3339Nano, nextAttemptAt); err != nil {
+		return d, err
+	}
+	if d.DeliveredAt, err = parseNullTimestamp(deliveredAt); err != nil {
+		return d, err
+	}
+	d.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	return d, err
+}
+
+func (r *sqliteWebhookRepository) CreateEndpoint(ctx context.Context, e *model.WebhookEndpoint) error {
+	r.log.WithFields(logrus.Fields{
+		"url": e.Url,
+	}).Debug("Creating webhook endpoint")
+
+	query := `
+		INSERT INTO webhook_endpoints (org_id, url, secret, events, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING ` + endpointColumns
+	created, err := scanSQLiteEndpoint(sqlConn(ctx, r.db).QueryRowContext(ctx, query,
+		reqctx.OrgId(ctx), e.Url, e.Secret, strings.Join(e.Events, ","), formatTimestamp(time.Now())))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "INSERT INTO webhook_endpoints",
+			"url":   e.Url,
+		}).Error("Failed to create webhook endpoint")
+		return err
+	}
+	*e = created
+	return nil
+}
+
+func (r *sqliteWebhookRepository) GetEndpoint(ctx context.Context, id int) (*model.WebhookEndpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE org_id = ? AND endpoint_id = ?`
+	e, err := scanSQLiteEndpoint(sqlConn(ctx, r.db).QueryRowContext(ctx, query, reqctx.OrgId(ctx), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "SELECT FROM webhook_endpoints",
+			"endpoint_id": id,
+		}).Error("Failed to get webhook endpoint")
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *sqliteWebhookRepository) ListEndpoints(ctx context.Context, limit, offset int) ([]model.WebhookEndpoint, error) {
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+endpointColumns+`
+		FROM webhook_endpoints WHERE org_id = ? ORDER BY endpoint_id LIMIT ? OFFSET ?
+	`, reqctx.OrgId(ctx), limit, offset)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM webhook_endpoints",
+		}).Error("Failed to list webhook endpoints")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.WebhookEndpoint
+	for rows.Next() {
+		e, err := scanSQLiteEndpoint(rows)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for webhook endpoints")
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+func (r *sqliteWebhookRepository) CountEndpoints(ctx context.Context) (int, error) {
+	n, err := sqliteCount(ctx, r.db, `SELECT count(*) FROM webhook_endpoints WHERE org_id = ?`, reqctx.OrgId(ctx))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT count(*) FROM webhook_endpoints",
+		}).Error("Failed to count webhook endpoints")
+	}
+	return n, err
+}
+
+func (r *sqliteWebhookRepository) DeleteEndpoint(ctx context.Context, id int) error {
+	r.log.WithFields(logrus.Fields{
+		"endpoint_id": id,
+	}).Debug("Deleting webhook endpoint")
+
+	res, err := sqlConn(ctx, r.db).ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE org_id = ? AND endpoint_id = ?`, reqctx.OrgId(ctx), id)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "DELETE FROM webhook_endpoints",
+			"endpoint_id": id,
+		}).Error("Failed to delete webhook endpoint")
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteWebhookRepository) Enqueue(ctx context.Context, e *model.WebhookEvent) error {
+	query := `
+		INSERT INTO webhook_outbox (org_id, event_type, sub_id, data, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING ` + eventColumns
+	created, err := scanSQLiteEvent(sqlConn(ctx, r.db).QueryRowContext(ctx, query,
+		reqctx.OrgId(ctx), e.Type, e.SubId, string(e.Data), formatTimestamp(time.Now())))
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":  "INSERT INTO webhook_outbox",
+			"type":   e.Type,
+			"sub_id": e.SubId,
+		}).Error("Failed to enqueue webhook event")
+		return err
+	}
+	*e = created
+	return nil
+}
+
+func (r *sqliteWebhookRepository) Undispatched(ctx context.Context, limit int) ([]model.WebhookEvent, error) {
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+eventColumns+`
+		FROM webhook_outbox WHERE org_id = ? AND dispatched_at IS NULL ORDER BY event_id LIMIT ?
+	`, reqctx.OrgId(ctx), limit)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "SELECT FROM webhook_outbox",
+		}).Error("Failed to list undispatched webhook events")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.WebhookEvent
+	for rows.Next() {
+		e, err := scanSQLiteEvent(rows)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for webhook events")
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+func (r *sqliteWebhookRepository) GetEvent(ctx context.Context, id int64) (*model.WebhookEvent, error) {
+	query := `SELECT ` + eventColumns + ` FROM webhook_outbox WHERE org_id = ? AND event_id = ?`
+	e, err := scanSQLiteEvent(sqlConn(ctx, r.db).QueryRowContext(ctx, query, reqctx.OrgId(ctx), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":    "SELECT FROM webhook_outbox",
+			"event_id": id,
+		}).Error("Failed to get webhook event")
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *sqliteWebhookRepository) Dispatch(ctx context.Context, eventId int64, endpointIds []int) error {
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		orgId := reqctx.OrgId(ctx)
+		now := time.Now()
+		res, err := sqlConn(ctx, r.db).ExecContext(ctx, `
+			UPDATE webhook_outbox SET dispatched_at = ?
+			WHERE org_id = ? AND event_id = ? AND dispatched_at IS NULL
+		`, formatTimestamp(now), orgId, eventId)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrConflict
+		}
+
+		for _, id := range endpointIds {
+			_, err := sqlConn(ctx, r.db).ExecContext(ctx, `
+				INSERT INTO webhook_deliveries (org_id, event_id, endpoint_id, next_attempt_at, created_at)
+				VALUES (?, ?, ?, ?, ?)
+			`, orgId, eventId, id, formatSortableTimestamp(now), formatTimestamp(now))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrConflict) {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":    "INSERT INTO webhook_deliveries",
+			"event_id": eventId,
+		}).Error("Failed to dispatch webhook event")
+	}
+	return err
+}
+
+func (r *sqliteWebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.WebhookDelivery, error) {
+	var result []model.WebhookDelivery
+	err := r.txm.WithinTx(ctx, func(ctx context.Context) error {
+		rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+			SELECT `+deliveryColumns+`
+			FROM webhook_deliveries d JOIN webhook_outbox o ON o.event_id = d.event_id
+			WHERE d.org_id = ? AND d.status = 'pending' AND d.next_attempt_at <= ?
+			ORDER BY d.next_attempt_at, d.delivery_id LIMIT ?
+		`, reqctx.OrgId(ctx), formatSortableTimestamp(now), limit)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			d, err := scanSQLiteDelivery(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			result = append(result, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		until := now.Add(lease)
+		for i := range result {
+			_, err := sqlConn(ctx, r.db).ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = ? WHERE delivery_id = ?`,
+				formatSortableTimestamp(until), result[i].DeliveryId)
+			if err != nil {
+				return err
+			}
+			result[i].NextAttemptAt = until.UTC()
+		}
+		return nil
+	})
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query": "UPDATE webhook_deliveries",
+		}).Error("Failed to claim webhook deliveries")
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *sqliteWebhookRepository) SaveAttempt(ctx context.Context, d *model.WebhookDelivery) error {
+	var deliveredAt sql.NullString
+	if d.DeliveredAt != nil {
+		deliveredAt = sql.NullString{String: formatTimestamp(*d.DeliveredAt), Valid: true}
+	}
+	res, err := sqlConn(ctx, r.db).ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, response_status = ?, last_error = ?, next_attempt_at = ?, delivered_at = ?
+		WHERE org_id = ? AND delivery_id = ?
+	`, d.Status, d.Attempts, nullIfZero(d.ResponseStatus), nullIfEmpty(d.LastError), formatSortableTimestamp(d.NextAttemptAt),
+		deliveredAt, reqctx.OrgId(ctx), d.DeliveryId)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "UPDATE webhook_deliveries",
+			"delivery_id": d.DeliveryId,
+		}).Error("Failed to save webhook delivery attempt")
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *sqliteWebhookRepository) ListDeliveries(ctx context.Context, endpointId int, limit, offset int) ([]model.WebhookDelivery, error) {
+	rows, err := sqlConn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d JOIN webhook_outbox o ON o.event_id = d.event_id
+		WHERE d.org_id = ? AND d.endpoint_id = ?
+		ORDER BY d.delivery_id DESC LIMIT ? OFFSET ?
+	`, reqctx.OrgId(ctx), endpointId, limit, offset)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "SELECT FROM webhook_deliveries",
+			"endpoint_id": endpointId,
+		}).Error("Failed to list webhook deliveries")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []model.WebhookDelivery
+	for rows.Next() {
+		d, err := scanSQLiteDelivery(rows)
+		if err != nil {
+			r.log.WithError(err).Error("Failed to scan rows for webhook deliveries")
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+func (r *sqliteWebhookRepository) CountDeliveries(ctx context.Context, endpointId int) (int, error) {
+	n, err := sqliteCount(ctx, r.db, `SELECT count(*) FROM webhook_deliveries WHERE org_id = ? AND endpoint_id = ?`, reqctx.OrgId(ctx), endpointId)
+	if err != nil {
+		r.log.WithError(err).WithFields(logrus.Fields{
+			"query":       "SELECT count(*) FROM webhook_deliveries",
+			"endpoint_id": endpointId,
+		}).Error("Failed to count webhook deliveries")
+	}
+	return n, err
+}
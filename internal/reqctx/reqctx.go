@@ -0,0 +1,63 @@
+package reqctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type ctxKey int
+
+const (
+	requestIdKey ctxKey = iota
+	actorKey
+	orgIdKey
+)
+
+const (
+	AnonymousActor = "anonymous"
+	// DefaultOrgId is the organization of callers that don't belong to any other
+	DefaultOrgId = "default"
+)
+
+// NewRequestId makes an id for a request that came without one
+func NewRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey, id)
+}
+
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
+
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns who initiated the request, "anonymous" if unknown
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	if actor == "" {
+		return AnonymousActor
+	}
+	return actor
+}
+
+func WithOrgId(ctx context.Context, orgId string) context.Context {
+	return context.WithValue(ctx, orgIdKey, orgId)
+}
+
+// OrgId returns the organization (tenant) the request acts in, "default" if unknown
+func OrgId(ctx context.Context) string {
+	orgId, _ := ctx.Value(orgIdKey).(string)
+	if orgId == "" {
+		return DefaultOrgId
+	}
+	return orgId
+}
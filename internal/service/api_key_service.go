@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/auth"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+var ErrInvalidScope = errors.New("invalid scope")
+
+type ApiKeyService interface {
+	// Issue creates a key of the organization and returns its plain value, which is not stored
+	Issue(ctx context.Context, name, orgId string, scopes []string) (string, *model.ApiKey, error)
+	Revoke(ctx context.Context, id int) error
+	List(ctx context.Context) ([]model.ApiKey, error)
+	// Authenticate returns the active key with the given plain value
+	Authenticate(ctx context.Context, key string) (*model.ApiKey, error)
+}
+
+type apiKeyService struct {
+	repository repository.ApiKeyRepository
+	log        *logrus.Logger
+}
+
+func NewApiKeyService(r repository.ApiKeyRepository, log *logrus.Logger) ApiKeyService {
+	return &apiKeyService{repository: r, log: log}
+}
+
+func (s *apiKeyService) Issue(ctx context.Context, name, orgId string, scopes []string) (string, *model.ApiKey, error) {
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, scope := range scopes {
+		if !auth.ValidScope(scope) {
+			return "", nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+
+	key, prefix, hash, err := auth.GenerateApiKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if orgId == "" {
+		orgId = reqctx.DefaultOrgId
+	}
+
+	k := &model.ApiKey{Name: name, OrgId: orgId, Prefix: prefix, Scopes: scopes}
+	if err := s.repository.Create(ctx, k, hash); err != nil {
+		return "", nil, err
+	}
+
+	s.log.WithFields(logrus.Fields{
+		"key_id": k.KeyId,
+		"name":   k.Name,
+		"org_id": k.OrgId,
+		"scopes": k.Scopes,
+	}).Info("Api key issued")
+
+	return key, k, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id int) error {
+	s.log.WithFields(logrus.Fields{
+		"key_id": id,
+	}).Info("Revoking api key")
+
+	return s.repository.Revoke(ctx, id)
+}
+
+func (s *apiKeyService) List(ctx context.Context) ([]model.ApiKey, error) {
+	return s.repository.List(ctx)
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, key string) (*model.ApiKey, error) {
+	k, err := s.repository.GetByHash(ctx, auth.HashApiKey(key))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, auth.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if k.RevokedAt != nil {
+		s.log.WithFields(logrus.Fields{
+			"key_id": k.KeyId,
+		}).Warn("Revoked api key used")
+		return nil, auth.ErrInvalidCredentials
+	}
+	return k, nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/policy"
+	"github.com/tmozzze/SubChecker/internal/repository"
+)
+
+var (
+	// ErrInvalidBudget means a budget is set for both or neither of a user and a team
+	ErrInvalidBudget = errors.New("budget must be set for either a user or a team")
+	// ErrUnknownTeam means a team is not in the access policy
+	ErrUnknownTeam = errors.New("unknown team")
+)
+
+type BudgetService interface {
+	Create(ctx context.Context, b *model.Budget) error
+	GetById(ctx context.Context, id int) (*model.Budget, error)
+	Update(ctx context.Context, b *model.Budget) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, limit, offset int) ([]model.Budget, error)
+	Count(ctx context.Context) (int, error)
+	// Report compares spend of every month of the period with the budget
+	Report(ctx context.Context, id int, periodStart, periodEnd time.Time) (*model.BudgetReport, error)
+}
+
+type budgetService struct {
+	repository repository.BudgetRepository
+	subs       SubService
+	policy     *policy.Policy
+	log        *logrus.Logger
+}
+
+// NewBudgetService computes spend with subs, so reports are limited
+// to what subs lets the caller sum. Teams come from the policy.
+func NewBudgetService(r repository.BudgetRepository, subs SubService, p *policy.Policy, log *logrus.Logger) BudgetService {
+	return &budgetService{repository: r, subs: subs, policy: p, log: log}
+}
+
+func (s *budgetService) Create(ctx context.Context, b *model.Budget) error {
+	s.log.WithFields(logrus.Fields{
+		"user_id": b.UserId,
+		"team":    b.Team,
+		"amount":  b.AmountRub,
+	}).Info("Creating budget")
+
+	if (b.UserId == "") == (b.Team == "") {
+		return ErrInvalidBudget
+	}
+	if b.Team != "" {
+		if _, ok := s.policy.Team(b.Team); !ok {
+			return ErrUnknownTeam
+		}
+	}
+	return s.repository.Create(ctx, b)
+}
+
+func (s *budgetService) GetById(ctx context.Context, id int) (*model.Budget, error) {
+	return s.repository.GetById(ctx, id)
+}
+
+func (s *budgetService) Update(ctx context.Context, b *model.Budget) error {
+	s.log.WithFields(logrus.Fields{
+		"budget_id": b.BudgetId,
+		"amount":    b.AmountRub,
+	}).Info("Updating budget")
+
+	return s.repository.Update(ctx, b)
+}
+
+func (s *budgetService) Delete(ctx context.Context, id int) error {
+	s.log.WithFields(logrus.Fields{
+		"budget_id": id,
+	}).Info("Deleting budget")
+
+	return s.repository.Delete(ctx, id)
+}
+
+func (s *budgetService) List(ctx context.Context, limit, offset int) ([]model.Budget, error) {
+	return s.repository.List(ctx, limit, offset)
+}
+
+func (s *budgetService) Count(ctx context.Context) (int, error) {
+	return s.repository.Count(ctx)
+}
+
+func (s *budgetService) Report(ctx context.Context, id int, startDate, endDate time.Time) (*model.BudgetReport, error) {
+	b, err := s.repository.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := model.SubFilter{UserId: b.UserId}
+	if b.Team != "" {
+		// A team removed from the policy has no members
+		members, _ := s.policy.Team(b.Team)
+		if members == nil {
+			members = []string{}
+		}
+		filter.UserIds = members
+	}
+
+	months, err := s.subs.MonthlyCost(ctx, filter, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.BudgetReport{Budget: *b, Months: make([]model.BudgetMonth, 0, len(months))}
+	for _, m := range months {
+		month := model.BudgetMonth{Month: m.Month, SpentRub: m.TotalRub, BudgetRub: int64(b.AmountRub)}
+		if month.SpentRub > month.BudgetRub {
+			month.OverBudget = true
+			month.OverByRub = month.SpentRub - month.BudgetRub
+			report.OverMonths++
+		}
+		report.Months = append(report.Months, month)
+	}
+
+	if report.OverMonths > 0 {
+		s.log.WithFields(logrus.Fields{
+			"budget_id":   id,
+			"over_months": report.OverMonths,
+		}).Info("Budget exceeded")
+	}
+	return report, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+)
+
+var (
+	ErrEmptyServiceName     = errors.New("service name is empty")
+	ErrInvalidBillingPeriod = errors.New("billing period must be month or year")
+)
+
+// CatalogService manages services subscriptions refer to
+type CatalogService interface {
+	Create(ctx context.Context, s *model.Service) error
+	GetById(ctx context.Context, id int) (*model.Service, error)
+	Update(ctx context.Context, s *model.Service) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, limit, offset int) ([]model.Service, error)
+	Count(ctx context.Context) (int, error)
+}
+
+type catalogService struct {
+	repository repository.ServiceRepository
+	log        *logrus.Logger
+}
+
+func NewCatalogService(r repository.ServiceRepository, log *logrus.Logger) CatalogService {
+	return &catalogService{repository: r, log: log}
+}
+
+func (c *catalogService) Create(ctx context.Context, s *model.Service) error {
+	c.log.WithFields(logrus.Fields{
+		"name": s.Name,
+	}).Info("Creating service")
+
+	if err := normalizeService(s); err != nil {
+		return err
+	}
+	return c.repository.Create(ctx, s)
+}
+
+func (c *catalogService) GetById(ctx context.Context, id int) (*model.Service, error) {
+	return c.repository.GetById(ctx, id)
+}
+
+func (c *catalogService) Update(ctx context.Context, s *model.Service) error {
+	c.log.WithFields(logrus.Fields{
+		"service_id": s.ServiceId,
+		"name":       s.Name,
+	}).Info("Updating service")
+
+	if err := normalizeService(s); err != nil {
+		return err
+	}
+	return c.repository.Update(ctx, s)
+}
+
+func (c *catalogService) Delete(ctx context.Context, id int) error {
+	c.log.WithFields(logrus.Fields{
+		"service_id": id,
+	}).Info("Deleting service")
+
+	return c.repository.Delete(ctx, id)
+}
+
+func (c *catalogService) List(ctx context.Context, limit, offset int) ([]model.Service, error) {
+	return c.repository.List(ctx, limit, offset)
+}
+
+func (c *catalogService) Count(ctx context.Context) (int, error) {
+	return c.repository.Count(ctx)
+}
+
+// normalizeService validates s and stores categories in lower case,
+// so that "Video" and "video" are grouped together
+func normalizeService(s *model.Service) error {
+	if strings.TrimSpace(s.Name) == "" {
+		return ErrEmptyServiceName
+	}
+	switch s.BillingPeriod {
+	case "":
+		s.BillingPeriod = model.BillingMonth
+	case model.BillingMonth, model.BillingYear:
+	default:
+		return ErrInvalidBillingPeriod
+	}
+	s.Category = strings.ToLower(strings.TrimSpace(s.Category))
+	return nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+	"github.com/tmozzze/SubChecker/internal/stream"
+)
+
+// subEventData is the data of a subscription event. Sub is the state
+// after the change, Previous the state before it.
+type subEventData struct {
+	Sub      *model.Sub `json:"sub,omitempty"`
+	Previous *model.Sub `json:"previous,omitempty"`
+}
+
+// emit writes an event to the outbox, within the transaction bound to ctx
+func (s *subService) emit(ctx context.Context, eventType string, before, after *model.Sub) error {
+	subId := 0
+	if after != nil {
+		subId = after.SubId
+	} else if before != nil {
+		subId = before.SubId
+	}
+
+	data, err := json.Marshal(subEventData{Sub: after, Previous: before})
+	if err != nil {
+		return err
+	}
+	return s.webhooks.Enqueue(ctx, &model.WebhookEvent{Type: eventType, SubId: subId, Data: data})
+}
+
+// publish passes a committed change on to stream subscribers
+func (s *subService) publish(ctx context.Context, eventType string, before, after *model.Sub) {
+	e := model.SubEvent{Type: eventType, OrgId: reqctx.OrgId(ctx)}
+	if after == nil {
+		e.Sub = *before
+	} else {
+		e.Sub = *after
+		e.Previous = before
+	}
+	s.events.Publish(e)
+}
+
+func (s *subService) Watch(ctx context.Context, filter model.SubFilter, afterId int64) (*stream.Subscription, error) {
+	s.log.WithFields(logrus.Fields{
+		"user_id":    filter.UserId,
+		"service_id": filter.ServiceId,
+		"service":    filter.ServiceName,
+		"after_id":   afterId,
+	}).Info("Watching subscription changes")
+
+	match := func(e model.SubEvent) bool {
+		return repository.MatchSub(e.Sub, filter) || (e.Previous != nil && repository.MatchSub(*e.Previous, filter))
+	}
+	return s.events.Subscribe(reqctx.OrgId(ctx), afterId, match), nil
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/auth"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/policy"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/stream"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+type policySubService struct {
+	next   SubService
+	policy *policy.Policy
+	log    *logrus.Logger
+}
+
+// NewPolicySubService checks every operation against the caller's roles
+// (see package policy). A subscription the caller may not read looks as if
+// it doesn't exist; other denials return ErrForbidden. Calls without a
+// principal in the context are not restricted.
+func NewPolicySubService(next SubService, p *policy.Policy, log *logrus.Logger) SubService {
+	return &policySubService{next: next, policy: p, log: log}
+}
+
+// access is whose subscriptions the caller may target with an action
+type access struct {
+	users []string
+	all   bool
+}
+
+func (a access) allows(userId string) bool {
+	return a.all || slices.Contains(a.users, userId)
+}
+
+// restrict narrows filter to users the caller may target. It returns
+// ErrForbidden if the caller may target no one or the filter explicitly
+// asks for someone else.
+func (a access) restrict(filter model.SubFilter) (model.SubFilter, error) {
+	if a.all {
+		return filter, nil
+	}
+	if len(a.users) == 0 {
+		return filter, ErrForbidden
+	}
+	if filter.UserId == "" && filter.UserIds == nil {
+		filter.UserIds = append([]string{}, a.users...)
+		return filter, nil
+	}
+	if filter.UserId != "" && !a.allows(filter.UserId) {
+		return filter, ErrForbidden
+	}
+	for _, id := range filter.UserIds {
+		if !a.allows(id) {
+			return filter, ErrForbidden
+		}
+	}
+	return filter, nil
+}
+
+func (s *policySubService) access(ctx context.Context, action string) access {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return access{all: true}
+	}
+	users, all := s.policy.Users(p, action)
+	return access{users: users, all: all}
+}
+
+// readable returns the subscription if the caller may read it, ErrNotFound otherwise
+func (s *policySubService) readable(ctx context.Context, id int) (*model.Sub, error) {
+	sub, err := s.next.GetById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.access(ctx, policy.ActionRead).allows(sub.UserId) {
+		s.log.WithFields(logrus.Fields{
+			"sub_id":  id,
+			"user_id": sub.UserId,
+		}).Warn("Access to a subscription outside of caller's reach")
+		return nil, repository.ErrNotFound
+	}
+	return sub, nil
+}
+
+// writable returns ErrForbidden unless the caller may write subscriptions of userId
+func (s *policySubService) writable(ctx context.Context, userId string) error {
+	if !s.access(ctx, policy.ActionWrite).allows(userId) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func (s *policySubService) Create(ctx context.Context, sub *model.Sub) ([]model.Sub, error) {
+	if err := s.writable(ctx, sub.UserId); err != nil {
+		return nil, err
+	}
+	return s.next.Create(ctx, sub)
+}
+
+func (s *policySubService) GetById(ctx context.Context, id int) (*model.Sub, error) {
+	return s.readable(ctx, id)
+}
+
+func (s *policySubService) Update(ctx context.Context, sub *model.Sub) ([]model.Sub, error) {
+	current, err := s.readable(ctx, sub.SubId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.writable(ctx, current.UserId); err != nil {
+		return nil, err
+	}
+	if err := s.writable(ctx, sub.UserId); err != nil {
+		return nil, err
+	}
+	return s.next.Update(ctx, sub)
+}
+
+func (s *policySubService) Delete(ctx context.Context, id int) error {
+	current, err := s.readable(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.writable(ctx, current.UserId); err != nil {
+		return err
+	}
+	return s.next.Delete(ctx, id)
+}
+
+func (s *policySubService) List(ctx context.Context, filter model.SubFilter, limit, offset int) ([]model.Sub, error) {
+	filter, err := s.access(ctx, policy.ActionRead).restrict(filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.List(ctx, filter, limit, offset)
+}
+
+func (s *policySubService) Count(ctx context.Context, filter model.SubFilter) (int, error) {
+	filter, err := s.access(ctx, policy.ActionRead).restrict(filter)
+	if err != nil {
+		return 0, err
+	}
+	return s.next.Count(ctx, filter)
+}
+
+func (s *policySubService) SumCost(ctx context.Context, filter model.SubFilter, periodStart, periodEnd time.Time) (int64, error) {
+	filter, err := s.access(ctx, policy.ActionSum).restrict(filter)
+	if err != nil {
+		return 0, err
+	}
+	return s.next.SumCost(ctx, filter, periodStart, periodEnd)
+}
+
+func (s *policySubService) History(ctx context.Context, id int) ([]model.SubAudit, error) {
+	// An unknown sub has no history, even for callers who may read all
+	if _, err := s.readable(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.next.History(ctx, id)
+}
+
+// Prefill adds a new service_name to the catalog, so it needs write access
+// to subscriptions of the user before anything is written
+func (s *policySubService) Prefill(ctx context.Context, sub *model.Sub) error {
+	if err := s.writable(ctx, sub.UserId); err != nil {
+		return err
+	}
+	return s.next.Prefill(ctx, sub)
+}
+
+func (s *policySubService) SumByCategory(ctx context.Context, filter model.SubFilter, periodStart, periodEnd time.Time) ([]model.CategoryCost, error) {
+	filter, err := s.access(ctx, policy.ActionSum).restrict(filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.SumByCategory(ctx, filter, periodStart, periodEnd)
+}
+
+func (s *policySubService) MonthlyCost(ctx context.Context, filter model.SubFilter, periodStart, periodEnd time.Time) ([]model.MonthCost, error) {
+	filter, err := s.access(ctx, policy.ActionSum).restrict(filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.MonthlyCost(ctx, filter, periodStart, periodEnd)
+}
+
+func (s *policySubService) SchedulePrice(ctx context.Context, c *model.PriceChange) error {
+	current, err := s.readable(ctx, c.SubId)
+	if err != nil {
+		return err
+	}
+	if err := s.writable(ctx, current.UserId); err != nil {
+		return err
+	}
+	return s.next.SchedulePrice(ctx, c)
+}
+
+func (s *policySubService) PriceChanges(ctx context.Context, subId int) ([]model.PriceChange, error) {
+	if _, err := s.readable(ctx, subId); err != nil {
+		return nil, err
+	}
+	return s.next.PriceChanges(ctx, subId)
+}
+
+func (s *policySubService) CancelPriceChange(ctx context.Context, subId, id int) error {
+	current, err := s.readable(ctx, subId)
+	if err != nil {
+		return err
+	}
+	if err := s.writable(ctx, current.UserId); err != nil {
+		return err
+	}
+	return s.next.CancelPriceChange(ctx, subId, id)
+}
+
+func (s *policySubService) Forecast(ctx context.Context, filter model.SubFilter, periodStart, periodEnd time.Time) (*model.Forecast, error) {
+	filter, err := s.access(ctx, policy.ActionSum).restrict(filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.Forecast(ctx, filter, periodStart, periodEnd)
+}
+
+func (s *policySubService) Overlaps(ctx context.Context, filter model.SubFilter) (*model.OverlapReport, error) {
+	filter, err := s.access(ctx, policy.ActionRead).restrict(filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.Overlaps(ctx, filter)
+}
+
+func (s *policySubService) Watch(ctx context.Context, filter model.SubFilter, afterId int64) (*stream.Subscription, error) {
+	filter, err := s.access(ctx, policy.ActionRead).restrict(filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.Watch(ctx, filter, afterId)
+}
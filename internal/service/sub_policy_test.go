@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/auth"
+	"github.com/tmozzze/SubChecker/internal/config"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/policy"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/stream"
+)
+
+func newPolicyService(t *testing.T) (SubService, *repository.Repositories) {
+	t.Helper()
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	repos := repository.NewMemoryRepositories(repository.NewMemoryDB(), log)
+	svc := NewSubService(repos.Subs, repos.Users, repos.Services, repos.PriceChanges, repos.Webhooks,
+		stream.NewBroker(0), repos.Tx, nopObserver{}, config.OverlapOff, log)
+	return NewPolicySubService(svc, policy.Default(), log), repos
+}
+
+func TestPrefillNeedsWriteAccess(t *testing.T) {
+	svc, repos := newPolicyService(t)
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		UserId:  "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		Scopes:  []string{auth.ScopeRead, auth.ScopeWrite},
+	})
+
+	sub := &model.Sub{ServiceName: "Netflix", UserId: "0c3bd2c1-7a8e-4bd2-9f3a-5d6f0c8b1e2a"}
+	if err := svc.Prefill(ctx, sub); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("got %v, want ErrForbidden", err)
+	}
+	n, err := repos.Services.Count(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("denied prefill added %d services to the catalog", n)
+	}
+}
+
+func TestHistoryOfUnknownSub(t *testing.T) {
+	svc, _ := newPolicyService(t)
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Subject: "apikey:ops",
+		Scopes:  []string{auth.ScopeAdmin},
+	})
+
+	for name, ctx := range map[string]context.Context{"no principal": context.Background(), "admin": admin} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := svc.History(ctx, 42); !errors.Is(err, repository.ErrNotFound) {
+				t.Fatalf("got %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
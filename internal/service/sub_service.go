@@ -2,39 +2,190 @@ package service
 
 import (
 	"context"
+	"errors"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/config"
 	"github.com/tmozzze/SubChecker/internal/model"
 	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/stream"
 	"github.com/tmozzze/SubChecker/internal/utils"
 )
 
+var (
+	// ErrUnknownService means a subscription refers to a service id not in the catalog
+	ErrUnknownService = errors.New("unknown service")
+	// ErrNoDefaultPrice means a price can't be prefilled, the service has none
+	ErrNoDefaultPrice = errors.New("service has no default price")
+	// ErrPastPriceChange means a price change is scheduled for the current month or earlier
+	ErrPastPriceChange = errors.New("price change must be in a future month")
+	// ErrConflict means a subscription overlaps subscriptions of its user to
+	// its service and SUBS_OVERLAP_CHECK is reject, see OverlapError
+	ErrConflict = errors.New("subscription overlaps existing ones")
+)
+
+// OverlapError is ErrConflict with the subscriptions the rejected one overlaps
+type OverlapError struct {
+	Subs []model.Sub
+}
+
+func (e *OverlapError) Error() string { return ErrConflict.Error() }
+
+func (e *OverlapError) Unwrap() error { return ErrConflict }
+
+// uncategorized is the category reported for services without one
+const uncategorized = "uncategorized"
+
 type SubService interface {
-	Create(ctx context.Context, s *model.Sub) error
+	// Create and Update check s for overlaps according to SUBS_OVERLAP_CHECK
+	// within their transaction: with warn they return the subscriptions s
+	// overlaps, with reject they fail with an *OverlapError
+	Create(ctx context.Context, s *model.Sub) ([]model.Sub, error)
+	// Prefill sets the service of s and its price from the catalog
+	Prefill(ctx context.Context, s *model.Sub) error
 	GetById(ctx context.Context, id int) (*model.Sub, error)
-	Update(ctx context.Context, s *model.Sub) error
+	Update(ctx context.Context, s *model.Sub) ([]model.Sub, error)
 	Delete(ctx context.Context, id int) error
-	List(ctx context.Context, limit, offset int) ([]model.Sub, error)
-	SumCost(ctx context.Context, userID, serviceName string, periodStart, periodEnd time.Time) (int64, error)
+	List(ctx context.Context, filter model.SubFilter, limit, offset int) ([]model.Sub, error)
+	// Count is how many subscriptions List pages through
+	Count(ctx context.Context, filter model.SubFilter) (int, error)
+	SumCost(ctx context.Context, filter model.SubFilter, periodStart, periodEnd time.Time) (int64, error)
+	// SumByCategory is SumCost grouped by category of the service, largest first
+	SumByCategory(ctx context.Context, filter model.SubFilter, periodStart, periodEnd time.Time) ([]model.CategoryCost, error)
+	// MonthlyCost is SumCost of every month of the period, oldest first
+	MonthlyCost(ctx context.Context, filter model.SubFilter, periodStart, periodEnd time.Time) ([]model.MonthCost, error)
+	History(ctx context.Context, id int) ([]model.SubAudit, error)
+	// SchedulePrice schedules a new price of a subscription from a future month
+	SchedulePrice(ctx context.Context, c *model.PriceChange) error
+	PriceChanges(ctx context.Context, subId int) ([]model.PriceChange, error)
+	CancelPriceChange(ctx context.Context, subId, id int) error
+	// Forecast is MonthlyCost up to the current month and projected spend after it
+	Forecast(ctx context.Context, filter model.SubFilter, periodStart, periodEnd time.Time) (*model.Forecast, error)
+	// Overlaps finds subscriptions of one user to one service paid in the
+	// same months, up to the current month like SumCost
+	Overlaps(ctx context.Context, filter model.SubFilter) (*model.OverlapReport, error)
+	// Watch streams committed changes of subscriptions matching filter before
+	// or after the change. Buffered changes after afterId are sent first.
+	Watch(ctx context.Context, filter model.SubFilter, afterId int64) (*stream.Subscription, error)
+}
+
+// SumCostObserver is told how long a total cost took to compute and how
+// many subscriptions it was summed over
+type SumCostObserver interface {
+	ObserveSumCost(elapsed time.Duration, rows int)
 }
 
 type subService struct {
-	repository repository.SubRepository
-	log        *logrus.Logger
+	repository   repository.SubRepository
+	users        repository.UserRepository
+	services     repository.ServiceRepository
+	priceChanges repository.PriceChangeRepository
+	webhooks     repository.WebhookRepository
+	events       stream.Hub
+	txm          repository.TxManager
+	sumCost      SumCostObserver
+	overlapCheck string
+	log          *logrus.Logger
 }
 
-func NewSubService(r repository.SubRepository, log *logrus.Logger) SubService {
-	return &subService{repository: r, log: log}
+// NewSubService writes an event to the webhooks outbox within the
+// transaction of every create, update and delete, and publishes it to
+// events once committed. Written subscriptions are checked for overlaps
+// according to overlapCheck, one of config.OverlapOff, OverlapWarn and
+// OverlapReject.
+func NewSubService(r repository.SubRepository, users repository.UserRepository, services repository.ServiceRepository, priceChanges repository.PriceChangeRepository, webhooks repository.WebhookRepository, events stream.Hub, txm repository.TxManager, sumCost SumCostObserver, overlapCheck string, log *logrus.Logger) SubService {
+	return &subService{repository: r, users: users, services: services, priceChanges: priceChanges, webhooks: webhooks, events: events, txm: txm, sumCost: sumCost, overlapCheck: overlapCheck, log: log}
 }
 
-func (s *subService) Create(ctx context.Context, sub *model.Sub) error {
+// resolve registers the sub's user and finds its service
+func (s *subService) resolve(ctx context.Context, sub *model.Sub) error {
+	if err := s.users.Ensure(ctx, sub.UserId); err != nil {
+		return err
+	}
+	svc, err := s.service(ctx, sub)
+	if err != nil {
+		return err
+	}
+	sub.ServiceId = svc.ServiceId
+	sub.ServiceName = svc.Name
+	return nil
+}
+
+// checkOverlap applies overlapCheck to a resolved sub about to be written
+func (s *subService) checkOverlap(ctx context.Context, sub *model.Sub) ([]model.Sub, error) {
+	if s.overlapCheck == config.OverlapOff {
+		return nil, nil
+	}
+	if err := s.users.Lock(ctx, sub.UserId); err != nil {
+		return nil, err
+	}
+	overlapping, err := s.overlapping(ctx, sub)
+	if err != nil || len(overlapping) == 0 {
+		return nil, err
+	}
+
+	subIds := make([]int, 0, len(overlapping))
+	for _, other := range overlapping {
+		subIds = append(subIds, other.SubId)
+	}
 	s.log.WithFields(logrus.Fields{
 		"user_id": sub.UserId,
-		"service": sub.ServiceName,
+		"sub_ids": subIds,
+		"mode":    s.overlapCheck,
+	}).Warn("Subscription overlaps existing ones")
+
+	if s.overlapCheck == config.OverlapReject {
+		return nil, &OverlapError{Subs: overlapping}
+	}
+	return overlapping, nil
+}
+
+// service finds the service of sub: by ServiceId if set, otherwise by
+// ServiceName, adding it to the catalog when it is new
+func (s *subService) service(ctx context.Context, sub *model.Sub) (*model.Service, error) {
+	if sub.ServiceId != 0 {
+		svc, err := s.services.GetById(ctx, sub.ServiceId)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUnknownService
+		}
+		return svc, err
+	}
+	if strings.TrimSpace(sub.ServiceName) == "" {
+		return nil, ErrEmptyServiceName
+	}
+	return s.services.Ensure(ctx, sub.ServiceName)
+}
+
+func (s *subService) Create(ctx context.Context, sub *model.Sub) ([]model.Sub, error) {
+	s.log.WithFields(logrus.Fields{
+		"user_id":    sub.UserId,
+		"service_id": sub.ServiceId,
+		"service":    sub.ServiceName,
 	}).Info("Creating new subscription")
 
-	return s.repository.Create(ctx, sub)
+	var overlapping []model.Sub
+	err := s.txm.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.resolve(ctx, sub); err != nil {
+			return err
+		}
+		var err error
+		overlapping, err = s.checkOverlap(ctx, sub)
+		if err != nil {
+			return err
+		}
+		if err := s.repository.Create(ctx, sub); err != nil {
+			return err
+		}
+		return s.emit(ctx, model.EventSubCreated, nil, sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, model.EventSubCreated, nil, sub)
+	return overlapping, nil
 }
 
 func (s *subService) GetById(ctx context.Context, id int) (*model.Sub, error) {
@@ -45,12 +196,51 @@ func (s *subService) GetById(ctx context.Context, id int) (*model.Sub, error) {
 	return s.repository.GetById(ctx, id)
 }
 
-func (s *subService) Update(ctx context.Context, sub *model.Sub) error {
+func (s *subService) Update(ctx context.Context, sub *model.Sub) ([]model.Sub, error) {
 	s.log.WithFields(logrus.Fields{
 		"sub_id": sub.SubId,
 	}).Info("Updating subscription")
 
-	return s.repository.Update(ctx, sub)
+	var (
+		current     *model.Sub
+		event       string
+		overlapping []model.Sub
+	)
+	err := s.txm.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		current, err = s.repository.GetById(ctx, sub.SubId)
+		if err != nil {
+			return err
+		}
+		if err := s.resolve(ctx, sub); err != nil {
+			return err
+		}
+		overlapping, err = s.checkOverlap(ctx, sub)
+		if err != nil {
+			return err
+		}
+		if current.Price != sub.Price {
+			s.log.WithFields(logrus.Fields{
+				"sub_id":    sub.SubId,
+				"old_price": current.Price,
+				"new_price": sub.Price,
+			}).Info("Subscription price changed")
+		}
+
+		if err := s.repository.Update(ctx, sub); err != nil {
+			return err
+		}
+		event = model.EventSubUpdated
+		if current.EndDate == nil && sub.EndDate != nil {
+			event = model.EventSubEnded
+		}
+		return s.emit(ctx, event, current, sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, event, current, sub)
+	return overlapping, nil
 }
 
 func (s *subService) Delete(ctx context.Context, id int) error {
@@ -58,47 +248,413 @@ func (s *subService) Delete(ctx context.Context, id int) error {
 		"sub_id": id,
 	}).Info("Deleting subscription")
 
-	return s.repository.Delete(ctx, id)
+	var current *model.Sub
+	err := s.txm.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		current, err = s.repository.GetById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.repository.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.emit(ctx, model.EventSubDeleted, current, nil)
+	})
+	if err == nil {
+		s.publish(ctx, model.EventSubDeleted, current, nil)
+	}
+	return err
 }
 
-func (s *subService) List(ctx context.Context, limit, offset int) ([]model.Sub, error) {
-	s.log.Info("Getting list of subscriptions")
+func (s *subService) List(ctx context.Context, filter model.SubFilter, limit, offset int) ([]model.Sub, error) {
+	s.log.WithFields(logrus.Fields{
+		"user_id":    filter.UserId,
+		"service_id": filter.ServiceId,
+		"service":    filter.ServiceName,
+	}).Info("Getting list of subscriptions")
 
-	return s.repository.List(ctx, limit, offset)
+	return s.repository.List(ctx, filter, limit, offset)
 }
 
-func (s *subService) SumCost(ctx context.Context, userId, serviceName string, startDate, endDate time.Time) (int64, error) {
+func (s *subService) Count(ctx context.Context, filter model.SubFilter) (int, error) {
+	return s.repository.Count(ctx, filter)
+}
+
+func (s *subService) History(ctx context.Context, id int) ([]model.SubAudit, error) {
 	s.log.WithFields(logrus.Fields{
-		"user_id": userId,
-		"service": serviceName,
+		"sub_id": id,
+	}).Info("Getting subscription history")
+
+	return s.repository.History(ctx, id)
+}
+
+func (s *subService) SumCost(ctx context.Context, filter model.SubFilter, startDate, endDate time.Time) (int64, error) {
+	s.log.WithFields(logrus.Fields{
+		"user_id":    filter.UserId,
+		"service_id": filter.ServiceId,
+		"service":    filter.ServiceName,
 	}).Info("Calculating total subscription cost")
 
-	subs, err := s.repository.SumCost(ctx, userId, serviceName)
+	started := time.Now()
+	subs, changes, err := s.subsWithChanges(ctx, filter)
 	if err != nil {
 		return 0, err
 	}
 
 	var total int64 = 0
 	for _, sub := range subs {
-		sStart := utils.TruncateToMonth(sub.StartDate)
-		var sEnd time.Time
-		if sub.EndDate == nil {
-			sEnd = time.Now().UTC()
-		} else {
-			sEnd = *sub.EndDate
+		total += cost(sub, changes[sub.SubId], startDate, endDate)
+	}
+	s.sumCost.ObserveSumCost(time.Since(started), len(subs))
+
+	return total, nil
+}
+
+// subsWithChanges returns the subscriptions matching filter with their
+// price changes by subscription id, ordered by month
+func (s *subService) subsWithChanges(ctx context.Context, filter model.SubFilter) ([]model.Sub, map[int][]model.PriceChange, error) {
+	var (
+		subs    []model.Sub
+		changes = make(map[int][]model.PriceChange)
+	)
+	err := s.txm.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		subs, err = s.repository.SumCost(ctx, filter)
+		if err != nil {
+			return err
+		}
+		ids := make([]int, 0, len(subs))
+		for _, sub := range subs {
+			ids = append(ids, sub.SubId)
+		}
+		list, err := s.priceChanges.List(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, c := range list {
+			changes[c.SubId] = append(changes[c.SubId], c)
 		}
-		sEnd = utils.TruncateToMonth(sEnd)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return subs, changes, nil
+}
+
+// cost is what sub costs within the period, whole months inclusive. Each
+// price change applies from its month on.
+func cost(sub model.Sub, changes []model.PriceChange, periodStart, periodEnd time.Time) int64 {
+	sStart := utils.TruncateToMonth(sub.StartDate)
+	var sEnd time.Time
+	if sub.EndDate == nil {
+		sEnd = time.Now().UTC()
+	} else {
+		sEnd = *sub.EndDate
+	}
+	sEnd = utils.TruncateToMonth(sEnd)
 
-		pStart := utils.TruncateToMonth(startDate)
-		pEnd := utils.TruncateToMonth(endDate)
+	pStart := utils.TruncateToMonth(periodStart)
+	pEnd := utils.TruncateToMonth(periodEnd)
 
-		months := utils.MonthsOverlap(sStart, sEnd, pStart, pEnd)
-		if months > 0 {
-			total += int64(months) * int64(sub.Price)
-		} else {
-			continue
+	var total int64
+	from, price := sStart, sub.Price
+	for _, c := range changes {
+		month := utils.TruncateToMonth(c.Month)
+		if month.After(sEnd) {
+			break
 		}
+		if month.After(from) {
+			total += int64(utils.MonthsOverlap(from, month.AddDate(0, -1, 0), pStart, pEnd)) * int64(price)
+			from = month
+		}
+		price = c.Price
 	}
+	return total + int64(utils.MonthsOverlap(from, sEnd, pStart, pEnd))*int64(price)
+}
 
-	return total, nil
+func (s *subService) Prefill(ctx context.Context, sub *model.Sub) error {
+	return s.txm.WithinTx(ctx, func(ctx context.Context) error {
+		svc, err := s.service(ctx, sub)
+		if err != nil {
+			return err
+		}
+		price, ok := monthlyPrice(svc)
+		if !ok {
+			return ErrNoDefaultPrice
+		}
+		sub.ServiceId = svc.ServiceId
+		sub.ServiceName = svc.Name
+		sub.Price = price
+		return nil
+	})
+}
+
+// monthlyPrice is the default price of svc per month, the unit subscription
+// prices are kept in. A yearly price is divided by 12 and rounded.
+func monthlyPrice(svc *model.Service) (int, bool) {
+	if svc.DefaultPrice == nil {
+		return 0, false
+	}
+	if svc.BillingPeriod == model.BillingYear {
+		return (*svc.DefaultPrice + 6) / 12, true
+	}
+	return *svc.DefaultPrice, true
+}
+
+func (s *subService) SumByCategory(ctx context.Context, filter model.SubFilter, startDate, endDate time.Time) ([]model.CategoryCost, error) {
+	s.log.WithFields(logrus.Fields{
+		"user_id":    filter.UserId,
+		"service_id": filter.ServiceId,
+		"service":    filter.ServiceName,
+	}).Info("Calculating subscription cost by category")
+
+	totals := make(map[string]int64)
+	err := s.txm.WithinTx(ctx, func(ctx context.Context) error {
+		subs, changes, err := s.subsWithChanges(ctx, filter)
+		if err != nil {
+			return err
+		}
+
+		categories := make(map[int]string)
+		for _, sub := range subs {
+			c := cost(sub, changes[sub.SubId], startDate, endDate)
+			if c == 0 {
+				continue
+			}
+			category, ok := categories[sub.ServiceId]
+			if !ok {
+				svc, err := s.services.GetById(ctx, sub.ServiceId)
+				if err != nil {
+					return err
+				}
+				category = svc.Category
+				if category == "" {
+					category = uncategorized
+				}
+				categories[sub.ServiceId] = category
+			}
+			totals[category] += c
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.CategoryCost, 0, len(totals))
+	for category, total := range totals {
+		result = append(result, model.CategoryCost{Category: category, TotalRub: total})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalRub != result[j].TotalRub {
+			return result[i].TotalRub > result[j].TotalRub
+		}
+		return result[i].Category < result[j].Category
+	})
+	return result, nil
+}
+
+func (s *subService) MonthlyCost(ctx context.Context, filter model.SubFilter, startDate, endDate time.Time) ([]model.MonthCost, error) {
+	s.log.WithFields(logrus.Fields{
+		"user_id":    filter.UserId,
+		"service_id": filter.ServiceId,
+		"service":    filter.ServiceName,
+	}).Info("Calculating subscription cost by month")
+
+	subs, changes, err := s.subsWithChanges(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []model.MonthCost
+	end := utils.TruncateToMonth(endDate)
+	for m := utils.TruncateToMonth(startDate); !m.After(end); m = m.AddDate(0, 1, 0) {
+		var total int64
+		for _, sub := range subs {
+			total += cost(sub, changes[sub.SubId], m, m)
+		}
+		result = append(result, model.MonthCost{Month: m, TotalRub: total})
+	}
+	return result, nil
+}
+
+func (s *subService) SchedulePrice(ctx context.Context, c *model.PriceChange) error {
+	s.log.WithFields(logrus.Fields{
+		"sub_id": c.SubId,
+		"month":  c.Month,
+		"price":  c.Price,
+	}).Info("Scheduling price change")
+
+	c.Month = utils.TruncateToMonth(c.Month)
+	if !c.Month.After(utils.TruncateToMonth(time.Now().UTC())) {
+		return ErrPastPriceChange
+	}
+	return s.priceChanges.Create(ctx, c)
+}
+
+func (s *subService) PriceChanges(ctx context.Context, subId int) ([]model.PriceChange, error) {
+	result := []model.PriceChange{}
+	err := s.txm.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.repository.GetById(ctx, subId); err != nil {
+			return err
+		}
+		changes, err := s.priceChanges.List(ctx, []int{subId})
+		if err != nil {
+			return err
+		}
+		result = append(result, changes...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *subService) CancelPriceChange(ctx context.Context, subId, id int) error {
+	s.log.WithFields(logrus.Fields{
+		"sub_id":    subId,
+		"change_id": id,
+	}).Info("Cancelling price change")
+
+	return s.priceChanges.Delete(ctx, subId, id)
+}
+
+func (s *subService) Forecast(ctx context.Context, filter model.SubFilter, startDate, endDate time.Time) (*model.Forecast, error) {
+	s.log.WithFields(logrus.Fields{
+		"user_id":    filter.UserId,
+		"service_id": filter.ServiceId,
+		"service":    filter.ServiceName,
+	}).Info("Forecasting subscription cost")
+
+	subs, changes, err := s.subsWithChanges(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Months up to the current one are counted the way SumCost counts them
+	current := utils.TruncateToMonth(time.Now().UTC())
+	result := &model.Forecast{Months: []model.ForecastMonth{}}
+	end := utils.TruncateToMonth(endDate)
+	for m := utils.TruncateToMonth(startDate); !m.After(end); m = m.AddDate(0, 1, 0) {
+		month := model.ForecastMonth{Month: m}
+		for _, sub := range subs {
+			if m.After(current) {
+				month.ProjectedRub += projectedCost(sub, changes[sub.SubId], m)
+			} else {
+				month.ActualRub += cost(sub, changes[sub.SubId], m, m)
+			}
+		}
+		result.ActualRub += month.ActualRub
+		result.ProjectedRub += month.ProjectedRub
+		result.Months = append(result.Months, month)
+	}
+	return result, nil
+}
+
+// projectedCost is what sub will cost in month m after the current one.
+// An open subscription continues, the latest price change by m applies.
+func projectedCost(sub model.Sub, changes []model.PriceChange, m time.Time) int64 {
+	if utils.TruncateToMonth(sub.StartDate).After(m) {
+		return 0
+	}
+	if sub.EndDate != nil && utils.TruncateToMonth(*sub.EndDate).Before(m) {
+		return 0
+	}
+	price := sub.Price
+	for _, c := range changes {
+		if c.Month.After(m) {
+			break
+		}
+		price = c.Price
+	}
+	return int64(price)
+}
+
+func (s *subService) Overlaps(ctx context.Context, filter model.SubFilter) (*model.OverlapReport, error) {
+	s.log.WithFields(logrus.Fields{
+		"user_id":    filter.UserId,
+		"service_id": filter.ServiceId,
+		"service":    filter.ServiceName,
+	}).Info("Finding overlapping subscriptions")
+
+	subs, err := s.repository.SumCost(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		userId    string
+		serviceId int
+	}
+	groups := make(map[key][]model.Sub)
+	for _, sub := range subs {
+		k := key{userId: sub.UserId, serviceId: sub.ServiceId}
+		groups[k] = append(groups[k], sub)
+	}
+
+	now := time.Now().UTC()
+	report := &model.OverlapReport{Overlaps: []model.Overlap{}}
+	for _, group := range groups {
+		for i, a := range group {
+			for _, b := range group[i+1:] {
+				months := overlapMonths(a, b, now)
+				if months == 0 {
+					continue
+				}
+				o := model.Overlap{
+					UserId:      a.UserId,
+					ServiceId:   a.ServiceId,
+					ServiceName: a.ServiceName,
+					SubIds:      []int{a.SubId, b.SubId},
+					Months:      months,
+					WastedRub:   int64(months) * int64(min(a.Price, b.Price)),
+				}
+				report.Overlaps = append(report.Overlaps, o)
+				report.WastedRub += o.WastedRub
+			}
+		}
+	}
+	sort.Slice(report.Overlaps, func(i, j int) bool {
+		a, b := report.Overlaps[i].SubIds, report.Overlaps[j].SubIds
+		return a[0] < b[0] || a[0] == b[0] && a[1] < b[1]
+	})
+	return report, nil
+}
+
+// overlapping returns subscriptions of the user of sub to its service that
+// overlap sub. Open subscriptions never end.
+func (s *subService) overlapping(ctx context.Context, sub *model.Sub) ([]model.Sub, error) {
+	filter := model.SubFilter{UserId: sub.UserId, ServiceId: sub.ServiceId}
+	if filter.ServiceId == 0 {
+		filter.ServiceName = sub.ServiceName
+	}
+	subs, err := s.repository.SumCost(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []model.Sub{}
+	for _, other := range subs {
+		if other.SubId != sub.SubId && overlapMonths(*sub, other, time.Time{}) > 0 {
+			result = append(result, other)
+		}
+	}
+	return result, nil
+}
+
+// overlapMonths is the number of months both a and b are paid for. Open
+// subscriptions end at openEnd, or never if it is zero.
+func overlapMonths(a, b model.Sub, openEnd time.Time) int {
+	end := func(sub model.Sub) time.Time {
+		switch {
+		case sub.EndDate != nil:
+			return utils.TruncateToMonth(*sub.EndDate)
+		case openEnd.IsZero():
+			return time.Date(9999, time.December, 1, 0, 0, 0, 0, time.UTC)
+		}
+		return utils.TruncateToMonth(openEnd)
+	}
+	return utils.MonthsOverlap(utils.TruncateToMonth(a.StartDate), end(a), utils.TruncateToMonth(b.StartDate), end(b))
 }
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/config"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/stream"
+	"github.com/tmozzze/SubChecker/internal/utils"
+)
+
+type nopObserver struct{}
+
+func (nopObserver) ObserveSumCost(time.Duration, int) {}
+
+func month(t time.Time, offset int) time.Time {
+	return utils.TruncateToMonth(t).AddDate(0, offset, 0)
+}
+
+func TestCostAppliesPriceChanges(t *testing.T) {
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	sub := model.Sub{Price: 100, StartDate: start, EndDate: &end}
+	changes := []model.PriceChange{
+		// Before the start it sets the starting price
+		{Month: start.AddDate(0, -2, 0), Price: 50},
+		{Month: start.AddDate(0, 2, 0), Price: 200},
+		{Month: start.AddDate(0, 4, 0), Price: 300},
+		// After the end it never applies
+		{Month: end.AddDate(0, 1, 0), Price: 1000},
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     int64
+	}{
+		{"whole", start, end, 50*2 + 200*2 + 300*2},
+		{"one price", start.AddDate(0, 2, 0), start.AddDate(0, 3, 0), 200 * 2},
+		{"across a change", start.AddDate(0, 1, 0), start.AddDate(0, 2, 0), 50 + 200},
+		{"after the end", end.AddDate(0, 1, 0), end.AddDate(0, 3, 0), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cost(sub, changes, tt.from, tt.to); got != tt.want {
+				t.Errorf("cost = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSumCostMatchesForecast(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	repos := repository.NewMemoryRepositories(repository.NewMemoryDB(), log)
+	svc := NewSubService(repos.Subs, repos.Users, repos.Services, repos.PriceChanges, repos.Webhooks,
+		stream.NewBroker(0), repos.Tx, nopObserver{}, config.OverlapOff, log)
+	ctx := context.Background()
+
+	// Started in the past and ends in the future, with a change on each side
+	now := time.Now().UTC()
+	end := month(now, 3)
+	sub := &model.Sub{ServiceName: "Netflix", Price: 600, UserId: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: month(now, -3), EndDate: &end}
+	if _, err := svc.Create(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+	// A change that took effect can't be scheduled any more
+	if err := repos.PriceChanges.Create(ctx, &model.PriceChange{SubId: sub.SubId, Month: month(now, -1), Price: 700}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SchedulePrice(ctx, &model.PriceChange{SubId: sub.SubId, Month: month(now, 2), Price: 800}); err != nil {
+		t.Fatal(err)
+	}
+
+	from, to := month(now, -4), month(now, 4)
+	total, err := svc.SumCost(ctx, model.SubFilter{}, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast, err := svc.Forecast(ctx, model.SubFilter{}, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(600*2 + 700*3 + 800*2); total != want {
+		t.Errorf("SumCost = %d, want %d", total, want)
+	}
+	if got := forecast.ActualRub + forecast.ProjectedRub; got != total {
+		t.Errorf("forecast = %d, SumCost = %d", got, total)
+	}
+}
+
+func TestCreateChecksOverlaps(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	newSub := func(start time.Time, end *time.Time) *model.Sub {
+		return &model.Sub{ServiceName: "Netflix", Price: 600, UserId: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: start, EndDate: end}
+	}
+
+	for _, mode := range []string{config.OverlapOff, config.OverlapWarn, config.OverlapReject} {
+		t.Run(mode, func(t *testing.T) {
+			repos := repository.NewMemoryRepositories(repository.NewMemoryDB(), log)
+			svc := NewSubService(repos.Subs, repos.Users, repos.Services, repos.PriceChanges, repos.Webhooks,
+				stream.NewBroker(0), repos.Tx, nopObserver{}, mode, log)
+			ctx := context.Background()
+
+			first := newSub(start, &end)
+			if _, err := svc.Create(ctx, first); err != nil {
+				t.Fatal(err)
+			}
+			later := end.AddDate(0, 1, 0)
+			second := newSub(later, nil)
+			if overlapping, err := svc.Create(ctx, second); err != nil || len(overlapping) != 0 {
+				t.Fatalf("after the end: %v, %v", overlapping, err)
+			}
+
+			// Moving it back into the first one overlaps
+			second.StartDate = end
+			overlapping, err := svc.Update(ctx, second)
+			switch mode {
+			case config.OverlapOff:
+				if err != nil || len(overlapping) != 0 {
+					t.Fatalf("got %v, %v", overlapping, err)
+				}
+			case config.OverlapWarn:
+				if err != nil || len(overlapping) != 1 || overlapping[0].SubId != first.SubId {
+					t.Fatalf("got %v, %v, want sub %d", overlapping, err, first.SubId)
+				}
+			case config.OverlapReject:
+				var overlap *OverlapError
+				if !errors.As(err, &overlap) || !errors.Is(err, ErrConflict) || len(overlap.Subs) != 1 {
+					t.Fatalf("got %v, want an OverlapError", err)
+				}
+				got, err := svc.GetById(ctx, second.SubId)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !got.StartDate.Equal(later) {
+					t.Errorf("rejected update was written: start %v", got.StartDate)
+				}
+			}
+		})
+	}
+}
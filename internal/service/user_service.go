@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+)
+
+type UserService interface {
+	Create(ctx context.Context, u *model.User) error
+	GetById(ctx context.Context, id string) (*model.User, error)
+	// GetByIds returns the users that exist out of ids
+	GetByIds(ctx context.Context, ids []string) ([]model.User, error)
+	Update(ctx context.Context, u *model.User) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, limit, offset int) ([]model.User, error)
+	Count(ctx context.Context) (int, error)
+}
+
+type userService struct {
+	repository repository.UserRepository
+	log        *logrus.Logger
+}
+
+func NewUserService(r repository.UserRepository, log *logrus.Logger) UserService {
+	return &userService{repository: r, log: log}
+}
+
+func (s *userService) Create(ctx context.Context, u *model.User) error {
+	s.log.WithFields(logrus.Fields{
+		"user_id": u.UserId,
+	}).Info("Creating user")
+
+	return s.repository.Create(ctx, u)
+}
+
+func (s *userService) GetById(ctx context.Context, id string) (*model.User, error) {
+	return s.repository.GetById(ctx, id)
+}
+
+func (s *userService) GetByIds(ctx context.Context, ids []string) ([]model.User, error) {
+	return s.repository.GetByIds(ctx, ids)
+}
+
+func (s *userService) Update(ctx context.Context, u *model.User) error {
+	s.log.WithFields(logrus.Fields{
+		"user_id": u.UserId,
+	}).Info("Updating user")
+
+	return s.repository.Update(ctx, u)
+}
+
+func (s *userService) Delete(ctx context.Context, id string) error {
+	s.log.WithFields(logrus.Fields{
+		"user_id": id,
+	}).Info("Deleting user")
+
+	return s.repository.Delete(ctx, id)
+}
+
+func (s *userService) List(ctx context.Context, limit, offset int) ([]model.User, error) {
+	return s.repository.List(ctx, limit, offset)
+}
+
+func (s *userService) Count(ctx context.Context) (int, error) {
+	return s.repository.Count(ctx)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+)
+
+var (
+	ErrInvalidWebhookUrl = errors.New("webhook url must be an absolute http or https url")
+	ErrUnknownEvent      = errors.New("unknown event")
+)
+
+// webhookEvents are the events an endpoint can subscribe to
+var webhookEvents = map[string]bool{
+	model.EventSubCreated: true,
+	model.EventSubUpdated: true,
+	model.EventSubEnded:   true,
+	model.EventSubDeleted: true,
+}
+
+// WebhookService manages endpoints subscription events are sent to
+type WebhookService interface {
+	// Create generates the signing secret of the endpoint, it is only
+	// returned here
+	Create(ctx context.Context, e *model.WebhookEndpoint) error
+	GetById(ctx context.Context, id int) (*model.WebhookEndpoint, error)
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, limit, offset int) ([]model.WebhookEndpoint, error)
+	Count(ctx context.Context) (int, error)
+	// Deliveries is the delivery log of an endpoint, newest first
+	Deliveries(ctx context.Context, id int, limit, offset int) ([]model.WebhookDelivery, error)
+	CountDeliveries(ctx context.Context, id int) (int, error)
+}
+
+type webhookService struct {
+	repository repository.WebhookRepository
+	log        *logrus.Logger
+}
+
+func NewWebhookService(r repository.WebhookRepository, log *logrus.Logger) WebhookService {
+	return &webhookService{repository: r, log: log}
+}
+
+func (s *webhookService) Create(ctx context.Context, e *model.WebhookEndpoint) error {
+	s.log.WithFields(logrus.Fields{
+		"url":    e.Url,
+		"events": e.Events,
+	}).Info("Creating webhook endpoint")
+
+	u, err := url.Parse(e.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhookUrl
+	}
+	for _, event := range e.Events {
+		if !webhookEvents[event] {
+			return fmt.Errorf("%w: %q", ErrUnknownEvent, event)
+		}
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	e.Secret = "whsec_" + hex.EncodeToString(secret)
+	return s.repository.CreateEndpoint(ctx, e)
+}
+
+func (s *webhookService) GetById(ctx context.Context, id int) (*model.WebhookEndpoint, error) {
+	e, err := s.repository.GetEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	e.Secret = ""
+	return e, nil
+}
+
+func (s *webhookService) Delete(ctx context.Context, id int) error {
+	s.log.WithFields(logrus.Fields{
+		"endpoint_id": id,
+	}).Info("Deleting webhook endpoint")
+
+	return s.repository.DeleteEndpoint(ctx, id)
+}
+
+func (s *webhookService) List(ctx context.Context, limit, offset int) ([]model.WebhookEndpoint, error) {
+	endpoints, err := s.repository.ListEndpoints(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range endpoints {
+		endpoints[i].Secret = ""
+	}
+	return endpoints, nil
+}
+
+func (s *webhookService) Count(ctx context.Context) (int, error) {
+	return s.repository.CountEndpoints(ctx)
+}
+
+func (s *webhookService) Deliveries(ctx context.Context, id int, limit, offset int) ([]model.WebhookDelivery, error) {
+	if _, err := s.repository.GetEndpoint(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repository.ListDeliveries(ctx, id, limit, offset)
+}
+
+func (s *webhookService) CountDeliveries(ctx context.Context, id int) (int, error) {
+	return s.repository.CountDeliveries(ctx, id)
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/config"
+	database "github.com/tmozzze/SubChecker/internal/db"
+	"github.com/tmozzze/SubChecker/internal/repository"
+)
+
+// Storage is the backend selected by DB_DRIVER with its repositories
+type Storage struct {
+	Repos *repository.Repositories
+
+	// Pool is set for DB_DRIVER=postgres
+	Pool *pgxpool.Pool
+	// SQLite is set for DB_DRIVER=sqlite
+	SQLite *sql.DB
+
+	// migrations is the version of the newest migration found at start
+	migrations int64
+	// newer is the last version ahead of migrations that was logged
+	newer atomic.Int64
+	log   *logrus.Logger
+}
+
+// Open connects to the configured backend and applies its migrations
+func Open(ctx context.Context, cfg *config.Config, log *logrus.Logger) (*Storage, error) {
+	switch cfg.DBDriver {
+	case config.DriverMemory:
+		log.Warn("Using in-memory storage, data will be lost on restart")
+		return &Storage{Repos: repository.NewMemoryRepositories(repository.NewMemoryDB(), log), log: log}, nil
+
+	case config.DriverSQLite:
+		db, err := database.NewSQLite(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Opened SQLite database %s", cfg.SQLitePath)
+
+		if err := database.RunSQLiteMigration(ctx, db, cfg, log); err != nil {
+			db.Close()
+			return nil, err
+		}
+		latest, err := database.LatestVersion(database.SQLiteMigrationsDir(cfg))
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		return &Storage{Repos: repository.NewSQLiteRepositories(db, log), SQLite: db, migrations: latest, log: log}, nil
+
+	case config.DriverPostgres:
+		db, err := database.NewDB(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Connected to %s on port %s", cfg.DBName, cfg.DBPort)
+
+		if err := database.RunMigration(ctx, db.Pool, cfg, log); err != nil {
+			db.Pool.Close()
+			return nil, err
+		}
+		latest, err := database.LatestVersion(database.MigrationsDir(cfg))
+		if err != nil {
+			db.Pool.Close()
+			return nil, err
+		}
+		return &Storage{Repos: repository.NewPostgresRepositories(db.Pool, log), Pool: db.Pool, migrations: latest, log: log}, nil
+	}
+
+	return nil, errors.New("unknown DB_DRIVER " + cfg.DBDriver)
+}
+
+// Persistent reports whether data survives a restart
+func (s *Storage) Persistent() bool {
+	return s.Pool != nil || s.SQLite != nil
+}
+
+// Ping checks that the database is reachable
+func (s *Storage) Ping(ctx context.Context) error {
+	switch {
+	case s.Pool != nil:
+		return s.Pool.Ping(ctx)
+	case s.SQLite != nil:
+		return s.SQLite.PingContext(ctx)
+	}
+	return nil
+}
+
+// CheckMigrations checks that the database isn't dirty or behind the newest
+// migration the service was started with, e.g. rolled back by another
+// instance. A newer schema is fine: during a rolling deploy the first new
+// instance migrates it while old ones still serve, so it is only logged.
+func (s *Storage) CheckMigrations(ctx context.Context) error {
+	var (
+		version int64
+		dirty   bool
+		err     error
+	)
+	switch {
+	case s.Pool != nil:
+		version, dirty, err = database.Version(ctx, s.Pool)
+	case s.SQLite != nil:
+		version, dirty, err = database.SQLiteVersion(ctx, s.SQLite)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database is dirty at version %d", version)
+	}
+	if version < s.migrations {
+		return fmt.Errorf("database is at version %d, expected %d", version, s.migrations)
+	}
+	if version > s.migrations && s.newer.Swap(version) != version {
+		s.log.WithFields(logrus.Fields{
+			"version":  version,
+			"expected": s.migrations,
+		}).Warn("Database schema is newer than this instance")
+	}
+	return nil
+}
+
+func (s *Storage) Close() {
+	if s.Pool != nil {
+		s.Pool.Close()
+	}
+	if s.SQLite != nil {
+		s.SQLite.Close()
+	}
+}
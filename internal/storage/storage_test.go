@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/config"
+)
+
+func TestCheckMigrations(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	cfg := &config.Config{
+		DBDriver:            config.DriverSQLite,
+		SQLitePath:          filepath.Join(t.TempDir(), "storage.db"),
+		SQLiteMigrationsDir: "../../database/sqlite_migrations",
+	}
+	ctx := context.Background()
+	s, err := Open(ctx, cfg, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	applied := s.migrations
+
+	tests := []struct {
+		name     string
+		expected int64
+		ok       bool
+	}{
+		// A newer instance expects a migration not applied yet
+		{"behind", applied + 1, false},
+		{"equal", applied, true},
+		// An older instance during a rolling deploy
+		{"ahead", applied - 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.migrations = tt.expected
+			err := s.CheckMigrations(ctx)
+			if (err == nil) != tt.ok {
+				t.Errorf("CheckMigrations() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+
+	t.Run("dirty", func(t *testing.T) {
+		s.migrations = applied
+		if _, err := s.SQLite.ExecContext(ctx, `UPDATE schema_migrations SET dirty = 1`); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.CheckMigrations(ctx); err == nil {
+			t.Error("CheckMigrations() of a dirty database = nil")
+		}
+	})
+}
@@ -0,0 +1,169 @@
+// Package stream fans committed subscription changes out to in-process
+// subscribers, e.g. Server-Sent Events clients
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+// subscriberBuffer is how many events a subscriber may lag behind before
+// it is dropped. A dropped client reconnects and resumes from the buffer.
+const subscriberBuffer = 64
+
+// Publisher accepts subscription changes after they are committed
+type Publisher interface {
+	Publish(e model.SubEvent)
+}
+
+// Hub is where the service publishes changes and clients subscribe to them
+type Hub interface {
+	Publisher
+	Subscribe(orgId string, afterId int64, match func(model.SubEvent) bool) *Subscription
+}
+
+// Broker keeps the last published events for resuming and passes them on
+// to subscribers of their organization. Events without an id are numbered
+// by the broker, those notified by Postgres come with a global one.
+type Broker struct {
+	mu     sync.Mutex
+	buffer []model.SubEvent
+	size   int
+	lastId int64
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBroker keeps the last size events for resuming. Its own ids start
+// from the clock, so that ids from before a restart aren't reused.
+func NewBroker(size int) *Broker {
+	return &Broker{size: size, lastId: time.Now().UnixMilli(), subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives events until Close is called or it falls behind,
+// then Events is closed
+type Subscription struct {
+	// Missed is set if events after the requested id are no longer
+	// buffered, the client should reload what it shows
+	Missed bool
+	Events <-chan model.SubEvent
+
+	events chan model.SubEvent
+	orgId  string
+	match  func(model.SubEvent) bool
+	broker *Broker
+}
+
+func (b *Broker) Publish(e model.SubEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e.Id == 0 {
+		b.lastId++
+		e.Id = b.lastId
+	}
+	if e.At.IsZero() {
+		e.At = time.Now().UTC()
+	}
+	if b.size > 0 {
+		if len(b.buffer) == b.size {
+			b.buffer = append(b.buffer[:0], b.buffer[1:]...)
+		}
+		b.buffer = append(b.buffer, e)
+	}
+
+	for s := range b.subs {
+		if s.orgId != e.OrgId || !s.match(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			b.drop(s)
+		}
+	}
+}
+
+// Subscribe streams events of the organization that match. If afterId is
+// not 0 buffered events that came after it are sent first, and Missed is
+// set if it isn't buffered. Global ids are taken in transactions and may
+// commit out of order, so the backlog follows the buffer, not the ids.
+func (b *Broker) Subscribe(orgId string, afterId int64, match func(model.SubEvent) bool) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []model.SubEvent
+	missed := false
+	if afterId > 0 {
+		missed = true
+		for i, e := range b.buffer {
+			if e.Id != afterId {
+				continue
+			}
+			missed = false
+			for _, e := range b.buffer[i+1:] {
+				if e.OrgId == orgId && match(e) {
+					backlog = append(backlog, e)
+				}
+			}
+			break
+		}
+	}
+
+	events := make(chan model.SubEvent, len(backlog)+subscriberBuffer)
+	for _, e := range backlog {
+		events <- e
+	}
+	s := &Subscription{Missed: missed, Events: events, events: events, orgId: orgId, match: match, broker: b}
+	if b.closed {
+		close(events)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Gap tells that events may have been lost, e.g. while the database
+// connection was down. Subscribers are dropped, and clients resuming from
+// before the gap get Missed.
+func (b *Broker) Gap() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buffer = b.buffer[:0]
+	for s := range b.subs {
+		b.drop(s)
+	}
+}
+
+// Close ends every subscription, including those made later, so that
+// streams let the server shut down. Their clients reconnect to another
+// instance and resume.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subs {
+		b.drop(s)
+	}
+}
+
+// Close stops the subscription, it is safe to call more than once
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.drop(s)
+}
+
+// drop removes a subscriber. Caller holds b.mu.
+func (b *Broker) drop(s *Subscription) {
+	if _, ok := b.subs[s]; !ok {
+		return
+	}
+	delete(b.subs, s)
+	close(s.events)
+}
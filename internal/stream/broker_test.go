@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+func all(model.SubEvent) bool { return true }
+
+func ids(s *Subscription) []int64 {
+	var got []int64
+	for len(s.Events) > 0 {
+		got = append(got, (<-s.Events).Id)
+	}
+	return got
+}
+
+func TestSubscribeResumesInPublishOrder(t *testing.T) {
+	b := NewBroker(10)
+	// Global ids commit out of order
+	for _, id := range []int64{5, 7, 6, 8} {
+		b.Publish(model.SubEvent{Id: id, OrgId: "default"})
+	}
+	b.Publish(model.SubEvent{Id: 9, OrgId: "other"})
+
+	s := b.Subscribe("default", 7, all)
+	defer s.Close()
+	if s.Missed {
+		t.Fatal("missed a buffered id")
+	}
+	got := ids(s)
+	if len(got) != 2 || got[0] != 6 || got[1] != 8 {
+		t.Fatalf("backlog %v, want [6 8]", got)
+	}
+}
+
+func TestSubscribeUnknownIdMissed(t *testing.T) {
+	b := NewBroker(2)
+	for _, id := range []int64{1, 2, 3} {
+		b.Publish(model.SubEvent{Id: id, OrgId: "default"})
+	}
+	for _, afterId := range []int64{1, 4, 100} {
+		s := b.Subscribe("default", afterId, all)
+		if !s.Missed {
+			t.Errorf("after %d: not missed", afterId)
+		}
+		s.Close()
+	}
+
+	b.Gap()
+	s := b.Subscribe("default", 3, all)
+	defer s.Close()
+	if !s.Missed {
+		t.Error("not missed after a gap")
+	}
+}
+
+func TestPublishNumbersLocalEvents(t *testing.T) {
+	b := NewBroker(10)
+	s := b.Subscribe("default", 0, all)
+	defer s.Close()
+	b.Publish(model.SubEvent{OrgId: "default"})
+	b.Publish(model.SubEvent{OrgId: "default"})
+
+	got := ids(s)
+	if len(got) != 2 || got[0] == 0 || got[1] != got[0]+1 {
+		t.Fatalf("ids %v, want consecutive", got)
+	}
+}
+
+func TestPublishWithoutBuffer(t *testing.T) {
+	b := NewBroker(0)
+	s := b.Subscribe("default", 0, all)
+	defer s.Close()
+	b.Publish(model.SubEvent{OrgId: "default"})
+	if got := ids(s); len(got) != 1 {
+		t.Fatalf("got %v, want one event", got)
+	}
+}
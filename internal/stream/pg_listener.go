@@ -0,0 +1,98 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+// Channel is the Postgres channel the subs trigger notifies of changes
+const Channel = "sub_changes"
+
+const (
+	minReconnect = time.Second
+	maxReconnect = 30 * time.Second
+)
+
+// Listener publishes changes notified by Postgres to a broker, so clients
+// of every instance see changes made through any of them
+type Listener struct {
+	pool      *pgxpool.Pool
+	broker    *Broker
+	log       *logrus.Logger
+	listening atomic.Bool
+}
+
+func NewListener(pool *pgxpool.Pool, broker *Broker, log *logrus.Logger) *Listener {
+	return &Listener{pool: pool, broker: broker, log: log}
+}
+
+// Run listens until ctx is done, reconnecting with backoff. Notifications
+// sent while disconnected are lost, so subscribers are told of a gap.
+func (l *Listener) Run(ctx context.Context) {
+	wait := minReconnect
+	for {
+		started := time.Now()
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		l.broker.Gap()
+		if time.Since(started) > maxReconnect {
+			wait = minReconnect
+		}
+		l.log.WithError(err).WithField("retry_in", wait.String()).Error("Lost subscription change notifications")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, maxReconnect)
+	}
+}
+
+// listen takes a connection out of the pool, it can't be shared while it waits
+func (l *Listener) listen(ctx context.Context) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+	l.listening.Store(true)
+	defer l.listening.Store(false)
+	l.log.WithField("channel", Channel).Info("Listening for subscription changes")
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var e model.SubEvent
+		if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+			l.log.WithError(err).WithField("payload", n.Payload).Error("Bad subscription change notification")
+			continue
+		}
+		l.broker.Publish(e)
+	}
+}
+
+// Check fails while the listener is not connected, changes made meanwhile
+// don't reach the clients
+func (l *Listener) Check(context.Context) error {
+	if !l.listening.Load() {
+		return errors.New("not listening on " + Channel)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package stream
+
+import "github.com/tmozzze/SubChecker/internal/model"
+
+// remote is a hub of a broker fed from outside the instance, e.g. by a
+// Listener. Changes published locally come back through it, so Publish
+// does nothing.
+type remote struct {
+	*Broker
+}
+
+// Remote subscribes to b but leaves publishing to whatever feeds it
+func Remote(b *Broker) Hub {
+	return remote{b}
+}
+
+func (remote) Publish(model.SubEvent) {}
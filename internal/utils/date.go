@@ -2,6 +2,18 @@ package utils
 
 import "time"
 
+// MonthLayout is MM-YYYY, the format of months in requests and responses
+const MonthLayout = "01-2006"
+
+// ParseMonth parses an MM-YYYY month to its first day in UTC
+func ParseMonth(s string) (time.Time, error) {
+	t, err := time.Parse(MonthLayout, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return TruncateToMonth(t), nil
+}
+
 func TruncateToMonth(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
 }
@@ -0,0 +1,17 @@
+package utils
+
+// Page sizes of list requests
+const (
+	DefaultLimit = 50
+	// MaxLimit keeps a single request from loading a whole table
+	MaxLimit = 500
+)
+
+// Page normalizes limit and offset of a list request: DefaultLimit if
+// limit is not positive, MaxLimit at most, and 0 for a negative offset
+func Page(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	return min(limit, MaxLimit), max(offset, 0)
+}
@@ -0,0 +1,251 @@
+// Package webhook sends subscription events from the outbox to webhook endpoints
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+	"github.com/tmozzze/SubChecker/internal/repository"
+	"github.com/tmozzze/SubChecker/internal/reqctx"
+)
+
+// Headers of a webhook request
+const (
+	EventHeader     = "X-SubChecker-Event"
+	DeliveryHeader  = "X-SubChecker-Delivery"
+	TimestampHeader = "X-SubChecker-Timestamp"
+	SignatureHeader = "X-SubChecker-Signature"
+)
+
+const (
+	// batchSize limits events and deliveries handled per organization and run
+	batchSize = 100
+	// maxEndpoints limits endpoints of one organization events are fanned out to
+	maxEndpoints = 1000
+	// lease is how long a claimed delivery isn't picked up by other
+	// dispatchers, longer than the request timeout
+	lease      = time.Minute
+	maxBackoff = 6 * time.Hour
+)
+
+// Sign is the signature of a payload sent at timestamp (unix seconds):
+// "sha256=" and hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the
+// endpoint secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher fans events out of the outbox into deliveries, one per
+// subscribed endpoint, and sends due deliveries. A failed delivery is
+// retried with exponential backoff until maxAttempts is reached.
+type Dispatcher struct {
+	webhooks repository.WebhookRepository
+	client   *http.Client
+	// orgs are checked one by one, repositories only see one organization
+	orgs        *repository.OrgSelector
+	interval    time.Duration
+	backoff     time.Duration
+	maxAttempts int
+	log         *logrus.Logger
+}
+
+func NewDispatcher(webhooks repository.WebhookRepository, orgs *repository.OrgSelector, interval, backoff time.Duration,
+	maxAttempts int, log *logrus.Logger) *Dispatcher {
+	return &Dispatcher{
+		webhooks:    webhooks,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		orgs:        orgs,
+		interval:    interval,
+		backoff:     backoff,
+		maxAttempts: maxAttempts,
+		log:         log,
+	}
+}
+
+// Run dispatches right away and then every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.log.WithFields(logrus.Fields{
+		"interval":     d.interval.String(),
+		"backoff":      d.backoff.String(),
+		"max_attempts": d.maxAttempts,
+	}).Info("Starting webhook dispatcher")
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.RunOnce(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce fans out new events and sends deliveries due at now. Failures
+// are logged and picked up again on a later run.
+func (d *Dispatcher) RunOnce(ctx context.Context, now time.Time) {
+	orgs, err := d.orgs.List(ctx)
+	if err != nil {
+		d.log.WithError(err).Error("Failed to list organizations for webhooks")
+		return
+	}
+	for _, org := range orgs {
+		ctx := reqctx.WithActor(reqctx.WithOrgId(ctx, org), "webhook-dispatcher")
+
+		if err := d.fanOut(ctx); err != nil {
+			d.log.WithError(err).WithField("org_id", org).Error("Failed to fan out webhook events")
+		}
+		if err := d.deliver(ctx, now); err != nil {
+			d.log.WithError(err).WithField("org_id", org).Error("Failed to send webhook deliveries")
+		}
+	}
+}
+
+func (d *Dispatcher) fanOut(ctx context.Context) error {
+	events, err := d.webhooks.Undispatched(ctx, batchSize)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	endpoints, err := d.webhooks.ListEndpoints(ctx, maxEndpoints, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		var ids []int
+		for _, endpoint := range endpoints {
+			if endpoint.Wants(e.Type) {
+				ids = append(ids, endpoint.EndpointId)
+			}
+		}
+		// Another dispatcher got there first
+		if err := d.webhooks.Dispatch(ctx, e.EventId, ids); err != nil && !errors.Is(err, repository.ErrConflict) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, now time.Time) error {
+	deliveries, err := d.webhooks.ClaimDue(ctx, now, lease, batchSize)
+	if err != nil {
+		return err
+	}
+
+	endpoints := make(map[int]*model.WebhookEndpoint)
+	for _, delivery := range deliveries {
+		endpoint, ok := endpoints[delivery.EndpointId]
+		if !ok {
+			if endpoint, err = d.webhooks.GetEndpoint(ctx, delivery.EndpointId); err != nil {
+				// Deleted endpoints have no deliveries
+				if errors.Is(err, repository.ErrNotFound) {
+					continue
+				}
+				return err
+			}
+			endpoints[delivery.EndpointId] = endpoint
+		}
+		event, err := d.webhooks.GetEvent(ctx, delivery.EventId)
+		if err != nil {
+			return err
+		}
+
+		d.attempt(ctx, endpoint, event, delivery)
+	}
+	return nil
+}
+
+// attempt sends a delivery once and stores the outcome
+func (d *Dispatcher) attempt(ctx context.Context, endpoint *model.WebhookEndpoint, event *model.WebhookEvent, delivery model.WebhookDelivery) {
+	fields := logrus.Fields{
+		"org_id":      event.OrgId,
+		"delivery_id": delivery.DeliveryId,
+		"endpoint_id": endpoint.EndpointId,
+		"event_id":    event.EventId,
+		"type":        event.Type,
+	}
+
+	status, err := d.send(ctx, endpoint, event, delivery.DeliveryId)
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.LastError = ""
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliveryDelivered
+		delivery.DeliveredAt = &now
+		d.log.WithFields(fields).Debug("Webhook delivered")
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = model.DeliveryFailed
+		delivery.LastError = err.Error()
+		d.log.WithError(err).WithFields(fields).Error("Webhook delivery failed, giving up")
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.retryIn(delivery.Attempts))
+		d.log.WithError(err).WithFields(fields).Warn("Webhook delivery failed, will retry")
+	}
+
+	// The outcome must be stored even if ctx is cancelled mid-request
+	if err := d.webhooks.SaveAttempt(context.WithoutCancel(ctx), &delivery); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		d.log.WithError(err).WithFields(fields).Error("Failed to save webhook delivery attempt")
+	}
+}
+
+// retryIn is the delay after the given number of failed attempts:
+// backoff, then twice as long every time, at most maxBackoff
+func (d *Dispatcher) retryIn(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// send POSTs the event to the endpoint and returns the response status,
+// 0 if there was no response. A non-2xx response is an error.
+func (d *Dispatcher) send(ctx context.Context, endpoint *model.WebhookEndpoint, event *model.WebhookEvent, deliveryId int64) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event.Type)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(deliveryId, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmozzze/SubChecker/internal/model"
+)
+
+func TestSign(t *testing.T) {
+	// printf '1700000000.{"type":"sub.created"}' | openssl dgst -sha256 -hmac whsec
+	const want = "sha256=a7b6a7065b8b89cd19bf52beec8ce6237df69976df66324c93acedb3089597e0"
+	if got := Sign("whsec", 1700000000, []byte(`{"type":"sub.created"}`)); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// Every part of the signed payload matters
+	base := Sign("whsec", 1700000000, []byte("{}"))
+	for name, got := range map[string]string{
+		"secret":    Sign("other", 1700000000, []byte("{}")),
+		"timestamp": Sign("whsec", 1700000001, []byte("{}")),
+		"body":      Sign("whsec", 1700000000, []byte("[]")),
+	} {
+		if got == base {
+			t.Errorf("signature doesn't depend on the %s", name)
+		}
+	}
+}
+
+func TestRetryIn(t *testing.T) {
+	d := &Dispatcher{backoff: time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, maxBackoff},
+		{1000, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := d.retryIn(tt.attempts); got != tt.want {
+			t.Errorf("after %d attempts: got %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestSendSignsRequest(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	d := NewDispatcher(nil, nil, time.Minute, time.Minute, 3, log)
+	endpoint := &model.WebhookEndpoint{Url: srv.URL, Secret: "whsec"}
+	event := &model.WebhookEvent{EventId: 7, Type: model.EventSubCreated, SubId: 1}
+
+	status, err := d.send(context.Background(), endpoint, event, 42)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("got %d, %v", status, err)
+	}
+	if got.Header.Get(EventHeader) != event.Type || got.Header.Get(DeliveryHeader) != "42" {
+		t.Errorf("got headers %v", got.Header)
+	}
+	timestamp, err := strconv.ParseInt(got.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig := got.Header.Get(SignatureHeader); sig != Sign("whsec", timestamp, body) {
+		t.Errorf("signature %s doesn't match the body", sig)
+	}
+}